@@ -13,6 +13,7 @@ type ErrorData struct {
 	Title      string
 	Error      error
 	StatusCode int
+	Upstream   bool // Whether the error is a not found or forbidden response from pixiv
 }
 
 func Error(pageData ErrorData) templ.Component {
@@ -43,7 +44,7 @@ func Error(pageData ErrorData) templ.Component {
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(pageData.StatusCode)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `assets/components/pages/error.templ`, Line: 12, Col: 63}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `assets/components/pages/error.templ`, Line: 13, Col: 63}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
@@ -53,31 +54,49 @@ func Error(pageData ErrorData) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		if pageData.StatusCode == 404 {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<p class=\"text-neutral-200\">The requested route does not exist on the server.</p>")
+		if pageData.Upstream {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<p class=\"text-neutral-200\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(pageData.Error.Error())
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `assets/components/pages/error.templ`, Line: 16, Col: 28}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else if pageData.StatusCode == 404 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<p class=\"text-neutral-200\">The requested route does not exist on the server.</p>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		} else {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<p class=\"text-neutral-200\">The following error occured when trying to render this page:</p><div class=\"w-full bg-black border border-neutral-700 text-fuchsia-400 font-mono rounded-lg p-6 -mt-2\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<p class=\"text-neutral-200\">The following error occured when trying to render this page:</p><div class=\"w-full bg-black border border-neutral-700 text-fuchsia-400 font-mono rounded-lg p-6 -mt-2\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			var templ_7745c5c3_Var3 string
-			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(pageData.Error.Error())
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(pageData.Error.Error())
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `assets/components/pages/error.templ`, Line: 24, Col: 28}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `assets/components/pages/error.templ`, Line: 29, Col: 28}
 			}
-			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div><p class=\"text-neutral-400 font-medium text-xs -mt-3\"><span class=\"font-bold\">Note:</span> this may be an error from either PixivFE itself or the pixiv API.</p> <div class=\"flex flex-col text-neutral-200 border-s-4 border-pixivfe-400 ps-4 py-2 gap-4\"><p class=\"font-bold text-neutral-100\">Seeing this error repeatedly? (つ ◕︵◕ )つ</p><p><a class=\"text-link text-neutral-100 hover:text-neutral-50\" href=\"https://codeberg.org/PixivFE/PixivFE/issues/new?template=.forgejo%2fissue_template%2fbug-report.yaml\" rel=\"noopener\" target=\"_blank\">File a bug report on our Codeberg repository</a> so that the developers can look into it!</p></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "</div><p class=\"text-neutral-400 font-medium text-xs -mt-3\"><span class=\"font-bold\">Note:</span> this may be an error from either PixivFE itself or the pixiv API.</p> <div class=\"flex flex-col text-neutral-200 border-s-4 border-pixivfe-400 ps-4 py-2 gap-4\"><p class=\"font-bold text-neutral-100\">Seeing this error repeatedly? (つ ◕︵◕ )つ</p><p><a class=\"text-link text-neutral-100 hover:text-neutral-50\" href=\"https://codeberg.org/PixivFE/PixivFE/issues/new?template=.forgejo%2fissue_template%2fbug-report.yaml\" rel=\"noopener\" target=\"_blank\">File a bug report on our Codeberg repository</a> so that the developers can look into it!</p></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -2,8 +2,9 @@
 // SPDX-License-Identifier: AGPL-3.0-only
 
 /*
-Package audit provides logging for PixivFE.
+Package audit provides logging and metrics for PixivFE.
 
-The package uses uber-go/zap for structured logging.
+The package uses uber-go/zap for structured logging. Metrics are derived
+from the logged spans and exposed in the Prometheus text format.
 */
 package audit
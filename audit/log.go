@@ -21,7 +21,7 @@ const (
 )
 
 var (
-	staticSkippedPathPrefixes = []string{"/img/", "/css/", "/js/", "/diagnostics"}
+	staticSkippedPathPrefixes = []string{"/img/", "/css/", "/js/", "/diagnostics", "/metrics"}
 	devSkippedPathPrefixes    = []string{"/proxy/s.pximg.net/", "/proxy/i.pximg.net/"}
 	staticSkippedHostnames    = []string{"s.pximg.net", "i.pximg.net"}
 )
@@ -87,6 +87,8 @@ func (a *Auditor) LogAndRecord(span Span) {
 		logger.Info("Successful request", logFields...)
 	}
 
+	RecordSpanMetrics(span)
+
 	// Record the span if applicable
 	if GlobalAuditor.MaxRecorded > 0 {
 		if len(RecordedRequestSpans) >= GlobalAuditor.MaxRecorded {
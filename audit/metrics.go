@@ -0,0 +1,293 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+)
+
+// Metric types, as used in the TYPE line of the Prometheus text format.
+const (
+	MetricTypeCounter   string = "counter"
+	MetricTypeGauge     string = "gauge"
+	MetricTypeHistogram string = "histogram"
+)
+
+// unmatchedRoute is the route label used for server spans without a route template.
+const unmatchedRoute string = "unmatched"
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency histogram buckets.
+//
+// These match the default buckets of the Prometheus client libraries.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// globalMetrics holds the metrics recorded by LogAndRecord and RecordLimiterBlock.
+var globalMetrics = newMetricsRegistry()
+
+// MetricSample is a single sample of a metric family.
+type MetricSample struct {
+	Suffix string   // Appended to the family name, e.g. "_bucket" for histograms
+	Labels []string // Alternating label names and values
+	Value  float64
+}
+
+// histogram is a cumulative histogram using latencyBuckets.
+type histogram struct {
+	counts []uint64 // Count of observations less than or equal to each bucket bound
+	count  uint64
+	sum    float64
+}
+
+// observe adds a single observation to the histogram.
+func (h *histogram) observe(value float64) {
+	for i, bound := range latencyBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// metricsRegistry aggregates metrics derived from spans.
+//
+// Labels are encoded as a single string key to keep lookups cheap;
+// see labelKey.
+type metricsRegistry struct {
+	mu                sync.Mutex
+	serverDurations   map[string]*histogram // Keyed by route and method
+	serverTotals      map[string]uint64     // Keyed by route, method and status code
+	upstreamDurations map[string]*histogram // Keyed by host and method
+	upstreamTotals    map[string]uint64     // Keyed by host, method and status code
+	limiterBlocks     map[string]uint64     // Keyed by reason
+}
+
+// newMetricsRegistry creates an empty metricsRegistry.
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		serverDurations:   make(map[string]*histogram),
+		serverTotals:      make(map[string]uint64),
+		upstreamDurations: make(map[string]*histogram),
+		upstreamTotals:    make(map[string]uint64),
+		limiterBlocks:     make(map[string]uint64),
+	}
+}
+
+// observeDuration records a duration in seconds in the histogram for key in histograms.
+//
+// The caller must hold m.mu.
+func (m *metricsRegistry) observeDuration(histograms map[string]*histogram, key string, seconds float64) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		histograms[key] = h
+	}
+
+	h.observe(seconds)
+}
+
+// recordSpan updates the metrics for a single span.
+func (m *metricsRegistry) recordSpan(span Span) {
+	code := strconv.Itoa(span.StatusCode)
+	if span.StatusCode == 0 {
+		// No response was received, e.g. due to a network error.
+		code = "error"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch span.Component {
+	case ComponentServer:
+		route := span.Route
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		m.observeDuration(m.serverDurations, labelKey("route", route, "method", span.Method), span.Duration.Seconds())
+		m.serverTotals[labelKey("route", route, "method", span.Method, "code", code)]++
+	case ComponentUpstream:
+		host := "unknown"
+		if parsedURL, err := url.Parse(span.URL); err == nil && parsedURL.Host != "" {
+			host = parsedURL.Host
+		}
+
+		m.observeDuration(m.upstreamDurations, labelKey("host", host, "method", span.Method), span.Duration.Seconds())
+		m.upstreamTotals[labelKey("host", host, "method", span.Method, "code", code)]++
+	}
+}
+
+// RecordSpanMetrics updates the metrics for a single span without logging it,
+// as done for upstream requests to hosts that skip logging.
+//
+// Has no effect unless metrics are enabled.
+func RecordSpanMetrics(span Span) {
+	if !config.GlobalConfig.Metrics.Enabled {
+		return
+	}
+
+	globalMetrics.recordSpan(span)
+}
+
+// RecordLimiterBlock records a request blocked by the limiter middleware for the given reason.
+//
+// Has no effect unless metrics are enabled.
+func RecordLimiterBlock(reason string) {
+	if !config.GlobalConfig.Metrics.Enabled {
+		return
+	}
+
+	globalMetrics.mu.Lock()
+	defer globalMetrics.mu.Unlock()
+
+	globalMetrics.limiterBlocks[labelKey("reason", reason)]++
+}
+
+// WriteMetrics writes the metrics derived from spans, the limiter block counts
+// and the token manager health to w in the Prometheus text format.
+func WriteMetrics(w io.Writer) error {
+	m := globalMetrics
+
+	m.mu.Lock()
+
+	serverDurations := histogramSamples(m.serverDurations)
+	serverTotals := counterSamples(m.serverTotals)
+	upstreamDurations := histogramSamples(m.upstreamDurations)
+	upstreamTotals := counterSamples(m.upstreamTotals)
+	limiterBlocks := counterSamples(m.limiterBlocks)
+
+	m.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+
+	WriteMetricFamily(buf, "pixivfe_http_request_duration_seconds", MetricTypeHistogram,
+		"Duration of requests served by PixivFE, by route.", serverDurations...)
+	WriteMetricFamily(buf, "pixivfe_http_requests_total", MetricTypeCounter,
+		"Requests served by PixivFE, by route and status code.", serverTotals...)
+	WriteMetricFamily(buf, "pixivfe_upstream_request_duration_seconds", MetricTypeHistogram,
+		"Duration of requests sent to upstream servers, by host.", upstreamDurations...)
+	WriteMetricFamily(buf, "pixivfe_upstream_requests_total", MetricTypeCounter,
+		"Requests sent to upstream servers, by host and status code.", upstreamTotals...)
+	WriteMetricFamily(buf, "pixivfe_limiter_blocks_total", MetricTypeCounter,
+		"Requests blocked by the limiter, by reason.", limiterBlocks...)
+
+	if tokenManager := config.GlobalConfig.TokenManager.TokenManager; tokenManager != nil {
+		good, timedOut, invalid := tokenManager.StatusCounts()
+
+		WriteMetricFamily(buf, "pixivfe_tokens", MetricTypeGauge,
+			"Number of pixiv tokens, by status.",
+			MetricSample{Labels: []string{"status", "good"}, Value: float64(good)},
+			MetricSample{Labels: []string{"status", "timed_out"}, Value: float64(timedOut)},
+			MetricSample{Labels: []string{"status", "invalid"}, Value: float64(invalid)},
+		)
+	}
+
+	return buf.Flush()
+}
+
+// WriteMetricFamily writes a metric family with the given samples to w in the Prometheus text format.
+//
+// Nothing is written if there are no samples.
+func WriteMetricFamily(w io.Writer, name, metricType, help string, samples ...MetricSample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+
+	for _, sample := range samples {
+		fmt.Fprintf(w, "%s%s%s %s\n",
+			name, sample.Suffix, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64))
+	}
+}
+
+// histogramSamples converts histograms into samples for WriteMetricFamily, sorted by labels.
+func histogramSamples(histograms map[string]*histogram) []MetricSample {
+	samples := make([]MetricSample, 0, len(histograms)*(len(latencyBuckets)+3))
+
+	for _, key := range slices.Sorted(maps.Keys(histograms)) {
+		h := histograms[key]
+		labels := parseLabelKey(key)
+
+		for i, bound := range latencyBuckets {
+			samples = append(samples, MetricSample{
+				Suffix: "_bucket",
+				Labels: append(slices.Clone(labels), "le", strconv.FormatFloat(bound, 'g', -1, 64)),
+				Value:  float64(h.counts[i]),
+			})
+		}
+
+		samples = append(samples,
+			MetricSample{Suffix: "_bucket", Labels: append(slices.Clone(labels), "le", "+Inf"), Value: float64(h.count)},
+			MetricSample{Suffix: "_sum", Labels: labels, Value: h.sum},
+			MetricSample{Suffix: "_count", Labels: labels, Value: float64(h.count)},
+		)
+	}
+
+	return samples
+}
+
+// counterSamples converts counters into samples for WriteMetricFamily, sorted by labels.
+func counterSamples(counters map[string]uint64) []MetricSample {
+	samples := make([]MetricSample, 0, len(counters))
+
+	for _, key := range slices.Sorted(maps.Keys(counters)) {
+		samples = append(samples, MetricSample{Labels: parseLabelKey(key), Value: float64(counters[key])})
+	}
+
+	return samples
+}
+
+// labelKey encodes alternating label names and values into a single map key.
+func labelKey(labels ...string) string {
+	return strings.Join(labels, "\x00")
+}
+
+// parseLabelKey decodes a map key created by labelKey.
+func parseLabelKey(key string) []string {
+	return strings.Split(key, "\x00")
+}
+
+// formatLabels formats alternating label names and values as a Prometheus label set.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	builder.WriteByte('{')
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+
+		builder.WriteString(labels[i])
+		builder.WriteString(`="`)
+		builder.WriteString(escapeLabelValue(labels[i+1]))
+		builder.WriteByte('"')
+	}
+
+	builder.WriteByte('}')
+
+	return builder.String()
+}
+
+// escapeLabelValue escapes a label value as required by the Prometheus text format.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricsRegistry_RecordSpan verifies that server and upstream spans are aggregated
+// by route and host, and written in the Prometheus text format.
+func TestMetricsRegistry_RecordSpan(t *testing.T) {
+	t.Parallel()
+
+	m := newMetricsRegistry()
+
+	m.recordSpan(Span{Component: ComponentServer, Route: "/artworks/{id}", Method: "GET", StatusCode: 200, Duration: 20 * time.Millisecond})
+	m.recordSpan(Span{Component: ComponentServer, Route: "/artworks/{id}", Method: "GET", StatusCode: 200, Duration: 2 * time.Second})
+	m.recordSpan(Span{Component: ComponentServer, Method: "GET", StatusCode: 404, Duration: time.Millisecond})
+	m.recordSpan(Span{Component: ComponentUpstream, URL: "https://www.pixiv.net/ajax/illust/1", Method: "GET", Duration: time.Second})
+
+	var buf bytes.Buffer
+
+	WriteMetricFamily(&buf, "test_duration_seconds", MetricTypeHistogram, "Test.", histogramSamples(m.serverDurations)...)
+	WriteMetricFamily(&buf, "test_requests_total", MetricTypeCounter, "Test.", counterSamples(m.serverTotals)...)
+	WriteMetricFamily(&buf, "test_upstream_total", MetricTypeCounter, "Test.", counterSamples(m.upstreamTotals)...)
+
+	output := buf.String()
+
+	for _, want := range []string{
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{route="/artworks/{id}",method="GET",le="0.025"} 1` + "\n",
+		`test_duration_seconds_bucket{route="/artworks/{id}",method="GET",le="2.5"} 2` + "\n",
+		`test_duration_seconds_bucket{route="/artworks/{id}",method="GET",le="+Inf"} 2` + "\n",
+		`test_duration_seconds_sum{route="/artworks/{id}",method="GET"} 2.02` + "\n",
+		`test_duration_seconds_count{route="/artworks/{id}",method="GET"} 2` + "\n",
+		`test_requests_total{route="/artworks/{id}",method="GET",code="200"} 2` + "\n",
+		`test_requests_total{route="unmatched",method="GET",code="404"} 1` + "\n",
+		`test_upstream_total{host="www.pixiv.net",method="GET",code="error"} 1` + "\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatLabels checks that label values are escaped.
+func TestFormatLabels(t *testing.T) {
+	t.Parallel()
+
+	got := formatLabels([]string{"reason", "a \"quoted\"\\value\n"})
+	want := `{reason="a \"quoted\"\\value\n"}`
+
+	if got != want {
+		t.Errorf("formatLabels() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracerName is the instrumentation scope name used for all spans created by PixivFE.
+	tracerName string = "codeberg.org/pixivfe/pixivfe"
+
+	// serviceName is reported as the service.name resource attribute.
+	serviceName string = "pixivfe"
+
+	traceFilePermissions os.FileMode = 0o600
+	traceDirPermissions  os.FileMode = 0o700
+)
+
+// Tracer returns the tracer used to create OpenTelemetry spans.
+//
+// If tracing is disabled, the returned tracer creates non-recording spans,
+// so callers don't need to check whether tracing is enabled.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// SetupTracing configures the global OpenTelemetry tracer provider according to cfg.Tracing.
+//
+// It returns a function that flushes any pending spans and releases the exporter,
+// which should be called before the process exits. If tracing is disabled,
+// the returned function does nothing.
+func SetupTracing(cfg *config.ServerConfig) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	if cfg.Tracing.Exporter == config.NoneTracingExporter {
+		return noop, nil
+	}
+
+	exporter, closeOutput, err := newTraceExporter(cfg)
+	if err != nil {
+		return noop, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(cfg.Instance.Version),
+	))
+	if err != nil {
+		return noop, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOutput())
+	}, nil
+}
+
+// newTraceExporter creates the span exporter selected by cfg.Tracing.Exporter.
+//
+// The returned function closes the output file of the file exporter, if any.
+func newTraceExporter(cfg *config.ServerConfig) (sdktrace.SpanExporter, func() error, error) {
+	closeOutput := func() error { return nil }
+
+	switch cfg.Tracing.Exporter {
+	case config.OTLPTracingExporter:
+		var opts []otlptracehttp.Option
+
+		// If no endpoint is set, the standard OTEL_EXPORTER_OTLP_* environment variables are used.
+		if cfg.Tracing.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Tracing.Endpoint))
+		}
+
+		exporter, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, closeOutput, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+
+		return exporter, closeOutput, nil
+	case config.StdoutTracingExporter:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, closeOutput, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+
+		return exporter, closeOutput, nil
+	case config.FileTracingExporter:
+		if err := os.MkdirAll(filepath.Dir(cfg.Tracing.Path), traceDirPermissions); err != nil {
+			return nil, closeOutput, fmt.Errorf("failed to create trace directory: %w", err)
+		}
+
+		file, err := os.OpenFile(cfg.Tracing.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, traceFilePermissions)
+		if err != nil {
+			return nil, closeOutput, fmt.Errorf("failed to open trace file %s: %w", cfg.Tracing.Path, err)
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+
+			return nil, closeOutput, fmt.Errorf("failed to create file trace exporter: %w", err)
+		}
+
+		return exporter, file.Close, nil
+	default:
+		return nil, closeOutput, fmt.Errorf("unsupported trace exporter: %s", cfg.Tracing.Exporter)
+	}
+}
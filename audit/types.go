@@ -10,6 +10,7 @@ import (
 // Span represents the logging format used by PixivFE.
 type Span struct {
 	Component  string
+	Route      string // Route template for server spans, e.g. "/artworks/{id}"
 	Duration   time.Duration
 	RequestID  string
 	Method     string
@@ -155,6 +155,23 @@ i18n_upload() {
 i18n_download() {
 	echo "Downloading i18n strings from Crowdin..."
 	crowdin download
+	i18n_check
+}
+
+i18n_check() {
+	echo "Checking ICU MessageFormat strings in translations..."
+	for locale_dir in i18n/locale/*/; do
+		for file in code.json template.json; do
+			go run ./i18n/converter -check "${locale_dir}${file}" <"i18n/locale/en/${file}"
+		done
+	done
+}
+
+i18n_coverage() {
+	echo "Reporting translation coverage..."
+	go run ./i18n/codecrawler >i18n/code_strings.json
+	go run ./i18n/templatecrawler >i18n/template_strings.json
+	go run ./i18n/coverage -code i18n/code_strings.json -template i18n/template_strings.json
 }
 
 run() {
@@ -193,6 +210,8 @@ help() {
 	echo "  i18n               - Extract i18n strings"
 	echo "  i18n-up            - Upload strings to Crowdin"
 	echo "  i18n-down          - Download strings from Crowdin"
+	echo "  i18n-check         - Check ICU MessageFormat strings in translations"
+	echo "  i18n-coverage      - Report translation coverage, missing and orphaned strings"
 	echo "  run                - Build and run the binary"
 	echo "  watch              - Build and run the binary, restart when file changes"
 	echo "  clean              - Remove the binary"
@@ -224,6 +243,10 @@ execute_command() {
 	i18n_upload) i18n_upload ;;
 	i18n-down) i18n_download ;;
 	i18n_download) i18n_download ;;
+	i18n-check) i18n_check ;;
+	i18n_check) i18n_check ;;
+	i18n-coverage) i18n_coverage ;;
+	i18n_coverage) i18n_coverage ;;
 	check_css) check_css ;;
 	run) run ;;
 	watch) watch ;;
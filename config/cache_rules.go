@@ -0,0 +1,135 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CacheRule overrides the API response cache TTL for upstream URLs that match it.
+//
+// A rule matches on exactly one of Prefix or Regex:
+//   - Prefix is compared against the start of the cleaned URL path (e.g. "/ajax/illust/").
+//   - Regex is matched against the URL path followed by "?" and the raw query string, if any.
+//
+// If NoCache is true, matching responses are never cached and TTL is ignored.
+type CacheRule struct {
+	Prefix  string        `yaml:"prefix"`
+	Regex   string        `yaml:"regex"`
+	TTL     time.Duration `yaml:"ttl"`
+	NoCache bool          `yaml:"noCache"`
+
+	compiledRegex *regexp.Regexp
+}
+
+// defaultCacheRules are evaluated before any rules from the configuration file.
+//
+// They list API endpoints for which responses are never cached, regardless of any other factors,
+// so configured rules can't override them.
+var defaultCacheRules = []CacheRule{
+	{Prefix: "/ajax/discovery/artworks", NoCache: true},
+	{Prefix: "/ajax/discovery/novels", NoCache: true},
+	{Prefix: "/ajax/discovery/users", NoCache: true},
+	{Prefix: "/ajax/illust/new", NoCache: true},
+}
+
+// Matches reports whether the rule applies to a URL with the given path and raw query string.
+func (rule CacheRule) Matches(urlPath, rawQuery string) bool {
+	if rule.compiledRegex != nil {
+		target := urlPath
+		if rawQuery != "" {
+			target += "?" + rawQuery
+		}
+
+		return rule.compiledRegex.MatchString(target)
+	}
+
+	return rule.Prefix != "" && strings.HasPrefix(urlPath, rule.Prefix)
+}
+
+// String returns a human-readable representation of the rule for printConfiguration.
+func (rule CacheRule) String() string {
+	var target string
+
+	if rule.Regex != "" {
+		target = "regex " + rule.Regex
+	} else {
+		target = "prefix " + rule.Prefix
+	}
+
+	if rule.NoCache {
+		return target + " -> never cache"
+	}
+
+	return fmt.Sprintf("%s -> %s", target, rule.TTL)
+}
+
+// compile validates the rule and compiles its regular expression, if any.
+func (rule *CacheRule) compile() error {
+	switch {
+	case rule.Prefix != "" && rule.Regex != "":
+		return fmt.Errorf("cache rule %q: cannot specify both prefix and regex", rule.Prefix)
+	case rule.Prefix == "" && rule.Regex == "":
+		return errors.New("cache rule must specify either prefix or regex")
+	case !rule.NoCache && rule.TTL <= 0:
+		return fmt.Errorf("cache rule %s: ttl must be positive unless noCache is set", rule.String())
+	}
+
+	if rule.Regex == "" {
+		return nil
+	}
+
+	compiled, err := regexp.Compile(rule.Regex)
+	if err != nil {
+		return fmt.Errorf("cache rule: invalid regex %q: %w", rule.Regex, err)
+	}
+
+	rule.compiledRegex = compiled
+
+	return nil
+}
+
+// setCacheRules validates the configured cache rules and puts the default rules before them,
+// so that the default rules take precedence.
+func (cfg *ServerConfig) setCacheRules() error {
+	rules := make([]CacheRule, 0, len(defaultCacheRules)+len(cfg.Cache.Rules))
+	rules = append(rules, defaultCacheRules...)
+
+	for _, rule := range cfg.Cache.Rules {
+		if err := rule.compile(); err != nil {
+			return err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	cfg.Cache.Rules = rules
+
+	return nil
+}
+
+// CacheTTLFor returns the TTL to use for an upstream URL with the given path and raw query string.
+//
+// The first matching rule wins, starting with the default rules. If no rule matches,
+// Cache.TTL is returned.
+// The boolean return is false if the response should not be cached at all.
+func (cfg *ServerConfig) CacheTTLFor(urlPath, rawQuery string) (time.Duration, bool) {
+	for _, rule := range cfg.Cache.Rules {
+		if !rule.Matches(urlPath, rawQuery) {
+			continue
+		}
+
+		if rule.NoCache {
+			return 0, false
+		}
+
+		return rule.TTL, true
+	}
+
+	return cfg.Cache.TTL, true
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCacheTTLFor verifies that the default rules take precedence over configured rules,
+// and that Cache.TTL is used when no rule matches.
+func TestCacheTTLFor(t *testing.T) {
+	cfg := &ServerConfig{}
+	cfg.Cache.TTL = time.Hour
+	cfg.Cache.Rules = []CacheRule{
+		{Prefix: "/ajax/illust/new", TTL: time.Minute},
+		{Regex: `^/touch/ajax/ranking/illust\?.*date=`, TTL: 24 * time.Hour},
+		{Prefix: "/ajax/illust/", TTL: 5 * time.Minute},
+		{Prefix: "/ajax/user/", NoCache: true},
+	}
+
+	if err := cfg.setCacheRules(); err != nil {
+		t.Fatalf("setCacheRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		query         string
+		wantTTL       time.Duration
+		wantCacheable bool
+	}{
+		{"Prefix rule", "/ajax/illust/123", "", 5 * time.Minute, true},
+		{"Default rule overrides configured rule", "/ajax/illust/new", "limit=30", 0, false},
+		{"Default rule overrides broad configured prefix", "/ajax/illust/new", "", 0, false},
+		{"Regex rule matches query", "/touch/ajax/ranking/illust", "mode=daily&date=20240101", 24 * time.Hour, true},
+		{"Regex rule does not match", "/touch/ajax/ranking/illust", "mode=daily", time.Hour, true},
+		{"NoCache rule", "/ajax/user/1", "full=1", 0, false},
+		{"Default rule", "/ajax/discovery/artworks", "mode=all", 0, false},
+		{"No matching rule", "/ajax/novel/1", "", time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTTL, gotCacheable := cfg.CacheTTLFor(tt.path, tt.query)
+
+			if gotTTL != tt.wantTTL {
+				t.Errorf("CacheTTLFor() gotTTL = %v, want %v", gotTTL, tt.wantTTL)
+			}
+			if gotCacheable != tt.wantCacheable {
+				t.Errorf("CacheTTLFor() gotCacheable = %v, want %v", gotCacheable, tt.wantCacheable)
+			}
+		})
+	}
+}
+
+// TestCacheRuleCompile verifies that invalid rules are rejected.
+func TestCacheRuleCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    CacheRule
+		wantErr bool
+	}{
+		{"Valid prefix", CacheRule{Prefix: "/ajax/", TTL: time.Minute}, false},
+		{"Valid regex", CacheRule{Regex: `^/ajax/\d+$`, TTL: time.Minute}, false},
+		{"NoCache without TTL", CacheRule{Prefix: "/ajax/", NoCache: true}, false},
+		{"Missing target", CacheRule{TTL: time.Minute}, true},
+		{"Both targets", CacheRule{Prefix: "/ajax/", Regex: "^/ajax/", TTL: time.Minute}, true},
+		{"Missing TTL", CacheRule{Prefix: "/ajax/"}, true},
+		{"Invalid regex", CacheRule{Regex: "(", TTL: time.Minute}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.compile()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 	"time"
 	_ "time/tzdata" // Import the timezone database for when the system timezone database is not available
@@ -41,6 +42,30 @@ const (
 	TurnstileDetectionMethod string = "turnstile"
 )
 
+// Tracing.Exporter values.
+const (
+	NoneTracingExporter   string = ""
+	OTLPTracingExporter   string = "otlp"
+	StdoutTracingExporter string = "stdout"
+	FileTracingExporter   string = "file"
+)
+
+// Development.UpstreamMode values.
+const (
+	NetworkUpstreamMode string = ""
+	RecordUpstreamMode  string = "record"
+	ReplayUpstreamMode  string = "replay"
+)
+
+// Cache.Backend values.
+const (
+	MemoryCacheBackend string = "memory"
+	DiskCacheBackend   string = "disk"
+)
+
+// Ugoira.Format values, which are the formats that the ugoira package renders.
+var UgoiraFormats = []string{"webp", "gif", "apng"}
+
 // Configration defaults.
 const (
 	version                                 string        = "v3.0.1"
@@ -50,13 +75,27 @@ const (
 	defaultImageProxyStaging                string        = BuiltInImageProxyPath
 	defaultStaticProxyStaging               string        = BuiltInStaticProxyPath
 	defaultUgoiraProxyStaging               string        = BuiltInUgoiraProxyPath
+	defaultUgoiraFormat                     string        = "webp"
+	defaultUgoiraCachePath                  string        = "/tmp/pixivfe/ugoira"
+	defaultUgoiraCacheMaxBytes              int64         = 1 << 30 // 1 GiB
+	defaultImageTransformEnabled            bool          = false
+	defaultImageTransformCachePath          string        = "/tmp/pixivfe/images"
+	defaultImageTransformCacheMaxBytes      int64         = 1 << 30 // 1 GiB
+	defaultProxyCheckEnabled                bool          = false
+	defaultProxyCheckInterval               time.Duration = 10 * time.Minute
+	defaultProxyCheckTimeout                time.Duration = 5 * time.Second
+	defaultProxyCheckProbePath              string        = "/common/images/no_profile_s.png"
 	defaultTokenLoadBalancing               string        = "round-robin"
 	defaultTokenMaxRetries                  int           = 5
 	defaultTokenBaseTimeout                 time.Duration = 1000 * time.Millisecond
 	defaultTokenMaxBackoffTime              time.Duration = 32000 * time.Millisecond
 	defaultCacheEnabled                     bool          = false
 	defaultCacheSize                        int           = 100
+	defaultCacheMaxBytes                    int64         = 0
 	defaultCacheTTL                         time.Duration = 60 * time.Minute
+	defaultCacheBackend                     string        = MemoryCacheBackend
+	defaultCachePath                        string        = "/tmp/pixivfe/cache"
+	defaultCacheStaleWhileRevalidate        time.Duration = 0
 	defaultCacheControlMaxAge               time.Duration = 30 * time.Second
 	defaultCacheControlStaleWhileRevalidate time.Duration = 60 * time.Second
 	defaultAcceptLanguage                   string        = "en-US,en;q=0.5"
@@ -64,6 +103,8 @@ const (
 	defaultPopularSearchEnabled             bool          = false
 	defaultRepoURL                          string        = "https://codeberg.org/PixivFE/PixivFE"
 	defaultResponseSaveLocation             string        = "/tmp/pixivfe/responses"
+	defaultUpstreamMode                     string        = NetworkUpstreamMode
+	defaultFixturePath                      string        = "/tmp/pixivfe/fixtures"
 	defaultLogLevel                         string        = "info"
 	defaultLogFormat                        string        = "console"
 	defaultLimiterEnabled                   bool          = false
@@ -72,6 +113,10 @@ const (
 	DefaultLimiterIPv6Prefix                int           = 48 // /48 network
 	defaultLimiterCheckHeaders              bool          = true
 	defaultLimiterDetectionMethod           string        = ""
+	defaultMetricsEnabled                   bool          = false
+	defaultTracingExporter                  string        = NoneTracingExporter
+	defaultTracingPath                      string        = "/tmp/pixivfe/traces.jsonl"
+	defaultTracingSampleRatio               float64       = 1
 )
 
 var defaultLogOutputs = []string{"stdout"}
@@ -92,21 +137,50 @@ type ServerConfig struct {
 		RawStatic string  `env:"PIXIVFE_STATICPROXY,overwrite" yaml:"staticProxy"`
 		Static    url.URL // For s.pximg.net
 		RawUgoira string  `env:"PIXIVFE_UGOIRAPROXY,overwrite" yaml:"ugoiraProxy"`
-		Ugoira    url.URL // For ugoira.com
+		Ugoira    url.URL // For ugoira.com, or the built-in renderer
 	}
 
+	// Ugoira configures the built-in renderer, used when ContentProxies.Ugoira is BuiltInUgoiraRendererPath.
+	Ugoira struct {
+		Format        string `env:"PIXIVFE_UGOIRA_FORMAT,overwrite" yaml:"format"`
+		CachePath     string `env:"PIXIVFE_UGOIRA_CACHE_PATH,overwrite" yaml:"cachePath"`
+		CacheMaxBytes int64  `env:"PIXIVFE_UGOIRA_CACHE_MAX_BYTES,overwrite" yaml:"cacheMaxBytes"`
+	}
+
+	// ImageTransform configures resizing and conversion in the built-in image proxy.
+	ImageTransform struct {
+		Enabled       bool   `env:"PIXIVFE_IMAGE_TRANSFORM_ENABLED,overwrite" yaml:"enabled"`
+		CachePath     string `env:"PIXIVFE_IMAGE_TRANSFORM_CACHE_PATH,overwrite" yaml:"cachePath"`
+		CacheMaxBytes int64  `env:"PIXIVFE_IMAGE_TRANSFORM_CACHE_MAX_BYTES,overwrite" yaml:"cacheMaxBytes"`
+	} `yaml:"imageTransform"`
+
+	// ProxyCheck configures the health checker of the image proxies in BuiltInImageProxyList.
+	ProxyCheck struct {
+		Enabled   bool          `env:"PIXIVFE_PROXY_CHECK_ENABLED,overwrite" yaml:"enabled"`
+		Interval  time.Duration `env:"PIXIVFE_PROXY_CHECK_INTERVAL,overwrite" yaml:"interval"`
+		Timeout   time.Duration `env:"PIXIVFE_PROXY_CHECK_TIMEOUT,overwrite" yaml:"timeout"`
+		ProbePath string        `env:"PIXIVFE_PROXY_CHECK_PROBE_PATH,overwrite" yaml:"probePath"`
+	} `yaml:"proxyCheck"`
+
 	TokenManager struct {
 		TokenManager   *tokenmanager.TokenManager
 		LoadBalancing  string        `env:"PIXIVFE_TOKEN_LOAD_BALANCING,overwrite" yaml:"tokenLoadBalancing"`
 		MaxRetries     int           `env:"PIXIVFE_TOKEN_MAX_RETRIES,overwrite" yaml:"tokenMaxRetries"`
 		BaseTimeout    time.Duration `env:"PIXIVFE_TOKEN_BASE_TIMEOUT,overwrite" yaml:"tokenBaseTimeout"`
 		MaxBackoffTime time.Duration `env:"PIXIVFE_TOKEN_MAX_BACKOFF_TIME,overwrite" yaml:"tokenMaxBackoffTime"`
+		StatePath      string        `env:"PIXIVFE_TOKEN_STATE_PATH,overwrite" yaml:"tokenStatePath"`
 	}
 
 	Cache struct {
-		Enabled bool          `env:"PIXIVFE_CACHE_ENABLED,overwrite" yaml:"cacheEnabled"`
-		Size    int           `env:"PIXIVFE_CACHE_SIZE,overwrite" yaml:"cacheSize"`
-		TTL     time.Duration `env:"PIXIVFE_CACHE_TTL,overwrite" yaml:"cacheTTL"`
+		Enabled  bool          `env:"PIXIVFE_CACHE_ENABLED,overwrite" yaml:"cacheEnabled"`
+		Size     int           `env:"PIXIVFE_CACHE_SIZE,overwrite" yaml:"cacheSize"`
+		MaxBytes int64         `env:"PIXIVFE_CACHE_MAX_BYTES,overwrite" yaml:"cacheMaxBytes"`
+		TTL      time.Duration `env:"PIXIVFE_CACHE_TTL,overwrite" yaml:"cacheTTL"`
+		Backend  string        `env:"PIXIVFE_CACHE_BACKEND,overwrite" yaml:"cacheBackend"`
+		Path     string        `env:"PIXIVFE_CACHE_PATH,overwrite" yaml:"cachePath"`
+		Rules    []CacheRule   `yaml:"cacheRules"`
+
+		StaleWhileRevalidate time.Duration `env:"PIXIVFE_CACHE_STALE_WHILE_REVALIDATE,overwrite" yaml:"cacheStaleWhileRevalidate"`
 	}
 
 	HTTPCache struct {
@@ -124,7 +198,8 @@ type ServerConfig struct {
 	}
 
 	Feature struct {
-		PopularSearchEnabled bool `env:"PIXIVFE_POPULAR_SEARCH_ENABLED,overwrite" yaml:"popularSearchEnabled"`
+		PopularSearchEnabled bool   `env:"PIXIVFE_POPULAR_SEARCH_ENABLED,overwrite" yaml:"popularSearchEnabled"`
+		FeedSecret           string `env:"PIXIVFE_FEED_SECRET" yaml:"feedSecret"`
 	}
 
 	Instance struct {
@@ -140,12 +215,15 @@ type ServerConfig struct {
 	Development struct {
 		InDevelopment        bool   `env:"PIXIVFE_DEV" yaml:"inDevelopment"`
 		ResponseSaveLocation string `env:"PIXIVFE_RESPONSE_SAVE_LOCATION,overwrite" yaml:"responseSaveLocation"`
+		UpstreamMode         string `env:"PIXIVFE_UPSTREAM_MODE,overwrite" yaml:"upstreamMode"`
+		FixturePath          string `env:"PIXIVFE_FIXTURE_PATH,overwrite" yaml:"fixturePath"`
 	}
 
 	Log struct {
-		Level   string   `env:"PIXIVFE_LOG_LEVEL,overwrite" yaml:"logLevel"`
-		Outputs []string `env:"PIXIVFE_LOG_OUTPUTS,overwrite" yaml:"logOutputs"`
-		Format  string   `env:"PIXIVFE_LOG_FORMAT,overwrite" yaml:"logFormat"`
+		Level             string   `env:"PIXIVFE_LOG_LEVEL,overwrite" yaml:"logLevel"`
+		Outputs           []string `env:"PIXIVFE_LOG_OUTPUTS,overwrite" yaml:"logOutputs"`
+		Format            string   `env:"PIXIVFE_LOG_FORMAT,overwrite" yaml:"logFormat"`
+		TranslationMisses bool     `env:"PIXIVFE_LOG_TRANSLATION_MISSES,overwrite" yaml:"logTranslationMisses"`
 	}
 
 	Limiter struct {
@@ -161,28 +239,57 @@ type ServerConfig struct {
 		TurnstileSitekey   string   `env:"PIXIVFE_LIMITER_TURNSTILE_SITEKEY" yaml:"turnstileSitekey"`
 		TurnstileSecretKey string   `env:"PIXIVFE_LIMITER_TURNSTILE_SECRET_KEY" yaml:"turnstileSecretKey"`
 	}
+
+	Admin struct {
+		Token string `env:"PIXIVFE_ADMIN_TOKEN" yaml:"adminToken"`
+	}
+
+	Metrics struct {
+		Enabled bool `env:"PIXIVFE_METRICS_ENABLED,overwrite" yaml:"enabled"`
+	}
+
+	Tracing struct {
+		Exporter    string  `env:"PIXIVFE_TRACING_EXPORTER,overwrite" yaml:"exporter"`
+		Endpoint    string  `env:"PIXIVFE_TRACING_ENDPOINT,overwrite" yaml:"endpoint"`
+		Path        string  `env:"PIXIVFE_TRACING_PATH,overwrite" yaml:"path"`
+		SampleRatio float64 `env:"PIXIVFE_TRACING_SAMPLE_RATIO,overwrite" yaml:"sampleRatio"`
+	}
 }
 
+// GetToken returns the value of a token selected by the token manager.
+//
+// Tokens are read from the token manager rather than from Basic.Token,
+// since the token pool can be swapped by Reload while serving requests.
 func (cfg *ServerConfig) GetToken() string {
-	token := cfg.TokenManager.TokenManager.GetToken()
+	token := cfg.availableToken()
 	if token == nil {
-		log.Println("[WARNING] All tokens are timed out. Using the first available token.")
-
-		return cfg.Basic.Token[0]
+		return ""
 	}
 
 	return token.Value
 }
 
+// GetP_AB returns the personal ID of a token selected by the token manager.
 func (cfg *ServerConfig) GetP_AB() string {
+	token := cfg.availableToken()
+	if token == nil {
+		return ""
+	}
+
+	return token.P_AB
+}
+
+// availableToken returns a token selected by the token manager, or the first
+// token in the pool if all tokens are timed out.
+func (cfg *ServerConfig) availableToken() *tokenmanager.Token {
 	token := cfg.TokenManager.TokenManager.GetToken()
 	if token == nil {
 		log.Println("[WARNING] All tokens are timed out. Using the first available token.")
 
-		return cfg.Basic.Token[0]
+		return cfg.TokenManager.TokenManager.FirstToken()
 	}
 
-	return token.P_AB
+	return token
 }
 
 func (cfg *ServerConfig) LoadConfig() error {
@@ -195,6 +302,22 @@ func (cfg *ServerConfig) LoadConfig() error {
 
 	cfg.setInstanceInfo()
 
+	if err := cfg.load(); err != nil {
+		return err
+	}
+
+	if err := cfg.initComponents(); err != nil {
+		return fmt.Errorf("component initialization failed: %w", err)
+	}
+
+	cfg.printConfiguration()
+
+	return nil
+}
+
+// load populates cfg from the default values, the configuration file
+// and environment variables, in that order, then validates the result.
+func (cfg *ServerConfig) load() error {
 	cfg.loadFromDefaults()
 
 	if err := cfg.loadFromYAML(); err != nil {
@@ -209,10 +332,6 @@ func (cfg *ServerConfig) LoadConfig() error {
 		return fmt.Errorf("configuration validation and setting failed: %w", err)
 	}
 
-	cfg.initComponents()
-
-	cfg.printConfiguration()
-
 	return nil
 }
 
@@ -240,18 +359,34 @@ func (cfg *ServerConfig) loadFromDefaults() {
 	cfg.ContentProxies.RawImage = defaultImageProxyStaging
 	cfg.ContentProxies.RawStatic = defaultStaticProxyStaging
 	cfg.ContentProxies.RawUgoira = defaultUgoiraProxyStaging
+	cfg.Ugoira.Format = defaultUgoiraFormat
+	cfg.Ugoira.CachePath = defaultUgoiraCachePath
+	cfg.Ugoira.CacheMaxBytes = defaultUgoiraCacheMaxBytes
+	cfg.ImageTransform.Enabled = defaultImageTransformEnabled
+	cfg.ImageTransform.CachePath = defaultImageTransformCachePath
+	cfg.ImageTransform.CacheMaxBytes = defaultImageTransformCacheMaxBytes
+	cfg.ProxyCheck.Enabled = defaultProxyCheckEnabled
+	cfg.ProxyCheck.Interval = defaultProxyCheckInterval
+	cfg.ProxyCheck.Timeout = defaultProxyCheckTimeout
+	cfg.ProxyCheck.ProbePath = defaultProxyCheckProbePath
 	cfg.TokenManager.LoadBalancing = defaultTokenLoadBalancing
 	cfg.TokenManager.MaxRetries = defaultTokenMaxRetries
 	cfg.TokenManager.BaseTimeout = defaultTokenBaseTimeout
 	cfg.TokenManager.MaxBackoffTime = defaultTokenMaxBackoffTime
 	cfg.Cache.Enabled = defaultCacheEnabled
 	cfg.Cache.Size = defaultCacheSize
+	cfg.Cache.MaxBytes = defaultCacheMaxBytes
 	cfg.Cache.TTL = defaultCacheTTL
+	cfg.Cache.Backend = defaultCacheBackend
+	cfg.Cache.Path = defaultCachePath
+	cfg.Cache.StaleWhileRevalidate = defaultCacheStaleWhileRevalidate
 	cfg.HTTPCache.MaxAge = defaultCacheControlMaxAge
 	cfg.HTTPCache.StaleWhileRevalidate = defaultCacheControlStaleWhileRevalidate
 	cfg.Response.EarlyHintsResponsesEnabled = defaultEarlyHintsResponsesEnabled
 	cfg.Feature.PopularSearchEnabled = defaultPopularSearchEnabled
 	cfg.Development.ResponseSaveLocation = defaultResponseSaveLocation
+	cfg.Development.UpstreamMode = defaultUpstreamMode
+	cfg.Development.FixturePath = defaultFixturePath
 	cfg.Log.Level = defaultLogLevel
 	cfg.Log.Outputs = defaultLogOutputs
 	cfg.Log.Format = defaultLogFormat
@@ -261,6 +396,10 @@ func (cfg *ServerConfig) loadFromDefaults() {
 	cfg.Limiter.FilterLocal = DefaultLimiterFilterLocal
 	cfg.Limiter.IPv4Prefix = DefaultLimiterIPv4Prefix
 	cfg.Limiter.IPv6Prefix = DefaultLimiterIPv6Prefix
+	cfg.Metrics.Enabled = defaultMetricsEnabled
+	cfg.Tracing.Exporter = defaultTracingExporter
+	cfg.Tracing.Path = defaultTracingPath
+	cfg.Tracing.SampleRatio = defaultTracingSampleRatio
 }
 
 func (cfg *ServerConfig) loadFromYAML() error {
@@ -326,16 +465,25 @@ func (cfg *ServerConfig) validateAndSet() error {
 		return errors.New("no token supplied. Please supply at least one token")
 	}
 
-	// Validate image proxy
-	if err := validateProxy(&cfg.ContentProxies.RawImage, BuiltInImageProxyPath, "image"); err != nil {
-		return err
-	}
+	// Validate image proxy, which may also be resolved automatically
+	switch cfg.ContentProxies.RawImage {
+	case AutoImageProxy:
+		if !cfg.ProxyCheck.Enabled {
+			return fmt.Errorf("image proxy %s requires ProxyCheck.Enabled", AutoImageProxy)
+		}
 
-	if cfg.ContentProxies.RawImage == BuiltInImageProxyPath {
-		cfg.ContentProxies.Image = url.URL{Path: BuiltInImageProxyPath}
-	} else {
-		parsedURL, _ := url.Parse(cfg.ContentProxies.RawImage)
-		cfg.ContentProxies.Image = *parsedURL
+		cfg.ContentProxies.Image = url.URL{Path: AutoImageProxy}
+	default:
+		if err := validateProxy(&cfg.ContentProxies.RawImage, BuiltInImageProxyPath, "image"); err != nil {
+			return err
+		}
+
+		if cfg.ContentProxies.RawImage == BuiltInImageProxyPath {
+			cfg.ContentProxies.Image = url.URL{Path: BuiltInImageProxyPath}
+		} else {
+			parsedURL, _ := url.Parse(cfg.ContentProxies.RawImage)
+			cfg.ContentProxies.Image = *parsedURL
+		}
 	}
 
 	// Validate static proxy
@@ -350,20 +498,57 @@ func (cfg *ServerConfig) validateAndSet() error {
 		cfg.ContentProxies.Static = *parsedURL
 	}
 
-	// Validate ugoira proxy
-	if err := validateProxy(&cfg.ContentProxies.RawUgoira, BuiltInUgoiraProxyPath, "ugoira"); err != nil {
-		return err
-	}
+	// Validate ugoira proxy, which may also be the built-in renderer
+	switch cfg.ContentProxies.RawUgoira {
+	case BuiltInUgoiraProxyPath, BuiltInUgoiraRendererPath:
+		cfg.ContentProxies.Ugoira = url.URL{Path: cfg.ContentProxies.RawUgoira}
+	default:
+		if err := validateProxy(&cfg.ContentProxies.RawUgoira, BuiltInUgoiraProxyPath, "ugoira"); err != nil {
+			return err
+		}
 
-	if cfg.ContentProxies.RawUgoira == BuiltInUgoiraProxyPath {
-		cfg.ContentProxies.Ugoira = url.URL{Path: BuiltInUgoiraProxyPath}
-	} else {
 		parsedURL, _ := url.Parse(cfg.ContentProxies.RawUgoira)
 		cfg.ContentProxies.Ugoira = *parsedURL
 	}
 
+	// Validate the ugoira renderer
+	if !slices.Contains(UgoiraFormats, cfg.Ugoira.Format) {
+		return fmt.Errorf("invalid Ugoira.Format value: %s (must be one of %v)", cfg.Ugoira.Format, UgoiraFormats)
+	}
+
+	if cfg.Ugoira.CachePath == "" {
+		return errors.New("Ugoira.CachePath is required")
+	}
+
+	if cfg.Ugoira.CacheMaxBytes <= 0 {
+		return fmt.Errorf("Ugoira.CacheMaxBytes must be positive, got %d", cfg.Ugoira.CacheMaxBytes)
+	}
+
+	// Validate the proxy health checker
+	if cfg.ProxyCheck.Enabled {
+		if cfg.ProxyCheck.Interval <= 0 || cfg.ProxyCheck.Timeout <= 0 {
+			return fmt.Errorf("ProxyCheck.Interval and ProxyCheck.Timeout must be positive, got %s and %s",
+				cfg.ProxyCheck.Interval, cfg.ProxyCheck.Timeout)
+		}
+
+		if !strings.HasPrefix(cfg.ProxyCheck.ProbePath, "/") {
+			return fmt.Errorf("ProxyCheck.ProbePath must start with /, got %s", cfg.ProxyCheck.ProbePath)
+		}
+	}
+
+	// Validate image transformations
+	if cfg.ImageTransform.Enabled {
+		if cfg.ImageTransform.CachePath == "" {
+			return errors.New("ImageTransform.CachePath is required")
+		}
+
+		if cfg.ImageTransform.CacheMaxBytes <= 0 {
+			return fmt.Errorf("ImageTransform.CacheMaxBytes must be positive, got %d", cfg.ImageTransform.CacheMaxBytes)
+		}
+	}
+
 	// Validate RepoURL
-	repoURL, err := utils.ValidateURL(cfg.Instance.RepoURL, "Repo")
+	repoURL, err := utils.ValidateURL(context.Background(), cfg.Instance.RepoURL, "Repo")
 	if err != nil {
 		return fmt.Errorf("invalid repo URL: %w", err)
 	}
@@ -379,6 +564,68 @@ func (cfg *ServerConfig) validateAndSet() error {
 			cfg.TokenManager.LoadBalancing)
 	}
 
+	// Validate Cache.Backend
+	switch cfg.Cache.Backend {
+	case MemoryCacheBackend:
+		// valid
+	case DiskCacheBackend:
+		if cfg.Cache.Path == "" {
+			return fmt.Errorf("Cache.Path is required when Cache.Backend is %q", DiskCacheBackend)
+		}
+	default:
+		return fmt.Errorf("invalid Cache.Backend value: %s (must be %q or %q)",
+			cfg.Cache.Backend, MemoryCacheBackend, DiskCacheBackend)
+	}
+
+	if cfg.Cache.MaxBytes < 0 {
+		return fmt.Errorf("Cache.MaxBytes must not be negative, got %d", cfg.Cache.MaxBytes)
+	}
+
+	if cfg.Cache.StaleWhileRevalidate < 0 {
+		return fmt.Errorf("Cache.StaleWhileRevalidate must not be negative, got %s", cfg.Cache.StaleWhileRevalidate)
+	}
+
+	// Validate Tracing.Exporter
+	switch cfg.Tracing.Exporter {
+	case NoneTracingExporter, OTLPTracingExporter, StdoutTracingExporter:
+		// valid
+	case FileTracingExporter:
+		if cfg.Tracing.Path == "" {
+			return fmt.Errorf("Tracing.Path is required when Tracing.Exporter is %q", FileTracingExporter)
+		}
+	default:
+		return fmt.Errorf("invalid Tracing.Exporter value: %s (must be %q, %q or %q)",
+			cfg.Tracing.Exporter, OTLPTracingExporter, StdoutTracingExporter, FileTracingExporter)
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("Tracing.SampleRatio must be between 0 and 1, got %v", cfg.Tracing.SampleRatio)
+	}
+
+	// FeedSecret is optional, but must be long enough to derive a key from if set
+	if cfg.Feature.FeedSecret != "" && len(cfg.Feature.FeedSecret) < 32 {
+		return errors.New("Feature.FeedSecret must be at least 32 characters long")
+	}
+
+	// Validate Development.UpstreamMode
+	switch cfg.Development.UpstreamMode {
+	case NetworkUpstreamMode:
+		// valid
+	case RecordUpstreamMode, ReplayUpstreamMode:
+		if cfg.Development.FixturePath == "" {
+			return fmt.Errorf("Development.FixturePath is required when Development.UpstreamMode is %q",
+				cfg.Development.UpstreamMode)
+		}
+	default:
+		return fmt.Errorf("invalid Development.UpstreamMode value: %s (must be %q or %q)",
+			cfg.Development.UpstreamMode, RecordUpstreamMode, ReplayUpstreamMode)
+	}
+
+	// Validate Cache.Rules
+	if err := cfg.setCacheRules(); err != nil {
+		return fmt.Errorf("invalid Cache.Rules: %w", err)
+	}
+
 	// Skip validating Limiter configuration if it's not enabled
 	if !cfg.Limiter.Enabled {
 		return nil
@@ -440,8 +687,16 @@ func (cfg *ServerConfig) validateAndSet() error {
 	return nil
 }
 
-func (cfg *ServerConfig) initComponents() {
+func (cfg *ServerConfig) initComponents() error {
 	cfg.TokenManager.TokenManager = tokenmanager.NewTokenManager(cfg.Basic.Token, cfg.TokenManager.MaxRetries, cfg.TokenManager.BaseTimeout, cfg.TokenManager.MaxBackoffTime, cfg.TokenManager.LoadBalancing)
+
+	if cfg.TokenManager.StatePath != "" {
+		if err := cfg.TokenManager.TokenManager.PersistState(cfg.TokenManager.StatePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // parseRevision extracts RevisionDate, RevisionHash, and IsDirty status from the Revision string.
@@ -473,7 +728,7 @@ func validateProxy(rawURL *string, defaultPath string, proxyType string) error {
 		return nil
 	}
 
-	_, err := utils.ValidateURL(*rawURL, proxyType+" proxy server")
+	_, err := utils.ValidateURL(context.Background(), *rawURL, proxyType+" proxy server")
 	if err != nil {
 		return fmt.Errorf("invalid %s proxy URL: %w", proxyType, err)
 	}
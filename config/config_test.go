@@ -4,6 +4,7 @@
 package config
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
@@ -85,7 +86,7 @@ func TestValidateURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := utils.ValidateURL(tt.urlStr, tt.urlType)
+			got, err := utils.ValidateURL(context.Background(), tt.urlStr, tt.urlType)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("utils.ValidateURL() error = %v, wantErr %v", err, tt.wantErr)
@@ -26,6 +26,8 @@ var skippedFields = []string{
 	"ContentProxies.RawUgoira",
 	"Limiter.PingHMAC",
 	"Limiter.TurnstileSecretKey",
+	"Feature.FeedSecret",
+	"Admin.Token",
 }
 
 // printConfiguration prints the server configuration.
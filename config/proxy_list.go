@@ -7,6 +7,10 @@ const (
 	BuiltInImageProxyPath  = "/proxy/i.pximg.net" // built-in proxy route for i.pximg.net
 	BuiltInStaticProxyPath = "/proxy/s.pximg.net" // built-in proxy route for s.pximg.net
 	BuiltInUgoiraProxyPath = "/proxy/ugoira.com"  // built-in proxy route for ugoira.com
+
+	BuiltInUgoiraRendererPath = "/ugoira" // built-in route rendering ugoira from pixiv's frame archives
+
+	AutoImageProxy = "auto" // image proxy resolved to the fastest healthy proxy in BuiltInImageProxyList
 )
 
 // the list of proxies on /settings.
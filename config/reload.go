@@ -0,0 +1,130 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// reloadMu serializes calls to Reload.
+var reloadMu sync.Mutex
+
+// ReloadResult describes the changes applied by Reload.
+type ReloadResult struct {
+	TokensAdded     int      // Number of tokens added to the token pool
+	TokensRemoved   int      // Number of tokens removed from the token pool
+	RestartRequired []string // Names of the changed sections that require a restart to take effect
+}
+
+// Reload re-reads the configuration file and environment variables, and applies
+// the token pool and token management settings without a restart.
+//
+// The new configuration is validated in full before anything is applied.
+// If it's invalid, an error is returned and the current configuration stays active.
+//
+// The token pool is swapped by the TokenManager, which has its own lock, and
+// Basic.Token and the token management settings of cfg are updated to match.
+// While serving requests, tokens are only read from the TokenManager, such as by
+// GetToken and GetP_AB, and these fields are only read by Reload itself, which is
+// serialized by reloadMu. Changes to any other section are reported in the result
+// as requiring a restart to take effect.
+func (cfg *ServerConfig) Reload() (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	var next ServerConfig
+
+	// Instance information is determined at startup, and isn't loaded from the configuration.
+	next.Instance = cfg.Instance
+
+	if err := next.load(); err != nil {
+		return ReloadResult{}, fmt.Errorf("invalid configuration, keeping the current one: %w", err)
+	}
+
+	result := ReloadResult{RestartRequired: cfg.restartRequiredChanges(&next)}
+
+	result.TokensAdded, result.TokensRemoved = cfg.TokenManager.TokenManager.Reconfigure(
+		next.Basic.Token,
+		next.TokenManager.MaxRetries,
+		next.TokenManager.BaseTimeout,
+		next.TokenManager.MaxBackoffTime,
+		next.TokenManager.LoadBalancing,
+	)
+
+	cfg.Basic.Token = next.Basic.Token
+	cfg.TokenManager.LoadBalancing = next.TokenManager.LoadBalancing
+	cfg.TokenManager.MaxRetries = next.TokenManager.MaxRetries
+	cfg.TokenManager.BaseTimeout = next.TokenManager.BaseTimeout
+	cfg.TokenManager.MaxBackoffTime = next.TokenManager.MaxBackoffTime
+
+	return result, nil
+}
+
+// restartRequiredChanges returns the names of the configuration sections
+// that differ between cfg and next, other than those applied by Reload.
+//
+// Only the fields set from the configuration file or environment variables are
+// compared, so values derived from them, such as parsed URLs and compiled
+// regular expressions, are ignored.
+func (cfg *ServerConfig) restartRequiredChanges(next *ServerConfig) []string {
+	current, updated := *cfg, *next
+
+	// Exclude the fields applied by Reload.
+	current.Basic.Token = updated.Basic.Token
+	current.TokenManager.LoadBalancing = updated.TokenManager.LoadBalancing
+	current.TokenManager.MaxRetries = updated.TokenManager.MaxRetries
+	current.TokenManager.BaseTimeout = updated.TokenManager.BaseTimeout
+	current.TokenManager.MaxBackoffTime = updated.TokenManager.MaxBackoffTime
+
+	currentValue := reflect.ValueOf(current)
+	updatedValue := reflect.ValueOf(updated)
+
+	var sections []string
+
+	for i := range currentValue.NumField() {
+		if !reflect.DeepEqual(configuredValues(currentValue.Field(i)), configuredValues(updatedValue.Field(i))) {
+			sections = append(sections, currentValue.Type().Field(i).Name)
+		}
+	}
+
+	return sections
+}
+
+// configuredValues returns the values of the fields of the struct v that are set
+// from the configuration file or environment variables, which are those with a
+// yaml or env tag, descending into structs and slices of structs.
+func configuredValues(v reflect.Value) []any {
+	var values []any
+
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+
+		_, hasYAML := field.Tag.Lookup("yaml")
+		_, hasEnv := field.Tag.Lookup("env")
+
+		if !field.IsExported() || (!hasYAML && !hasEnv) {
+			continue
+		}
+
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			values = append(values, configuredValues(value))
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			elements := make([]any, value.Len())
+			for j := range value.Len() {
+				elements[j] = configuredValues(value.Index(j))
+			}
+
+			values = append(values, elements)
+		default:
+			values = append(values, value.Interface())
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/server/tokenmanager"
+)
+
+// TestReload verifies that Reload swaps the token pool while keeping the metadata
+// of unchanged tokens, and that invalid configurations are rejected.
+func TestReload(t *testing.T) {
+	restoreLogger := setupTestLogger()
+	defer restoreLogger()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	t.Setenv("PIXIVFE_CONFIGFILE", path)
+
+	writeConfig := func(content string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+
+	// Cache rules with a regex and a proxy URL have values derived from them,
+	// which must not be reported as changes.
+	rules := "contentproxies:\n  imageProxy: https://proxy.example.com\n" +
+		"cache:\n  cacheRules:\n    - regex: '^/ajax/user/\\d+$'\n      ttl: 1m\n"
+
+	writeConfig("basic:\n  token: [token1, token2]\n" + rules)
+
+	cfg := &ServerConfig{}
+	if err := cfg.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tokenManager := cfg.TokenManager.TokenManager
+	tokenManager.MarkTokenStatus(tokenManager.GetToken(), tokenmanager.TimedOut)
+
+	// Replace token2 with token3, without changing anything else.
+	writeConfig("basic:\n  token: [token1, token3]\n" + rules)
+
+	next := ServerConfig{Instance: cfg.Instance}
+	if err := next.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	if sections := cfg.restartRequiredChanges(&next); len(sections) != 0 {
+		t.Errorf("restartRequiredChanges() = %v, want none", sections)
+	}
+
+	// Also change a setting that requires a restart, and one that doesn't.
+	writeConfig("basic:\n  token: [token1, token3]\ntokenmanager:\n  tokenMaxRetries: 7\n" +
+		rules + "  cacheSize: 5\n")
+
+	result, err := cfg.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if result.TokensAdded != 1 || result.TokensRemoved != 1 {
+		t.Errorf("Reload() added %d and removed %d tokens, want 1 and 1", result.TokensAdded, result.TokensRemoved)
+	}
+
+	if !slices.Equal(result.RestartRequired, []string{"Cache"}) {
+		t.Errorf("Reload() RestartRequired = %v, want [Cache]", result.RestartRequired)
+	}
+
+	if !slices.Equal(cfg.Basic.Token, []string{"token1", "token3"}) || cfg.TokenManager.MaxRetries != 7 {
+		t.Errorf("Reload() did not update the configuration: token %v, max retries %d",
+			cfg.Basic.Token, cfg.TokenManager.MaxRetries)
+	}
+
+	snapshots := tokenManager.Snapshot()
+	if len(snapshots) != 2 {
+		t.Fatalf("Reload() token count = %d, want 2", len(snapshots))
+	}
+
+	if snapshots[0].Status != tokenmanager.TimedOut || snapshots[0].FailureCount != 1 {
+		t.Errorf("Reload() did not keep the state of token1: %+v", snapshots[0])
+	}
+
+	if snapshots[1].Status != tokenmanager.Good || snapshots[1].FailureCount != 0 {
+		t.Errorf("Reload() did not add token3 in a good state: %+v", snapshots[1])
+	}
+
+	// An invalid configuration must leave the token pool untouched.
+	writeConfig("basic:\n  token: [token4]\n  timeZone: Invalid/Zone\n")
+
+	if _, err := cfg.Reload(); err == nil {
+		t.Error("Reload() expected an error for an invalid configuration")
+	}
+
+	if got := tokenManager.Len(); got != 2 {
+		t.Errorf("Reload() token count after invalid reload = %d, want 2", got)
+	}
+}
+
+// TestReload_Concurrent verifies that Reload can run while tokens are being read
+// for requests, including the fallback used when no token is usable.
+// Run with -race to detect unsynchronized access.
+func TestReload_Concurrent(t *testing.T) {
+	restoreLogger := setupTestLogger()
+	defer restoreLogger()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	t.Setenv("PIXIVFE_CONFIGFILE", path)
+
+	if err := os.WriteFile(path, []byte("basic:\n  token: [token1]\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg := &ServerConfig{}
+	if err := cfg.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	// With every token invalid, the first token is used as a fallback.
+	tokenManager := cfg.TokenManager.TokenManager
+	tokenManager.MarkTokenStatus(tokenManager.GetToken(), tokenmanager.Invalid)
+
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					if cfg.GetP_AB() == "" || cfg.GetToken() == "" {
+						t.Error("expected a token while reloading")
+
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range 20 {
+		content := fmt.Sprintf("basic:\n  token: [token1]\ntokenmanager:\n  tokenMaxRetries: %d\n", i+1)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		if _, err := cfg.Reload(); err != nil {
+			t.Fatalf("Reload() error = %v", err)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
@@ -4,6 +4,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -37,14 +38,14 @@ const (
 	R18G XRestrict = 2
 )
 
-func (x XRestrict) String() (string, error) {
+func (x XRestrict) String(ctx context.Context) (string, error) {
 	switch x {
 	case Safe:
-		return i18n.Tr("Safe"), nil
+		return i18n.TrContext(ctx, "Safe"), nil
 	case R18:
-		return i18n.Tr("R18"), nil
+		return i18n.TrContext(ctx, "R18"), nil
 	case R18G:
-		return i18n.Tr("R18G"), nil
+		return i18n.TrContext(ctx, "R18G"), nil
 	}
 
 	return "", fmt.Errorf("%w: %d", ErrInvalidXRestrict, int(x))
@@ -63,14 +64,14 @@ const (
 	AI      AiType = 2 //nolint:varnamelen
 )
 
-func (x AiType) String() (string, error) {
+func (x AiType) String(ctx context.Context) (string, error) {
 	switch x {
 	case Unrated:
-		return i18n.Tr("Unrated"), nil
+		return i18n.TrContext(ctx, "Unrated"), nil
 	case NotAI:
-		return i18n.Tr("Not AI"), nil
+		return i18n.TrContext(ctx, "Not AI"), nil
 	case AI:
-		return i18n.Tr("AI"), nil
+		return i18n.TrContext(ctx, "AI"), nil
 	}
 
 	return "", fmt.Errorf("%w: %d", ErrInvalidAiType, int(x))
@@ -155,6 +156,8 @@ type ArtworkBrief struct {
 	Tags         []string      `json:"tags"`     // used by core/popular_search
 	SeriesID     string        `json:"seriesId"` // used by core/mangaseries
 	SeriesTitle  string        `json:"seriesTitle"`
+	CreateDate   time.Time     `json:"createDate"`
+	UpdateDate   time.Time     `json:"updateDate"`
 	Thumbnails   Thumbnails
 	Width        int
 	Height       int
@@ -359,11 +362,7 @@ func GetArtwork(w http.ResponseWriter, r *http.Request, artworkID string) (*Illu
 	}
 
 	if illust.IllustType == Ugoira {
-		proxy := session.GetProxyPrefix(session.GetUgoiraProxy(r))
-
-		if len(illust.Images) > 0 {
-			illust.Images[0].Video = proxy + "/ugoira/" + illust.ID
-		}
+		setUgoiraRendition(r, &illust)
 	}
 
 	// Write timing headers and total duration
@@ -473,10 +472,7 @@ func GetArtworkFast(w http.ResponseWriter, r *http.Request, params FastIllustPar
 	}
 
 	if illust.IllustType == Ugoira {
-		proxy := session.GetProxyPrefix(session.GetUgoiraProxy(r))
-		if len(illust.Images) > 0 {
-			illust.Images[0].Video = proxy + "/ugoira/" + illust.ID
-		}
+		setUgoiraRendition(r, &illust)
 	}
 
 	// Add timing headers
@@ -0,0 +1,259 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"encoding/xml"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"golang.org/x/sync/errgroup"
+)
+
+// mangaSeriesImagesConcurrency limits how many artworks of a manga series have
+// their images retrieved at once.
+const mangaSeriesImagesConcurrency = 4
+
+// MangaSeriesWork is an artwork of a manga series, along with its images.
+type MangaSeriesWork struct {
+	ArtworkBrief
+
+	Order  int // Position of the artwork in the series, starting from 1
+	Images []Thumbnails
+}
+
+// GetArtworkWithImages retrieves the basic information of an artwork along with
+// its images, without the related data shown on the artwork page.
+func GetArtworkWithImages(r *http.Request, artworkID string) (*Illust, error) {
+	var illust Illust
+
+	if err := GetBasicArtwork(r, artworkID, &illust); err != nil {
+		return nil, err
+	}
+
+	images, err := getArtworkImages(r, artworkID, illust.IllustType)
+	if err != nil {
+		return nil, err
+	}
+
+	illust.Images = images
+
+	return &illust, nil
+}
+
+// GetMangaSeriesWorks retrieves a manga series along with every artwork in it,
+// in series order, and their images.
+func GetMangaSeriesWorks(r *http.Request, seriesID string) (IllustSeries, []MangaSeriesWork, error) {
+	var (
+		series   IllustSeries
+		entries  []SeriesEntry
+		artworks = make(map[string]ArtworkBrief)
+	)
+
+	// Series pages don't state how many pages there are, so fetch them until
+	// every artwork has been seen
+	for page := 1; ; page++ {
+		mangaSeries, err := GetMangaSeriesByID(r, seriesID, page)
+		if err != nil {
+			return series, nil, err
+		}
+
+		idx := slices.IndexFunc(mangaSeries.IllustSeries, func(s IllustSeries) bool {
+			return s.ID == seriesID
+		})
+		if idx == -1 {
+			return series, nil, i18n.ErrorfContext(r.Context(), "Invalid series ID: %s", seriesID)
+		}
+
+		series = mangaSeries.IllustSeries[idx]
+
+		for _, artwork := range series.List {
+			artworks[artwork.ID] = artwork
+		}
+
+		entries = append(entries, mangaSeries.Page.Series...)
+
+		if len(mangaSeries.Page.Series) == 0 || len(entries) >= series.Total {
+			break
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b SeriesEntry) int {
+		return a.Order - b.Order
+	})
+
+	works := make([]MangaSeriesWork, 0, len(entries))
+
+	for _, entry := range entries {
+		// Artworks that can't be viewed are listed without their data
+		if artwork, ok := artworks[entry.WorkID]; ok {
+			works = append(works, MangaSeriesWork{ArtworkBrief: artwork, Order: entry.Order})
+		}
+	}
+
+	g, _ := errgroup.WithContext(r.Context())
+	g.SetLimit(mangaSeriesImagesConcurrency)
+
+	for i := range works {
+		g.Go(func() error {
+			images, err := getArtworkImages(r, works[i].ID, IllustType(works[i].IllustType))
+			if err != nil {
+				return err
+			}
+
+			works[i].Images = images
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return series, nil, err
+	}
+
+	return series, works, nil
+}
+
+// ComicInfo is the metadata of a comic book archive, in the ComicInfo.xml format
+// of the Anansi Project that comic book readers support.
+type ComicInfo struct {
+	XMLName   xml.Name        `xml:"ComicInfo"`
+	Title     string          `xml:"Title,omitempty"`
+	Series    string          `xml:"Series,omitempty"`
+	Count     int             `xml:"Count,omitempty"`
+	Year      int             `xml:"Year,omitempty"`
+	Month     int             `xml:"Month,omitempty"`
+	Day       int             `xml:"Day,omitempty"`
+	Writer    string          `xml:"Writer,omitempty"`
+	Tags      string          `xml:"Tags,omitempty"`
+	Web       string          `xml:"Web,omitempty"`
+	PageCount int             `xml:"PageCount"`
+	Manga     string          `xml:"Manga,omitempty"`
+	AgeRating string          `xml:"AgeRating,omitempty"`
+	Pages     []ComicInfoPage `xml:"Pages>Page,omitempty"`
+}
+
+// ComicInfoPage is the metadata of a page of a comic book archive.
+type ComicInfoPage struct {
+	Image    int    `xml:"Image,attr"`              // Index of the page, starting from 0
+	Type     string `xml:"Type,attr,omitempty"`     // Such as FrontCover or Story
+	Bookmark string `xml:"Bookmark,attr,omitempty"` // Title of the chapter starting at the page
+}
+
+// NewArtworkComicInfo returns the ComicInfo.xml metadata of an archive of the
+// pages of illust.
+func NewArtworkComicInfo(illust *Illust) ComicInfo {
+	tags := make([]string, 0, len(illust.Tags.Tags))
+	for _, tag := range illust.Tags.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	info := ComicInfo{
+		Title:     illust.Title,
+		Writer:    illust.UserName,
+		Tags:      strings.Join(tags, ","),
+		Web:       "https://www.pixiv.net/artworks/" + illust.ID,
+		Manga:     comicInfoManga(illust.IllustType),
+		AgeRating: comicInfoAgeRating(illust.XRestrict),
+	}
+
+	for range illust.Images {
+		info.addPage("")
+	}
+
+	info.setDate(illust.Date)
+
+	return info
+}
+
+// NewMangaSeriesComicInfo returns the ComicInfo.xml metadata of an archive of the
+// pages of every artwork in a manga series, with each artwork bookmarked.
+func NewMangaSeriesComicInfo(series IllustSeries, works []MangaSeriesWork) ComicInfo {
+	info := ComicInfo{
+		Title:  series.Title,
+		Series: series.Title,
+		Count:  len(works),
+		Web:    "https://www.pixiv.net/user/" + series.UserID + "/series/" + series.ID,
+		Manga:  comicInfoManga(Manga),
+	}
+
+	var (
+		tags      []string
+		xRestrict XRestrict
+	)
+
+	for _, work := range works {
+		info.Writer = work.UserName
+
+		for _, tag := range work.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+
+		xRestrict = max(xRestrict, work.XRestrict)
+
+		for i := range work.Images {
+			var bookmark string
+			if i == 0 {
+				bookmark = "#" + strconv.Itoa(work.Order) + " " + work.Title
+			}
+
+			info.addPage(bookmark)
+		}
+	}
+
+	info.Tags = strings.Join(tags, ",")
+	info.AgeRating = comicInfoAgeRating(xRestrict)
+	info.setDate(series.CreateDate)
+
+	return info
+}
+
+// addPage adds the next page of the archive to info, bookmarked as bookmark
+// unless it's empty. The first page is marked as the cover.
+func (info *ComicInfo) addPage(bookmark string) {
+	page := ComicInfoPage{Image: info.PageCount, Bookmark: bookmark}
+	if page.Image == 0 {
+		page.Type = "FrontCover"
+	}
+
+	info.Pages = append(info.Pages, page)
+	info.PageCount++
+}
+
+// setDate sets the publication date of info to t, unless t is zero.
+func (info *ComicInfo) setDate(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+
+	info.Year, info.Month, info.Day = t.Year(), int(t.Month()), t.Day()
+}
+
+// comicInfoManga returns the value of the Manga field of ComicInfo.xml for
+// artworks of illustType.
+//
+// Manga on pixiv is almost always read from right to left.
+func comicInfoManga(illustType IllustType) string {
+	if illustType == Manga {
+		return "YesAndRightToLeft"
+	}
+
+	return "No"
+}
+
+// comicInfoAgeRating returns the value of the AgeRating field of ComicInfo.xml
+// for artworks restricted by xRestrict, or an empty string if they aren't.
+func comicInfoAgeRating(xRestrict XRestrict) string {
+	if xRestrict != Safe {
+		return "Adults Only 18+"
+	}
+
+	return ""
+}
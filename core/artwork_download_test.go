@@ -0,0 +1,54 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestNewMangaSeriesComicInfo(t *testing.T) {
+	series := IllustSeries{
+		ID:         "10",
+		UserID:     "20",
+		Title:      "Series",
+		CreateDate: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+	}
+
+	works := []MangaSeriesWork{
+		{
+			ArtworkBrief: ArtworkBrief{Title: "First", UserName: "Author", Tags: []string{"a", "b"}},
+			Order:        1,
+			Images:       make([]Thumbnails, 2),
+		},
+		{
+			ArtworkBrief: ArtworkBrief{Title: "Second", UserName: "Author", Tags: []string{"b", "c"}, XRestrict: R18},
+			Order:        2,
+			Images:       make([]Thumbnails, 1),
+		},
+	}
+
+	got, err := xml.Marshal(NewMangaSeriesComicInfo(series, works))
+	if err != nil {
+		t.Fatalf("failed to marshal ComicInfo: %v", err)
+	}
+
+	want := `<ComicInfo>` +
+		`<Title>Series</Title><Series>Series</Series><Count>2</Count>` +
+		`<Year>2024</Year><Month>5</Month><Day>6</Day>` +
+		`<Writer>Author</Writer><Tags>a,b,c</Tags>` +
+		`<Web>https://www.pixiv.net/user/20/series/10</Web>` +
+		`<PageCount>3</PageCount><Manga>YesAndRightToLeft</Manga><AgeRating>Adults Only 18+</AgeRating>` +
+		`<Pages>` +
+		`<Page Image="0" Type="FrontCover" Bookmark="#1 First"></Page>` +
+		`<Page Image="1"></Page>` +
+		`<Page Image="2" Bookmark="#2 Second"></Page>` +
+		`</Pages>` +
+		`</ComicInfo>`
+
+	if string(got) != want {
+		t.Errorf("NewMangaSeriesComicInfo() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package atomicfile writes files atomically, so that readers never see a partially
+written file.
+
+Data is written to a temporary file in the same directory as the file, which is
+then renamed into place.
+*/
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// TempPattern is the pattern used for files being written, before they are renamed into place.
+const TempPattern = ".tmp-*"
+
+// WriteFile atomically writes data to the file name in dir, with permissions perm.
+//
+// dir must already exist.
+func WriteFile(dir, name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(dir, TempPattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, filepath.Join(dir, name)); err != nil {
+		os.Remove(tmpName)
+
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFile verifies that files are replaced with the given permissions,
+// without leaving temporary files behind.
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFile(dir, "file", []byte(data), 0o640); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(dir, "file"))
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+
+		if string(got) != data {
+			t.Errorf("file = %q, want %q", got, data)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "file"))
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0o640 {
+		t.Errorf("permissions = %o, want %o", perm, 0o640)
+	}
+
+	if tmp, _ := filepath.Glob(filepath.Join(dir, TempPattern)); len(tmp) != 0 {
+		t.Errorf("temporary files left behind: %v", tmp)
+	}
+
+	if err := WriteFile(filepath.Join(dir, "missing"), "file", nil, 0o600); err == nil {
+		t.Error("WriteFile() error = nil for a missing directory")
+	}
+}
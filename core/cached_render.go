@@ -0,0 +1,66 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedRender returns the data cached under key in the cache returned by cache,
+// or renders it with render and caches it if it isn't cached.
+//
+// Concurrent renders of the same key are coalesced through group. Rendering isn't
+// canceled along with r, so that its result can still be cached, and is instead
+// bounded by timeout. If the cache can't be created, data is rendered without it.
+func cachedRender(
+	r *http.Request,
+	cache func() (*requests.BlobCache, error),
+	group *singleflight.Group,
+	key string,
+	timeout time.Duration,
+	render func(ctx context.Context) ([]byte, error),
+) ([]byte, error) {
+	blobCache, err := cache()
+	if err != nil {
+		audit.GlobalAuditor.Logger.Errorf("Failed to create cache: %v", err)
+	} else if data, ok := blobCache.Get(key); ok {
+		return data, nil
+	}
+
+	result := group.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), timeout)
+		defer cancel()
+
+		data, err := render(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if blobCache != nil {
+			blobCache.Add(key, data)
+		}
+
+		return data, nil
+	})
+
+	select {
+	case <-r.Context().Done():
+		return nil, fmt.Errorf("context canceled: %w", r.Context().Err())
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		data, _ := res.Val.([]byte)
+
+		return data, nil
+	}
+}
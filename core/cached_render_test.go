@@ -0,0 +1,66 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"golang.org/x/sync/singleflight"
+)
+
+// TestCachedRender verifies that rendered data is cached, and that failed
+// renders aren't.
+func TestCachedRender(t *testing.T) {
+	blobCache, err := requests.NewBlobCache(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	cache := func() (*requests.BlobCache, error) { return blobCache, nil }
+
+	var (
+		group   singleflight.Group
+		renders int
+	)
+
+	render := func(context.Context) ([]byte, error) {
+		renders++
+
+		return []byte("rendered"), nil
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+
+	for range 2 {
+		data, err := cachedRender(r, cache, &group, "0123abcd", time.Minute, render)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(data) != "rendered" {
+			t.Errorf("got %q, want %q", data, "rendered")
+		}
+	}
+
+	if renders != 1 {
+		t.Errorf("rendered %d times, want 1", renders)
+	}
+
+	errRender := errors.New("render failed")
+
+	fail := func(context.Context) ([]byte, error) { return nil, errRender }
+
+	if _, err := cachedRender(r, cache, &group, "4567cdef", time.Minute, fail); !errors.Is(err, errRender) {
+		t.Errorf("got error %v, want %v", err, errRender)
+	}
+
+	if _, ok := blobCache.Get("4567cdef"); ok {
+		t.Error("failed render was cached")
+	}
+}
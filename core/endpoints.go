@@ -235,6 +235,12 @@ func GetArtworkImagesURL(illustID string) string {
 	return fmt.Sprintf(base, illustID)
 }
 
+func GetUgoiraMetaURL(illustID string) string {
+	base := "https://www.pixiv.net/ajax/illust/%s/ugoira_meta"
+
+	return fmt.Sprintf(base, illustID)
+}
+
 func GetArtworkRelatedURL(illustID string, limit int) string {
 	base := "https://www.pixiv.net/ajax/illust/%s/recommend/init?limit=%d"
 
@@ -0,0 +1,164 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package epub writes books in the EPUB 3 format.
+
+ref: https://www.w3.org/TR/epub-33/
+*/
+package epub
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"text/template"
+	"time"
+)
+
+// Book is an EPUB publication.
+type Book struct {
+	Identifier string    // Unique identifier of the book, such as its URL
+	Title      string    // Title of the book
+	Author     string    // Name of the author
+	Language   string    // BCP 47 language tag of the content, undetermined if empty
+	Subjects   []string  // Keywords describing the book, such as tags
+	Published  time.Time // Date of first publication, omitted if zero
+	Modified   time.Time // Date of last modification, the time of writing if zero
+	Vertical   bool      // Whether the content is written vertically, from right to left
+
+	Cover    *Image    // Cover image, shown on a page of its own before the first chapter
+	Chapters []Chapter // Chapters, in reading order
+	Images   []Image   // Images referenced by the chapters
+}
+
+// Chapter is a document in the reading order of a Book.
+type Chapter struct {
+	Title    string    // Title of the chapter, as shown in the table of contents
+	Body     string    // Well-formed XHTML content of the body element
+	Sections []Section // Sections of the chapter listed in the table of contents
+}
+
+// Section is a heading within a Chapter.
+type Section struct {
+	Title string // Title of the section, as shown in the table of contents
+	ID    string // ID of the element starting the section
+}
+
+// Image is an image embedded in a Book.
+type Image struct {
+	Name      string // File name of the image, unique within the book
+	MediaType string // Media type of the image, such as image/jpeg
+	Data      []byte
+}
+
+// MediaType is the media type of EPUB files.
+const MediaType = "application/epub+zip"
+
+// Href returns the path of img relative to the chapters of a book, to be used as
+// the src of img elements.
+func (img Image) Href() string {
+	return "images/" + img.Name
+}
+
+// chapterHref returns the path of the document of the chapter with index i.
+func chapterHref(i int) string {
+	return fmt.Sprintf("chapter-%03d.xhtml", i+1)
+}
+
+// Write writes b to w as an EPUB file.
+func (b *Book) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	modified := b.Modified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+
+	// The mimetype file must come first and be stored without compression, an
+	// extra field or a data descriptor, so that the type of the file can be
+	// identified from its first bytes. CreateHeader would add both, as it sets
+	// the modification time in an extra field and streams the data.
+	mimetype, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(MediaType)),
+		CompressedSize64:   uint64(len(MediaType)),
+		UncompressedSize64: uint64(len(MediaType)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(mimetype, MediaType); err != nil {
+		return err
+	}
+
+	files := []bookFile{
+		{"META-INF/container.xml", containerTemplate, b},
+		{"OEBPS/content.opf", packageTemplate, b},
+		{"OEBPS/nav.xhtml", navTemplate, b},
+		{"OEBPS/style.css", styleTemplate, b},
+	}
+
+	if b.Cover != nil {
+		files = append(files, bookFile{"OEBPS/cover.xhtml", coverTemplate, b})
+	}
+
+	for i, chapter := range b.Chapters {
+		files = append(files, bookFile{"OEBPS/" + chapterHref(i), chapterTemplate, chapterData{Book: b, Chapter: chapter}})
+	}
+
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := file.tmpl.Execute(fw, file.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	images := b.Images
+	if b.Cover != nil {
+		images = append([]Image{*b.Cover}, images...)
+	}
+
+	for _, img := range images {
+		// Images are already compressed, so storing them saves time without
+		// making the file much larger.
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     "OEBPS/" + img.Href(),
+			Method:   zip.Store,
+			Modified: modified,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := fw.Write(img.Data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// bookFile is a file of a Book written from a template.
+type bookFile struct {
+	name string
+	tmpl *template.Template
+	data any
+}
+
+// chapterData is the data of chapterTemplate.
+type chapterData struct {
+	Book    *Book
+	Chapter Chapter
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	book := &Book{
+		Identifier: "https://www.pixiv.net/novel/show.php?id=1",
+		Title:      `Tom & Jerry's "Adventure"`,
+		Author:     "<author>",
+		Language:   "ja",
+		Subjects:   []string{"オリジナル"},
+		Modified:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Vertical:   true,
+		Cover:      &Image{Name: "cover.jpg", MediaType: "image/jpeg", Data: []byte("cover")},
+		Chapters: []Chapter{
+			{
+				Title:    "Chapter & 1",
+				Body:     `<p><ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby></p><h2 id="chapter-1">Section</h2>`,
+				Sections: []Section{{Title: "Section", ID: "chapter-1"}},
+			},
+			{Title: "Chapter 2", Body: `<p><img src="images/1-1.png" alt=""/></p>`},
+		},
+		Images: []Image{{Name: "1-1.png", MediaType: "image/png", Data: []byte("image")}},
+	}
+
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read the written EPUB: %v", err)
+	}
+
+	first := zr.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("first file = %q with method %d, want an uncompressed mimetype", first.Name, first.Method)
+	}
+
+	files := make(map[string]string)
+
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+
+		data, err := io.ReadAll(rc)
+		rc.Close()
+
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+
+		files[f.Name] = string(data)
+	}
+
+	if files["mimetype"] != MediaType {
+		t.Errorf("mimetype = %q, want %q", files["mimetype"], MediaType)
+	}
+
+	for _, name := range []string{
+		"META-INF/container.xml",
+		"OEBPS/content.opf",
+		"OEBPS/nav.xhtml",
+		"OEBPS/cover.xhtml",
+		"OEBPS/chapter-001.xhtml",
+		"OEBPS/chapter-002.xhtml",
+	} {
+		content, ok := files[name]
+		if !ok {
+			t.Errorf("missing %s", name)
+
+			continue
+		}
+
+		if err := checkWellFormed(content); err != nil {
+			t.Errorf("%s isn't well-formed XML: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"OEBPS/images/cover.jpg", "OEBPS/images/1-1.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		`<dc:creator id="author">&lt;author&gt;</dc:creator>`,
+		`<meta property="dcterms:modified">2024-01-01T00:00:00Z</meta>`,
+		`properties="cover-image"`,
+		`page-progression-direction="rtl"`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf doesn't contain %s", want)
+		}
+	}
+
+	if nav := files["OEBPS/nav.xhtml"]; !strings.Contains(nav, `href="chapter-001.xhtml#chapter-1"`) {
+		t.Errorf("nav.xhtml doesn't link to the section of the first chapter")
+	}
+
+	if style := files["OEBPS/style.css"]; !strings.Contains(style, "writing-mode: vertical-rl") {
+		t.Errorf("style.css doesn't set vertical writing")
+	}
+}
+
+// checkWellFormed returns an error if content isn't well-formed XML.
+func checkWellFormed(content string) error {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = true
+
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// TestWriteMimetype verifies that the file starts with the mimetype entry as
+// required by the EPUB Open Container Format: uncompressed, without an extra
+// field or a data descriptor, so that its content is found at offset 30.
+func TestWriteMimetype(t *testing.T) {
+	book := &Book{Title: "Title", Chapters: []Chapter{{Title: "Chapter", Body: "<p>Body</p>"}}}
+
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	header := buf.Bytes()[:58]
+
+	if got := string(header[:4]); got != "PK\x03\x04" {
+		t.Errorf("signature = %q, want a local file header", got)
+	}
+
+	if flags := binary.LittleEndian.Uint16(header[6:]); flags != 0 {
+		t.Errorf("flags = %#x, want 0", flags)
+	}
+
+	if method := binary.LittleEndian.Uint16(header[8:]); method != zip.Store {
+		t.Errorf("method = %d, want %d", method, zip.Store)
+	}
+
+	if extra := binary.LittleEndian.Uint16(header[28:]); extra != 0 {
+		t.Errorf("extra field length = %d, want 0", extra)
+	}
+
+	if got := string(header[30:]); got != "mimetype"+MediaType {
+		t.Errorf("content at offset 30 = %q, want %q", got, "mimetype"+MediaType)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package epub
+
+import (
+	"text/template"
+	"time"
+)
+
+// Templates of the files of a Book.
+//
+// text/template doesn't escape its output, so every value that isn't already
+// XHTML is passed to the html function, whose escaping is also valid in XML.
+var (
+	containerTemplate = newTemplate("container", containerXML)
+	packageTemplate   = newTemplate("package", packageOPF)
+	navTemplate       = newTemplate("nav", navXHTML)
+	coverTemplate     = newTemplate("cover", coverXHTML)
+	chapterTemplate   = newTemplate("chapter", chapterXHTML)
+	styleTemplate     = newTemplate("style", styleCSS)
+)
+
+var templateFuncs = template.FuncMap{
+	"chapterHref": chapterHref,
+	"inc":         func(i int) int { return i + 1 },
+	"language": func(lang string) string {
+		// "und" is the BCP 47 tag for an undetermined language
+		if lang == "" {
+			return "und"
+		}
+
+		return lang
+	},
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			t = time.Now()
+		}
+
+		return t.UTC().Format("2006-01-02T15:04:05Z")
+	},
+}
+
+func newTemplate(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(templateFuncs).Parse(text))
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const packageOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="{{ language .Language | html }}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{ .Identifier | html }}</dc:identifier>
+    <dc:title>{{ .Title | html }}</dc:title>
+    {{- if .Author }}
+    <dc:creator id="author">{{ .Author | html }}</dc:creator>
+    <meta refines="#author" property="role" scheme="marc:relators">aut</meta>
+    {{- end }}
+    <dc:language>{{ language .Language | html }}</dc:language>
+    {{- range .Subjects }}
+    <dc:subject>{{ . | html }}</dc:subject>
+    {{- end }}
+    {{- if not .Published.IsZero }}
+    <dc:date>{{ date .Published }}</dc:date>
+    {{- end }}
+    <meta property="dcterms:modified">{{ date .Modified }}</meta>
+    {{- if .Cover }}
+    <meta name="cover" content="cover-image"/>
+    {{- end }}
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+    {{- if .Cover }}
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover-image" href="{{ .Cover.Href | html }}" media-type="{{ .Cover.MediaType | html }}" properties="cover-image"/>
+    {{- end }}
+    {{- range $i, $chapter := .Chapters }}
+    <item id="chapter-{{ inc $i }}" href="{{ chapterHref $i }}" media-type="application/xhtml+xml"/>
+    {{- end }}
+    {{- range $i, $image := .Images }}
+    <item id="image-{{ inc $i }}" href="{{ $image.Href | html }}" media-type="{{ $image.MediaType | html }}"/>
+    {{- end }}
+  </manifest>
+  <spine{{ if .Vertical }} page-progression-direction="rtl"{{ end }}>
+    {{- if .Cover }}
+    <itemref idref="cover"/>
+    {{- end }}
+    {{- range $i, $chapter := .Chapters }}
+    <itemref idref="chapter-{{ inc $i }}"/>
+    {{- end }}
+  </spine>
+</package>
+`
+
+const navXHTML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="{{ language .Language | html }}" lang="{{ language .Language | html }}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{ .Title | html }}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>{{ .Title | html }}</h1>
+    <ol>
+      {{- range $i, $chapter := .Chapters }}
+      <li>
+        <a href="{{ chapterHref $i }}">{{ $chapter.Title | html }}</a>
+        {{- if $chapter.Sections }}
+        <ol>
+          {{- range $chapter.Sections }}
+          <li><a href="{{ chapterHref $i }}#{{ .ID | html }}">{{ .Title | html }}</a></li>
+          {{- end }}
+        </ol>
+        {{- end }}
+      </li>
+      {{- end }}
+    </ol>
+  </nav>
+  <nav epub:type="landmarks" id="landmarks" hidden="">
+    <ol>
+      {{- if .Cover }}
+      <li><a epub:type="cover" href="cover.xhtml">{{ .Title | html }}</a></li>
+      {{- end }}
+      <li><a epub:type="toc" href="#toc">{{ .Title | html }}</a></li>
+      {{- if .Chapters }}
+      <li><a epub:type="bodymatter" href="{{ chapterHref 0 }}">{{ (index .Chapters 0).Title | html }}</a></li>
+      {{- end }}
+    </ol>
+  </nav>
+</body>
+</html>
+`
+
+const coverXHTML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="{{ language .Language | html }}" lang="{{ language .Language | html }}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{ .Title | html }}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body class="cover" epub:type="cover">
+  <img src="{{ .Cover.Href | html }}" alt="{{ .Title | html }}"/>
+</body>
+</html>
+`
+
+const chapterXHTML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="{{ language .Book.Language | html }}" lang="{{ language .Book.Language | html }}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{ .Chapter.Title | html }}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+  <section epub:type="chapter">
+    <h1>{{ .Chapter.Title | html }}</h1>
+    {{ .Chapter.Body }}
+  </section>
+</body>
+</html>
+`
+
+const styleCSS = `{{ if .Vertical -}}
+html {
+  -epub-writing-mode: vertical-rl;
+  -webkit-writing-mode: vertical-rl;
+  writing-mode: vertical-rl;
+}
+
+{{ end -}}
+body {
+  line-height: 1.8;
+}
+
+h1 {
+  font-size: 1.4em;
+}
+
+h2 {
+  font-size: 1.2em;
+}
+
+p {
+  margin: 0;
+}
+
+img {
+  max-width: 100%;
+  max-height: 100%;
+}
+
+rt {
+  font-size: 0.5em;
+}
+
+.page + .page {
+  break-before: page;
+  page-break-before: always;
+}
+
+.cover {
+  margin: 0;
+  text-align: center;
+}
+
+.cover img {
+  height: 100%;
+}
+`
@@ -0,0 +1,109 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core/imageproxy"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// imageTransformTimeout bounds fetching and transforming an image, which isn't
+	// canceled along with the request that started it so that it can be cached.
+	imageTransformTimeout = time.Minute
+
+	// imageCacheTTL is how long transformed images are kept in the cache.
+	//
+	// Images on i.pximg.net are never modified in place, as the date in their path
+	// changes whenever an artwork is edited.
+	imageCacheTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// inflightImages coalesces concurrent transformations of the same image.
+	inflightImages singleflight.Group
+
+	// imageTransformSlots limits how many images are transformed at once, as
+	// transforming is CPU-bound and holds the decoded image in memory.
+	imageTransformSlots = make(chan struct{}, max(runtime.NumCPU()/2, 1))
+
+	// imageCache stores transformed images on disk, and is created on first use.
+	imageCache = sync.OnceValues(func() (*requests.BlobCache, error) {
+		return requests.NewBlobCache(config.GlobalConfig.ImageTransform.CachePath, config.GlobalConfig.ImageTransform.CacheMaxBytes, imageCacheTTL)
+	})
+)
+
+// TransformImage returns the image at path on i.pximg.net transformed as described
+// by opts, from the cache if it was transformed before.
+func TransformImage(r *http.Request, path string, opts imageproxy.Options) ([]byte, error) {
+	key := imageCacheKey(path, opts)
+
+	return cachedRender(r, imageCache, &inflightImages, key, imageTransformTimeout, func(ctx context.Context) ([]byte, error) {
+		return transformImage(ctx, path, opts)
+	})
+}
+
+// transformImage fetches the image at path on i.pximg.net and transforms it.
+func transformImage(ctx context.Context, path string, opts imageproxy.Options) ([]byte, error) {
+	data, err := requests.FetchContent(ctx, "https://i.pximg.net/"+path, map[string]string{"Referer": "https://www.pixiv.net/"})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case imageTransformSlots <- struct{}{}:
+		defer func() { <-imageTransformSlots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return imageproxy.Transform(ctx, data, opts)
+}
+
+// ImageSrcset returns a srcset attribute value for an image of the given width
+// served by the built-in image proxy, listing the image scaled down to each of
+// imageproxy.Widths narrower than it, followed by the image itself.
+//
+// An empty string is returned for images that can't be transformed.
+func ImageSrcset(imageURL string, width int) string {
+	if !config.GlobalConfig.ImageTransform.Enabled ||
+		!strings.HasPrefix(imageURL, config.BuiltInImageProxyPath+"/") ||
+		strings.Contains(imageURL, "?") || width <= 0 {
+		return ""
+	}
+
+	var candidates []string
+
+	for _, w := range imageproxy.Widths {
+		if w < width {
+			candidates = append(candidates, fmt.Sprintf("%s?w=%d %dw", imageURL, w, w))
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	return strings.Join(append(candidates, fmt.Sprintf("%s %dw", imageURL, width)), ", ")
+}
+
+// imageCacheKey returns the key that the image at path transformed as described
+// by opts is cached under, which is a valid BlobCache key.
+func imageCacheKey(path string, opts imageproxy.Options) string {
+	sum := sha256.Sum256([]byte(path + "?" + opts.Query().Encode()))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package imageproxy resizes and converts images served by the built-in image proxy.
+
+Transformations are requested with query parameters, such as ?w=600&fmt=webp,
+and are applied in pure Go. Images are only ever scaled down, and widths are
+limited to those in Widths so that each image has a bounded number of variants.
+
+The memory used to decode images is bounded both for each image, by maxPixels,
+and across the images transformed at once, by maxDecodeBytes.
+*/
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"codeberg.org/pixivfe/pixivfe/core/webp"
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/semaphore"
+
+	// Register the formats of source images for image.Decode
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// jpegQuality is the quality of images encoded as JPEG.
+	jpegQuality = 85
+
+	// maxPixels bounds the size of the images that are transformed, as each is
+	// decoded into memory in full.
+	maxPixels = 16 << 20
+
+	// maxDecodeBytes bounds the memory taken by all the images being transformed
+	// at once, as estimated by decodeCost.
+	maxDecodeBytes = 256 << 20
+
+	// maxBytesPerPixel is the most memory that a decoded pixel takes, for 16-bit
+	// PNGs with transparency.
+	maxBytesPerPixel = 8
+)
+
+// Format is a format that images can be converted to.
+//
+// WebP images are encoded losslessly, as that's all core/webp supports, so they're
+// usually bigger than JPEG for photographic images.
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	PNG  Format = "png"
+	WebP Format = "webp"
+)
+
+// Widths lists the widths that images can be resized to.
+var Widths = []int{240, 360, 480, 600, 720, 960, 1200, 1440, 1920}
+
+// decodeMemory is acquired for the estimated memory of each image being transformed.
+var decodeMemory = semaphore.NewWeighted(maxDecodeBytes)
+
+var (
+	ErrInvalidOptions = errors.New("invalid image transformation")
+	ErrImageTooLarge  = errors.New("image is too large to transform")
+)
+
+// Options describes how to transform an image.
+type Options struct {
+	Width  int    // Width to scale the image down to, or 0 to keep its size
+	Format Format // Format to convert the image to, or "" to keep its format
+}
+
+// ParseOptions returns the Options given by the w and fmt query parameters.
+func ParseOptions(query url.Values) (Options, error) {
+	var opts Options
+
+	if w := query.Get("w"); w != "" {
+		width, err := strconv.Atoi(w)
+		if err != nil || !slices.Contains(Widths, width) {
+			return opts, fmt.Errorf("%w: width must be one of %v, got %s", ErrInvalidOptions, Widths, w)
+		}
+
+		opts.Width = width
+	}
+
+	switch format := Format(query.Get("fmt")); format {
+	case "", JPEG, PNG, WebP:
+		opts.Format = format
+	default:
+		return opts, fmt.Errorf("%w: unknown format %s", ErrInvalidOptions, format)
+	}
+
+	return opts, nil
+}
+
+// IsZero reports whether opts leaves images unchanged.
+func (opts Options) IsZero() bool {
+	return opts == Options{}
+}
+
+// Query returns opts as query parameters, as parsed by ParseOptions.
+func (opts Options) Query() url.Values {
+	query := url.Values{}
+
+	if opts.Width != 0 {
+		query.Set("w", strconv.Itoa(opts.Width))
+	}
+
+	if opts.Format != "" {
+		query.Set("fmt", string(opts.Format))
+	}
+
+	return query
+}
+
+// Transform applies opts to the image in data.
+//
+// data is returned as is when opts leaves it unchanged, such as when it's already
+// narrower than opts.Width and in opts.Format. Otherwise, Transform waits until
+// the memory to decode the image is available, or ctx is done.
+func Transform(ctx context.Context, data []byte, opts Options) ([]byte, error) {
+	config, sourceFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = Format(sourceFormat)
+
+		// GIF isn't an output format, and only the first frame is kept anyway
+		if format == "gif" {
+			format = PNG
+		}
+	}
+
+	resize := opts.Width != 0 && opts.Width < config.Width
+
+	if !resize && string(format) == sourceFormat {
+		return data, nil
+	}
+
+	cost := decodeCost(config, opts.Width)
+	if err := decodeMemory.Acquire(ctx, cost); err != nil {
+		return nil, err
+	}
+	defer decodeMemory.Release(cost)
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if resize {
+		img = scale(img, opts.Width)
+	}
+
+	var buf bytes.Buffer
+
+	switch format {
+	case JPEG:
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: jpegQuality})
+	case PNG:
+		err = png.Encode(&buf, img)
+	case WebP:
+		err = webp.Encode(&buf, img)
+	default:
+		err = fmt.Errorf("%w: unsupported format %s", ErrInvalidOptions, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeCost estimates the memory, in bytes, taken by transforming an image of
+// config to width: the decoded image, and another one as big as the output for
+// scaling or flattening it.
+func decodeCost(config image.Config, width int) int64 {
+	pixels := int64(config.Width) * int64(config.Height)
+	output := pixels
+
+	if width != 0 && width < config.Width {
+		output = int64(width) * (int64(config.Height)*int64(width)/int64(config.Width) + 1)
+	}
+
+	return pixels*maxBytesPerPixel + output*4
+}
+
+// scale scales img down to width, keeping its aspect ratio.
+func scale(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	height := max((bounds.Dy()*width+bounds.Dx()/2)/bounds.Dx(), 1)
+
+	var dst draw.Image
+	if isOpaque(img) {
+		dst = image.NewRGBA(image.Rect(0, 0, width, height))
+	} else {
+		dst = image.NewNRGBA(image.Rect(0, 0, width, height))
+	}
+
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return dst
+}
+
+// flatten draws img over a white background if it may be transparent, as JPEG
+// has no transparency.
+func flatten(img image.Image) image.Image {
+	if isOpaque(img) {
+		return img
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	draw.Draw(dst, dst.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Over)
+
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	opaque, ok := img.(interface{ Opaque() bool })
+
+	return ok && opaque.Opaque()
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"testing"
+)
+
+func testPNG(t *testing.T, width, height int, alpha uint8) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 0x80, alpha})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Options
+		wantErr bool
+	}{
+		{"", Options{}, false},
+		{"w=600", Options{Width: 600}, false},
+		{"w=600&fmt=png", Options{Width: 600, Format: PNG}, false},
+		{"w=600&fmt=webp", Options{Width: 600, Format: WebP}, false},
+		{"fmt=jpeg", Options{Format: JPEG}, false},
+		{"w=601", Options{}, true},
+		{"w=abc", Options{}, true},
+		{"fmt=avif", Options{}, true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+
+		got, err := ParseOptions(query)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("ParseOptions(%q) error = %v, want ErrInvalidOptions", tt.query, err)
+			}
+
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("ParseOptions(%q) = %+v, %v, want %+v", tt.query, got, err, tt.want)
+		}
+
+		if roundtrip, _ := ParseOptions(got.Query()); roundtrip != got {
+			t.Errorf("ParseOptions(%+v.Query()) = %+v", got, roundtrip)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	data := testPNG(t, 800, 400, 0xff)
+
+	tests := []struct {
+		name       string
+		opts       Options
+		wantFormat string
+		wantSize   image.Point
+	}{
+		{"resize", Options{Width: 600}, "png", image.Pt(600, 300)},
+		{"convert", Options{Format: JPEG}, "jpeg", image.Pt(800, 400)},
+		{"resize and convert", Options{Width: 240, Format: JPEG}, "jpeg", image.Pt(240, 120)},
+		{"resize and convert to WebP", Options{Width: 240, Format: WebP}, "webp", image.Pt(240, 120)},
+		{"no upscaling", Options{Width: 1920, Format: JPEG}, "jpeg", image.Pt(800, 400)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Transform(context.Background(), data, tt.opts)
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+
+			config, format, err := image.DecodeConfig(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("failed to decode the transformed image: %v", err)
+			}
+
+			if format != tt.wantFormat || image.Pt(config.Width, config.Height) != tt.wantSize {
+				t.Errorf("got a %dx%d %s image, want a %v %s image",
+					config.Width, config.Height, format, tt.wantSize, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestTransformUnchanged(t *testing.T) {
+	data := testPNG(t, 200, 100, 0xff)
+
+	out, err := Transform(context.Background(), data, Options{Width: 240, Format: PNG})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+
+	if !bytes.Equal(out, data) {
+		t.Errorf("Transform() re-encoded an image it didn't need to change")
+	}
+}
+
+func TestTransformTransparentToJPEG(t *testing.T) {
+	out, err := Transform(context.Background(), testPNG(t, 10, 10, 0), Options{Format: JPEG})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("failed to decode the transformed image: %v", err)
+	}
+
+	// Fully transparent pixels are drawn over white
+	if r, g, b, _ := img.At(5, 5).RGBA(); r>>8 < 0xf0 || g>>8 < 0xf0 || b>>8 < 0xf0 {
+		t.Errorf("pixel = %v, want white", img.At(5, 5))
+	}
+}
+
+func TestTransformWaitsForMemory(t *testing.T) {
+	if !decodeMemory.TryAcquire(maxDecodeBytes) {
+		t.Fatal("decode memory is in use")
+	}
+	defer decodeMemory.Release(maxDecodeBytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Transform(ctx, testPNG(t, 800, 400, 0xff), Options{Width: 240}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Transform() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestDecodeCost(t *testing.T) {
+	// Every image that isn't too large must fit in the decode memory on its own
+	if cost := decodeCost(image.Config{Width: maxPixels, Height: 1}, 0); cost > maxDecodeBytes {
+		t.Errorf("decodeCost() of the largest image = %d, over maxDecodeBytes", cost)
+	}
+
+	if got, want := decodeCost(image.Config{Width: 800, Height: 400}, 240), int64(800*400*maxBytesPerPixel+240*121*4); got != want {
+		t.Errorf("decodeCost() = %d, want %d", got, want)
+	}
+}
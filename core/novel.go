@@ -139,6 +139,22 @@ var (
 )
 
 func GetNovelByID(r *http.Request, id string) (Novel, error) {
+	novel, err := fetchNovel(r, id)
+	if err != nil {
+		return novel, err
+	}
+
+	// Get view mode
+	viewMode := determineViewMode(r, novel.Settings.ViewMode)
+
+	// Process the novel content
+	novel.Content = processNovelContent(r, novel, viewMode)
+
+	return novel, nil
+}
+
+// fetchNovel fetches the novel with the given ID, leaving its content as markup.
+func fetchNovel(r *http.Request, id string) (Novel, error) {
 	var novel Novel
 
 	url := GetNovelURL(id)
@@ -170,12 +186,6 @@ func GetNovelByID(r *http.Request, id string) (Novel, error) {
 		novel.UserNovels = cleanedUserNovels
 	}
 
-	// Get view mode
-	viewMode := determineViewMode(r, novel.Settings.ViewMode)
-
-	// Process the novel content
-	novel.Content = processNovelContent(r, novel, viewMode)
-
 	return novel, nil
 }
 
@@ -227,18 +237,12 @@ func processNovelContent(r *http.Request, novel Novel, viewMode int) string {
 	// Replace [pixivimage:...] tags with actual images
 	content = re_r.ReplaceAllStringFunc(content, func(s string) string {
 		illustid := re_d.FindString(s)
-		url := GetInsertIllustURL(novel.ID, illustid)
-
-		cookies := map[string]string{
-			"PHPSESSID": session.GetUserToken(r),
-		}
 
-		resp, err := requests.FetchJSONBodyField(r.Context(), url, cookies, r.Header)
+		imgURL, err := getInsertIllustURL(r, novel.ID, illustid)
 		if err != nil {
 			return "Cannot insert illust" + illustid
 		}
 
-		imgURL := gjson.GetBytes(resp, "original").String()
 		if imgURL == "" {
 			return "Invalid image data for " + illustid
 		}
@@ -265,6 +269,25 @@ func processNovelContent(r *http.Request, novel Novel, viewMode int) string {
 	return content
 }
 
+// getInsertIllustURL returns the URL of the original image of an illust inserted
+// into a novel with [pixivimage:...], or an empty string if it has none.
+//
+// The URL isn't proxied.
+func getInsertIllustURL(r *http.Request, novelID, illustID string) (string, error) {
+	url := GetInsertIllustURL(novelID, illustID)
+
+	cookies := map[string]string{
+		"PHPSESSID": session.GetUserToken(r),
+	}
+
+	resp, err := requests.FetchJSONBodyField(r.Context(), url, cookies, r.Header)
+	if err != nil {
+		return "", err
+	}
+
+	return gjson.GetBytes(resp, "original").String(), nil
+}
+
 // createImageHTML generates the HTML for embedding an image based on view mode
 func createImageHTML(imgURL, alt, link string, viewMode int) string {
 	// Styling for horizontal text (default for viewMode 0 and 1)
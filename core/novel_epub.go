@@ -0,0 +1,380 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/core/epub"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// novelSeriesEPUBConcurrency limits how many novels of a series are fetched at
+// once when building an EPUB of the series.
+const novelSeriesEPUBConcurrency = 4
+
+// epubImageExtensions maps the media types of images that EPUB readers support
+// to the extension of their file name.
+var epubImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// novelImagePattern matches the markup of images inserted into novels.
+var novelImagePattern = regexp.MustCompile(`\[(?:pixivimage:\d+(?:-\d+)?|uploadedimage:\d+)\]`)
+
+// GetNovelEPUB builds an EPUB of the novel with the given ID.
+func GetNovelEPUB(r *http.Request, id string) (*epub.Book, error) {
+	novel, err := fetchNovel(r, id)
+	if err != nil {
+		return nil, err
+	}
+
+	chapter, images := novelEPUBChapter(r, novel, novel.Title)
+
+	tags := make([]string, 0, len(novel.Tags.Tags))
+	for _, tag := range novel.Tags.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return &epub.Book{
+		Identifier: "https://www.pixiv.net/novel/show.php?id=" + novel.ID,
+		Title:      novel.Title,
+		Author:     novel.UserName,
+		Language:   novel.Language,
+		Subjects:   tags,
+		Published:  novel.CreateDate,
+		Modified:   novel.UploadDate,
+		Vertical:   determineViewMode(r, novel.Settings.ViewMode) == 2,
+		Cover:      fetchEPUBImage(r, novel.CoverURL, "cover"),
+		Chapters:   []epub.Chapter{chapter},
+		Images:     images,
+	}, nil
+}
+
+// GetNovelSeriesEPUB builds an EPUB of the novel series with the given ID, with
+// each novel of the series as a chapter.
+func GetNovelSeriesEPUB(r *http.Request, id string) (*epub.Book, error) {
+	series, err := GetNovelSeriesByID(r, id)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := getAllNovelSeriesContent(r, id, series.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contents) == 0 {
+		return nil, i18n.ErrorContext(r.Context(), "This novel series has no novels that can be exported.")
+	}
+
+	var (
+		novels   = make([]Novel, len(contents))
+		chapters = make([]epub.Chapter, len(contents))
+		images   = make([][]epub.Image, len(contents))
+	)
+
+	g, _ := errgroup.WithContext(r.Context())
+	g.SetLimit(novelSeriesEPUBConcurrency)
+
+	for i, content := range contents {
+		g.Go(func() error {
+			novel, err := fetchNovel(r, content.ID)
+			if err != nil {
+				return err
+			}
+
+			title := fmt.Sprintf("#%d %s", content.Series.ContentOrder, novel.Title)
+
+			novels[i] = novel
+			chapters[i], images[i] = novelEPUBChapter(r, novel, title)
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	book := &epub.Book{
+		Identifier: "https://www.pixiv.net/novel/series/" + id,
+		Title:      series.Title,
+		Author:     series.UserName,
+		Language:   series.Language,
+		Subjects:   series.Tags,
+		Published:  series.CreateDate,
+		Modified:   series.UpdateDate,
+		// Novels of a series are almost always written in the same way
+		Vertical: determineViewMode(r, novels[0].Settings.ViewMode) == 2,
+		Cover:    fetchEPUBImage(r, series.Cover.Urls.Original, "cover"),
+		Chapters: chapters,
+	}
+
+	for _, novelImages := range images {
+		book.Images = append(book.Images, novelImages...)
+	}
+
+	return book, nil
+}
+
+// getAllNovelSeriesContent returns every novel in the novel series with the given ID,
+// by fetching each page of its contents.
+func getAllNovelSeriesContent(r *http.Request, seriesID string, total int) ([]NovelSeriesContent, error) {
+	// Same as the novel series page
+	const perPage = 30
+
+	contents := make([]NovelSeriesContent, 0, total)
+
+	for page := 1; len(contents) < total; page++ {
+		pageContents, err := GetNovelSeriesContentByID(r, seriesID, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+
+		contents = append(contents, pageContents...)
+
+		// Novels that can't be viewed aren't included, so the total may never be reached
+		if len(pageContents) < perPage {
+			break
+		}
+	}
+
+	return contents, nil
+}
+
+// novelEPUBChapter converts novel to an EPUB chapter with the given title, returning
+// it with the images inserted into the novel.
+func novelEPUBChapter(r *http.Request, novel Novel, title string) (epub.Chapter, []epub.Image) {
+	var (
+		srcs   = make(map[string]string)
+		images []epub.Image
+	)
+
+	for _, markup := range novelImagePattern.FindAllString(novel.Content, -1) {
+		if _, found := srcs[markup]; found {
+			continue
+		}
+
+		// Images that can't be fetched are left as markup, as on the novel page
+		srcs[markup] = ""
+
+		name := fmt.Sprintf("%s-%d", novel.ID, len(images)+1)
+		if img := fetchEPUBImage(r, novelImageURL(r, novel, markup), name); img != nil {
+			srcs[markup] = img.Href()
+			images = append(images, *img)
+		}
+	}
+
+	body, sections := novelXHTML(r.Context(), novel.Content, srcs)
+
+	return epub.Chapter{
+		Title:    title,
+		Body:     body,
+		Sections: sections,
+	}, images
+}
+
+// novelImageURL returns the proxied URL of the image inserted into novel by markup,
+// or an empty string if the image can't be found.
+func novelImageURL(r *http.Request, novel Novel, markup string) string {
+	if re_u.MatchString(markup) {
+		// URLs of uploaded images were already rewritten by fetchNovel
+		return novel.TextEmbeddedImages[re_id.FindString(markup)].Urls.Original
+	}
+
+	illustID := re_d.FindString(markup)
+
+	imgURL, err := getInsertIllustURL(r, novel.ID, illustID)
+	if err != nil {
+		audit.GlobalAuditor.Logger.Warn("Failed to get inserted illust",
+			zap.String("novel_id", novel.ID),
+			zap.String("illust_id", illustID),
+			zap.Error(err))
+
+		return ""
+	}
+
+	return string(RewriteContentURLsNoEscape(r, []byte(imgURL)))
+}
+
+// fetchEPUBImage fetches the image at the proxied imgURL to embed it in an EPUB,
+// under the given file name without extension.
+//
+// Returns nil if imgURL is empty, or the image can't be fetched or isn't supported
+// by EPUB readers.
+func fetchEPUBImage(r *http.Request, imgURL, name string) *epub.Image {
+	if imgURL == "" {
+		return nil
+	}
+
+	data, err := FetchProxiedContent(r, imgURL)
+	if err != nil {
+		audit.GlobalAuditor.Logger.Warn("Failed to fetch image for EPUB",
+			zap.String("url", imgURL),
+			zap.Error(err))
+
+		return nil
+	}
+
+	mediaType := http.DetectContentType(data)
+
+	ext, ok := epubImageExtensions[mediaType]
+	if !ok {
+		audit.GlobalAuditor.Logger.Warn("Unsupported image type for EPUB",
+			zap.String("url", imgURL),
+			zap.String("media_type", mediaType))
+
+		return nil
+	}
+
+	return &epub.Image{
+		Name:      name + ext,
+		MediaType: mediaType,
+		Data:      data,
+	}
+}
+
+// novelXHTML converts the markup of novel content to XHTML, returning it with the
+// sections started by [chapter:...] markup.
+//
+// Each page is wrapped in an element with the ID "page-N" for [jump:N] markup to
+// link to, and each line becomes a paragraph. srcs maps the markup of inserted
+// images to the src of the image, with markup missing from it left as text.
+// Link text is localized to the locale of ctx.
+func novelXHTML(ctx context.Context, content string, srcs map[string]string) (string, []epub.Section) {
+	var (
+		sb       strings.Builder
+		sections []epub.Section
+		inline   = novelInlineMarkup(ctx, srcs)
+	)
+
+	for i, page := range NovelNewPagePattern.Split(content, -1) {
+		fmt.Fprintf(&sb, `<div class="page" id="page-%d">`, i+1)
+
+		for _, line := range strings.Split(page, "\n") {
+			// Chapter headings can't be placed within paragraphs, so the text
+			// around them becomes paragraphs of its own
+			hasChapter := false
+
+			for {
+				loc := NovelChapterPattern.FindStringSubmatchIndex(line)
+				if loc == nil {
+					break
+				}
+
+				if before := line[:loc[0]]; strings.TrimSpace(before) != "" {
+					fmt.Fprintf(&sb, `<p>%s</p>`, novelInlineXHTML(before, inline))
+				}
+
+				title := line[loc[2]:loc[3]]
+				id := fmt.Sprintf("chapter-%d", len(sections)+1)
+
+				fmt.Fprintf(&sb, `<h2 id="%s">%s</h2>`, id, novelInlineXHTML(title, inline))
+
+				sections = append(sections, epub.Section{
+					// Only the base text of furigana is shown in the table of contents
+					Title: NovelFuriganaPattern.ReplaceAllString(title, "$1"),
+					ID:    id,
+				})
+
+				line = line[loc[1]:]
+				hasChapter = true
+			}
+
+			switch {
+			case strings.TrimSpace(line) != "":
+				fmt.Fprintf(&sb, `<p>%s</p>`, novelInlineXHTML(line, inline))
+			case !hasChapter:
+				// Keep blank lines, which separate scenes
+				sb.WriteString(`<p><br/></p>`)
+			}
+		}
+
+		sb.WriteString(`</div>`)
+	}
+
+	return sb.String(), sections
+}
+
+// novelMarkup converts inline markup matching pattern to XHTML.
+type novelMarkup struct {
+	pattern *regexp.Regexp
+	xhtml   func(submatches []string) string // Converts the submatches of pattern
+}
+
+// novelInlineMarkup returns the inline markup of novels, with images replaced by
+// the srcs given by srcs.
+func novelInlineMarkup(ctx context.Context, srcs map[string]string) []novelMarkup {
+	return []novelMarkup{
+		{NovelFuriganaPattern, func(m []string) string {
+			return fmt.Sprintf(`<ruby>%s<rp>(</rp><rt>%s</rt><rp>)</rp></ruby>`,
+				html.EscapeString(m[1]), html.EscapeString(m[2]))
+		}},
+		{NovelJumpURIPattern, func(m []string) string {
+			return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(m[2]), html.EscapeString(m[1]))
+		}},
+		{NovelJumpPagePattern, func(m []string) string {
+			return fmt.Sprintf(`<a href="#page-%s">%s</a>`, m[1], html.EscapeString(i18n.SprintfContext(ctx, "To page %s", m[1])))
+		}},
+		{novelImagePattern, func(m []string) string {
+			src := srcs[m[0]]
+			if src == "" {
+				return html.EscapeString(m[0])
+			}
+
+			return fmt.Sprintf(`<img src="%s" alt="%s"/>`, html.EscapeString(src), html.EscapeString(m[0]))
+		}},
+	}
+}
+
+// novelInlineXHTML converts the inline markup in s to XHTML, escaping the rest of s.
+func novelInlineXHTML(s string, inline []novelMarkup) string {
+	var sb strings.Builder
+
+	for s != "" {
+		// Convert the markup that comes first
+		var (
+			markup novelMarkup
+			loc    []int
+		)
+
+		for _, m := range inline {
+			if l := m.pattern.FindStringSubmatchIndex(s); l != nil && (loc == nil || l[0] < loc[0]) {
+				markup, loc = m, l
+			}
+		}
+
+		if loc == nil {
+			sb.WriteString(html.EscapeString(s))
+
+			break
+		}
+
+		submatches := make([]string, len(loc)/2)
+		for i := range submatches {
+			if loc[2*i] >= 0 {
+				submatches[i] = s[loc[2*i]:loc[2*i+1]]
+			}
+		}
+
+		sb.WriteString(html.EscapeString(s[:loc[0]]))
+		sb.WriteString(markup.xhtml(submatches))
+
+		s = s[loc[1]:]
+	}
+
+	return sb.String()
+}
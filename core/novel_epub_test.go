@@ -0,0 +1,42 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/core/epub"
+)
+
+func TestNovelXHTML(t *testing.T) {
+	content := "[chapter:[[rb:序章 > じょしょう]]]\n" +
+		"A & B <c>\n" +
+		"\n" +
+		"[[jumpuri:Link > https://example.com/?a=1&b=2]][uploadedimage:1][uploadedimage:2]\n" +
+		"[newpage]\n" +
+		"[jump:1]"
+
+	srcs := map[string]string{"[uploadedimage:1]": "images/1-1.jpg"}
+
+	body, sections := novelXHTML(context.Background(), content, srcs)
+
+	want := `<div class="page" id="page-1">` +
+		`<h2 id="chapter-1"><ruby>序章<rp>(</rp><rt>じょしょう</rt><rp>)</rp></ruby></h2>` +
+		`<p>A &amp; B &lt;c&gt;</p>` +
+		`<p><br/></p>` +
+		`<p><a href="https://example.com/?a=1&amp;b=2">Link</a>` +
+		`<img src="images/1-1.jpg" alt="[uploadedimage:1]"/>[uploadedimage:2]</p>` +
+		`</div>` +
+		`<div class="page" id="page-2"><p><a href="#page-1">To page 1</a></p></div>`
+
+	if body != want {
+		t.Errorf("novelXHTML() body =\n%s\nwant\n%s", body, want)
+	}
+
+	wantSections := []epub.Section{{Title: "序章", ID: "chapter-1"}}
+	if len(sections) != len(wantSections) || sections[0] != wantSections[0] {
+		t.Errorf("novelXHTML() sections = %v, want %v", sections, wantSections)
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import "regexp"
+
+// Patterns of the markup used in the content of novels, shared by the novel
+// page and EPUB export.
+var (
+	NovelFuriganaPattern = regexp.MustCompile(`\[\[rb:\s*(.+?)\s*>\s*(.+?)\s*\]\]`)
+	NovelChapterPattern  = regexp.MustCompile(`\[chapter:\s*((?:\[\[rb:.+?\]\]|[^\]])+?)\s*\]`) // Titles may contain furigana
+	NovelJumpURIPattern  = regexp.MustCompile(`\[\[jumpuri:\s*(.+?)\s*>\s*(.+?)\s*\]\]`)
+	NovelJumpPagePattern = regexp.MustCompile(`\[jump:\s*(\d+?)\s*\]`)
+	NovelNewPagePattern  = regexp.MustCompile(`\s*\[newpage\]\s*`)
+)
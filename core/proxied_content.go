@@ -0,0 +1,73 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"codeberg.org/pixivfe/pixivfe/server/session"
+)
+
+// contentUpstream is a pixiv server that content URLs are rewritten from, along
+// with the headers that the built-in proxy sends to it.
+type contentUpstream struct {
+	origin       string
+	builtInPath  string
+	headers      map[string]string
+	sessionProxy func(r *http.Request) url.URL
+}
+
+// contentUpstreams lists the pixiv servers that FetchProxiedContent fetches from.
+var contentUpstreams = []contentUpstream{
+	{"https://i.pximg.net", config.BuiltInImageProxyPath, map[string]string{"Referer": "https://www.pixiv.net/"}, session.GetImageProxy},
+	{"https://s.pximg.net", config.BuiltInStaticProxyPath, nil, session.GetStaticProxy},
+}
+
+// FetchProxiedContent fetches content whose URL was rewritten by RewriteContentURLs,
+// for content that PixivFE serves itself, such as images embedded in an EPUB.
+//
+// The content is always fetched from the pixiv server that the URL was rewritten
+// from, in the same way as the built-in proxy does, rather than from the proxy
+// chosen by the user. Otherwise, a proxy cookie pointing at an internal address
+// would make this instance fetch from it.
+func FetchProxiedContent(r *http.Request, contentURL string) ([]byte, error) {
+	upstreamURL, headers, ok := UpstreamContentURL(r, contentURL)
+	if !ok {
+		return nil, fmt.Errorf("unsupported content URL: %s", contentURL)
+	}
+
+	return requests.FetchContent(r.Context(), upstreamURL, headers)
+}
+
+// UpstreamContentURL returns the URL on pixiv's servers of the content at contentURL,
+// which is either such a URL or one rewritten by RewriteContentURLs for r,
+// along with the headers to fetch it with.
+//
+// The boolean return is false if contentURL isn't served by a pixiv content server.
+func UpstreamContentURL(r *http.Request, contentURL string) (string, map[string]string, bool) {
+	for _, upstream := range contentUpstreams {
+		prefixes := []string{
+			upstream.origin,
+			upstream.builtInPath,
+			session.GetProxyPrefix(upstream.sessionProxy(r)),
+		}
+
+		for _, prefix := range prefixes {
+			if prefix == "" {
+				continue
+			}
+
+			if path, found := strings.CutPrefix(contentURL, prefix+"/"); found {
+				return upstream.origin + "/" + path, upstream.headers, true
+			}
+		}
+	}
+
+	return "", nil, false
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/server/session"
+)
+
+func TestUpstreamContentURL(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/novel/1.epub", nil)
+	r.AddCookie(&http.Cookie{Name: string(session.Cookie_ImageProxy), Value: "http://169.254.169.254/latest"})
+
+	tests := []struct {
+		contentURL string
+		want       string
+		ok         bool
+	}{
+		{"/proxy/i.pximg.net/img-original/1.png", "https://i.pximg.net/img-original/1.png", true},
+		{"/proxy/s.pximg.net/common/images/no_profile.png", "https://s.pximg.net/common/images/no_profile.png", true},
+		{"https://i.pximg.net/img-original/1.png", "https://i.pximg.net/img-original/1.png", true},
+		// Rewritten with the proxy of the user, but still fetched from pixiv
+		{"http://169.254.169.254/latest/img-original/1.png", "https://i.pximg.net/img-original/1.png", true},
+		{"http://169.254.169.254/computeMetadata/v1/", "", false},
+		{"http://localhost:8282/admin/cache", "", false},
+		{"/proxy/i.pximg.net.example.com/1.png", "", false},
+	}
+
+	for _, tt := range tests {
+		got, _, ok := UpstreamContentURL(r, tt.contentURL)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("UpstreamContentURL(%q) = %q, %v, want %q, %v", tt.contentURL, got, ok, tt.want, tt.ok)
+		}
+	}
+}
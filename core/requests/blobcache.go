@@ -0,0 +1,186 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Implementation of a persistent cache for binary content, such as transformed
+images and rendered ugoira.
+
+Unlike DiskCache, each entry is stored as its raw bytes, so that the files on
+disk are no bigger than the content and the byte budget is accounted exactly.
+Entries expire a fixed TTL after they were written, which is tracked with the
+modification time of their file.
+*/
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/core/atomicfile"
+)
+
+// BlobCache stores byte slices in separate files under a directory, bounded by
+// their total size.
+type BlobCache struct {
+	dir   string        // Directory where entries are stored
+	ttl   time.Duration // How long entries are kept after being written
+	index *LRUCache     // Tracks known keys in usage order and the size of their files; values are unused
+}
+
+// NewBlobCache creates a new BlobCache that stores at most maxBytes bytes in dir,
+// keeping each entry for ttl.
+//
+// The directory is created if it doesn't exist. Existing entries are loaded into
+// the index, oldest first, and entries that have already expired are removed.
+// It returns an error if maxBytes is not a positive integer.
+func NewBlobCache(dir string, maxBytes int64, ttl time.Duration) (*BlobCache, error) {
+	if maxBytes <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	if err := os.MkdirAll(dir, diskCacheDirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	cache := &BlobCache{dir: dir, ttl: ttl}
+
+	index, err := newLRUCacheWithEvict(0, maxBytes, func(key string, _ any) {
+		removeCacheFile(cache.dir, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cache.index = index
+
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// Add stores data on disk under key and marks it as the most recently used entry.
+//
+// The boolean return indicates whether an eviction actually happened.
+func (c *BlobCache) Add(key string, data []byte) bool {
+	if !isValidDiskCacheKey(key) {
+		return false
+	}
+
+	if err := atomicfile.WriteFile(c.dir, key, data, diskCacheFilePermissions); err != nil {
+		audit.GlobalAuditor.Logger.Errorf("Failed to write cache entry %s: %v", key, err)
+
+		return false
+	}
+
+	return c.index.addWithSize(key, struct{}{}, int64(len(data)))
+}
+
+// Get retrieves the data stored under key, unless it has expired, and marks it
+// as the most recently used entry.
+func (c *BlobCache) Get(key string) ([]byte, bool) {
+	if !isValidDiskCacheKey(key) {
+		return nil, false
+	}
+
+	path := filepath.Join(c.dir, key)
+
+	info, err := os.Stat(path)
+	if err != nil || !time.Now().Before(info.ModTime().Add(c.ttl)) {
+		c.Remove(key)
+
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			audit.GlobalAuditor.Logger.Warnf("Failed to read cache entry %s: %v", key, err)
+		}
+
+		c.index.Remove(key)
+
+		return nil, false
+	}
+
+	c.index.addWithSize(key, struct{}{}, int64(len(data)))
+
+	return data, true
+}
+
+// Remove deletes the entry associated with the given key.
+//
+// It returns true if the key was found and removed, or false otherwise.
+func (c *BlobCache) Remove(key string) bool {
+	if !isValidDiskCacheKey(key) {
+		return false
+	}
+
+	c.index.Remove(key)
+
+	return removeCacheFile(c.dir, key)
+}
+
+// Len returns the number of entries known to this instance.
+func (c *BlobCache) Len() int {
+	return c.index.Len()
+}
+
+// Bytes returns the total size of the entries known to this instance.
+func (c *BlobCache) Bytes() int64 {
+	return c.index.Bytes()
+}
+
+// load populates the index from the entries already present in the cache directory.
+func (c *BlobCache) load() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+
+	infos := make([]fs.FileInfo, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isValidDiskCacheKey(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+
+	// Add the oldest entries first so that they end up at the back of the index.
+	slices.SortFunc(infos, func(a, b fs.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+
+	now := time.Now()
+	loaded := 0
+
+	for _, info := range infos {
+		if !now.Before(info.ModTime().Add(c.ttl)) {
+			removeCacheFile(c.dir, info.Name())
+
+			continue
+		}
+
+		c.index.addWithSize(info.Name(), struct{}{}, info.Size())
+
+		loaded++
+	}
+
+	audit.GlobalAuditor.Logger.Infof("Loaded %d cache entries from %s", loaded, c.dir)
+
+	return nil
+}
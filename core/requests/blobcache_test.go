@@ -0,0 +1,98 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "codeberg.org/pixivfe/pixivfe/core/requests" //nolint:revive
+)
+
+// TestBlobCache verifies that entries are stored as raw bytes, survive a new BlobCache
+// being created for the same directory, and are evicted by their exact size.
+func TestBlobCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	cache, err := NewBlobCache(dir, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := []byte{0x00, 0xff, 'G', 'I', 'F'}
+	cache.Add("a1", data)
+
+	onDisk, err := os.ReadFile(filepath.Join(dir, "a1"))
+	if err != nil || !bytes.Equal(onDisk, data) {
+		t.Fatalf("expected raw bytes on disk, got %v (error %v)", onDisk, err)
+	}
+
+	reopened, err := NewBlobCache(dir, 10, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, ok := reopened.Get("a1"); !ok || !bytes.Equal(got, data) {
+		t.Errorf("expected entry after reload, got %v, %v", got, ok)
+	}
+
+	if reopened.Bytes() != int64(len(data)) {
+		t.Errorf("expected %d bytes, got %d", len(data), reopened.Bytes())
+	}
+
+	// 5 + 6 bytes is over the budget, so the oldest entry is evicted along with its file.
+	reopened.Add("b2", []byte("123456"))
+
+	if _, ok := reopened.Get("a1"); ok {
+		t.Error("expected a1 to be evicted")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "a1")); !os.IsNotExist(err) {
+		t.Errorf("expected evicted file to be removed, got %v", err)
+	}
+
+	// Values larger than the whole budget are never kept.
+	reopened.Add("c3", make([]byte, 11))
+
+	if _, ok := reopened.Get("c3"); ok {
+		t.Error("expected oversized entry to be dropped")
+	}
+}
+
+// TestBlobCache_Expiry verifies that entries expire ttl after they were written.
+func TestBlobCache_Expiry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	cache, err := NewBlobCache(dir, 100, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Add("a1", []byte("old"))
+	cache.Add("b2", []byte("new"))
+
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a1"), past, past); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := cache.Get("a1"); ok {
+		t.Error("expected expired entry to be missed")
+	}
+
+	if _, ok := cache.Get("b2"); !ok {
+		t.Error("expected fresh entry to be found")
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("expected 1 entry, got %d", cache.Len())
+	}
+}
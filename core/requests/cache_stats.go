@@ -0,0 +1,248 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Statistics for the upstream HTTP response cache.
+*/
+package requests
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheFamilySegments is the number of URL path segments used to group URLs into endpoint families.
+const cacheFamilySegments int = 3
+
+// cacheStats tracks statistics for the cache global.
+var cacheStats = newCacheStatsTracker()
+
+// CacheFamilyStats holds cache statistics for a single endpoint family.
+type CacheFamilyStats struct {
+	Family      string // Endpoint family, e.g. "/ajax/illust/{id}"
+	Hits        uint64 // Number of requests served from the cache, including stale responses
+	Misses      uint64 // Number of cacheable requests that had to be sent upstream
+	Evictions   uint64 // Number of entries evicted to make room for new ones
+	Entries     int    // Number of entries currently stored
+	StoredBytes int64  // Total size of the response bodies currently stored
+}
+
+// CacheEntry describes a single entry in the cache.
+type CacheEntry struct {
+	URL       string
+	ExpiresAt time.Time
+	Size      int // Size of the response body in bytes
+}
+
+// cacheEntryInfo is the information kept by cacheStatsTracker for each stored key.
+type cacheEntryInfo struct {
+	family string
+	size   int64
+}
+
+// cacheStatsTracker aggregates cache statistics per endpoint family.
+//
+// It is safe for concurrent use, and never calls into the cache itself,
+// so its methods can be used from within eviction callbacks.
+type cacheStatsTracker struct {
+	mu       sync.Mutex
+	families map[string]*CacheFamilyStats
+	entries  map[string]cacheEntryInfo
+}
+
+// newCacheStatsTracker creates an empty cacheStatsTracker.
+func newCacheStatsTracker() *cacheStatsTracker {
+	return &cacheStatsTracker{
+		families: make(map[string]*CacheFamilyStats),
+		entries:  make(map[string]cacheEntryInfo),
+	}
+}
+
+// family returns the statistics for the named family, creating them if needed.
+//
+// The caller must hold t.mu.
+func (t *cacheStatsTracker) family(name string) *CacheFamilyStats {
+	stats, ok := t.families[name]
+	if !ok {
+		stats = &CacheFamilyStats{Family: name}
+		t.families[name] = stats
+	}
+
+	return stats
+}
+
+// recordHit records a request for rawURL that was served from the cache.
+func (t *cacheStatsTracker) recordHit(rawURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.family(cacheFamily(rawURL)).Hits++
+}
+
+// recordMiss records a cacheable request for rawURL that had to be sent upstream.
+func (t *cacheStatsTracker) recordMiss(rawURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.family(cacheFamily(rawURL)).Misses++
+}
+
+// recordStore records that a response body of size bytes for rawURL was stored under key,
+// replacing any previous entry for the same key.
+func (t *cacheStatsTracker) recordStore(key, rawURL string, size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(key)
+
+	info := cacheEntryInfo{family: cacheFamily(rawURL), size: int64(size)}
+	t.entries[key] = info
+
+	stats := t.family(info.family)
+	stats.Entries++
+	stats.StoredBytes += info.size
+}
+
+// recordRemove records that the entry stored under key was removed from the cache.
+//
+// If evicted is true, the removal is counted as an eviction for the entry's family.
+func (t *cacheStatsTracker) recordRemove(key string, evicted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, ok := t.removeLocked(key)
+	if ok && evicted {
+		t.family(info.family).Evictions++
+	}
+}
+
+// removeLocked stops tracking key, returning the information that was kept for it.
+//
+// The caller must hold t.mu.
+func (t *cacheStatsTracker) removeLocked(key string) (cacheEntryInfo, bool) {
+	info, ok := t.entries[key]
+	if !ok {
+		return cacheEntryInfo{}, false
+	}
+
+	delete(t.entries, key)
+
+	stats := t.family(info.family)
+	stats.Entries--
+	stats.StoredBytes -= info.size
+
+	return info, true
+}
+
+// reset clears all statistics.
+func (t *cacheStatsTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clear(t.families)
+	clear(t.entries)
+}
+
+// snapshot returns a copy of the statistics for every family, sorted by family name.
+func (t *cacheStatsTracker) snapshot() []CacheFamilyStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]CacheFamilyStats, 0, len(t.families))
+
+	for _, stats := range t.families {
+		result = append(result, *stats)
+	}
+
+	slices.SortFunc(result, func(a, b CacheFamilyStats) int {
+		return strings.Compare(a.Family, b.Family)
+	})
+
+	return result
+}
+
+// CacheStats returns the cache statistics for every endpoint family seen since startup,
+// sorted by family name.
+//
+// Returns nil if caching is disabled.
+func CacheStats() []CacheFamilyStats {
+	if cache == nil {
+		return nil
+	}
+
+	return cacheStats.snapshot()
+}
+
+// CacheUsage returns the active limits of the cache, as the maximum number of entries
+// and the maximum total size in bytes where 0 means no limit, followed by the total size
+// of the responses currently stored.
+//
+// Returns zeros if caching is disabled.
+func CacheUsage() (int, int64, int64) {
+	if cache == nil {
+		return 0, 0, 0
+	}
+
+	size, maxBytes := cacheLimits()
+
+	return size, maxBytes, cache.Bytes()
+}
+
+// CacheEntries returns the entries currently stored in the cache, from the oldest to the newest.
+//
+// Returns nil if caching is disabled.
+func CacheEntries() []CacheEntry {
+	if cache == nil {
+		return nil
+	}
+
+	keys := cache.Keys()
+	entries := make([]CacheEntry, 0, len(keys))
+
+	for _, key := range keys {
+		value, ok := cache.Peek(key)
+		if !ok {
+			continue
+		}
+
+		item, ok := value.(CachedItem)
+		if !ok || item.Response == nil {
+			continue
+		}
+
+		entries = append(entries, CacheEntry{
+			URL:       item.URL,
+			ExpiresAt: item.ExpiresAt,
+			Size:      len(item.Response.Body),
+		})
+	}
+
+	return entries
+}
+
+// cacheFamily groups rawURL into an endpoint family for statistics.
+//
+// The family is made up of the first few segments of the URL path,
+// with numeric segments replaced by "{id}" (e.g. "/ajax/illust/{id}").
+func cacheFamily(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "unknown"
+	}
+
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	if len(segments) > cacheFamilySegments {
+		segments = segments[:cacheFamilySegments]
+	}
+
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = "{id}"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+)
+
+// TestCacheFamily verifies that URLs are grouped into endpoint families.
+func TestCacheFamily(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.pixiv.net/ajax/illust/123", "/ajax/illust/{id}"},
+		{"https://www.pixiv.net/ajax/illust/123/pages?lang=en", "/ajax/illust/{id}"},
+		{"https://www.pixiv.net/ajax/user/1/profile/all", "/ajax/user/{id}"},
+		{"https://www.pixiv.net/touch/ajax/ranking/illust?mode=daily", "/touch/ajax/ranking"},
+		{"https://www.pixiv.net/", "/"},
+	}
+
+	for _, tt := range tests {
+		if got := cacheFamily(tt.url); got != tt.want {
+			t.Errorf("cacheFamily(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// TestCacheStats verifies that hits, misses, stores, evictions and removals
+// are attributed to the right endpoint family.
+func TestCacheStats(t *testing.T) {
+	originalConfig := config.GlobalConfig
+	originalCache := cache
+
+	t.Cleanup(func() {
+		config.GlobalConfig = originalConfig
+		cache = originalCache
+
+		cacheStats.reset()
+	})
+
+	config.GlobalConfig.Cache.Enabled = true
+	config.GlobalConfig.Cache.TTL = time.Hour
+	config.GlobalConfig.Cache.Size = 2
+	config.GlobalConfig.Cache.MaxBytes = 0
+
+	lru, err := newLRUCacheWithEvict(2, 0, recordEviction)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache = lru
+
+	cacheStats.reset()
+
+	illust1 := "https://www.pixiv.net/ajax/illust/1"
+	illust2 := "https://www.pixiv.net/ajax/illust/2"
+	user1 := "https://www.pixiv.net/ajax/user/1"
+
+	for _, url := range []string{illust1, illust2, user1} {
+		manageCaching(url, "", CachePolicy{ShouldUseCache: true, TTL: time.Hour}, &SimpleHTTPResponse{StatusCode: http.StatusOK, Body: []byte(url)})
+	}
+
+	cacheStats.recordHit(user1)
+	cacheStats.recordMiss(illust1)
+
+	stats := CacheStats()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 families, got %d: %+v", len(stats), stats)
+	}
+
+	illust, user := stats[0], stats[1]
+
+	// Adding user1 to a cache of size 2 evicts illust1.
+	want := CacheFamilyStats{Family: "/ajax/illust/{id}", Misses: 1, Evictions: 1, Entries: 1, StoredBytes: int64(len(illust2))}
+	if illust != want {
+		t.Errorf("got %+v, want %+v", illust, want)
+	}
+
+	want = CacheFamilyStats{Family: "/ajax/user/{id}", Hits: 1, Entries: 1, StoredBytes: int64(len(user1))}
+	if user != want {
+		t.Errorf("got %+v, want %+v", user, want)
+	}
+
+	if purged, _ := InvalidateURLs([]string{"https://www.pixiv.net/ajax/user/"}); purged != 1 {
+		t.Errorf("expected 1 purged entry, got %d", purged)
+	}
+
+	if entries := CacheEntries(); len(entries) != 1 || entries[0].URL != illust2 {
+		t.Errorf("expected only %s to remain cached, got %+v", illust2, entries)
+	}
+
+	if user := CacheStats()[1]; user.Entries != 0 || user.StoredBytes != 0 || user.Evictions != 0 {
+		t.Errorf("expected purge to clear stored entries without counting an eviction, got %+v", user)
+	}
+
+	if size, maxBytes, bytes := CacheUsage(); size != 2 || maxBytes != 0 || bytes != int64(len(illust2)) {
+		t.Errorf("expected a limit of 2 entries with %d bytes used, got %d entries, %d bytes with %d bytes used",
+			len(illust2), size, maxBytes, bytes)
+	}
+
+	// Cache.Size is ignored when the cache is bounded by size.
+	config.GlobalConfig.Cache.MaxBytes = 1024
+
+	if size, maxBytes, _ := CacheUsage(); size != 0 || maxBytes != 1024 {
+		t.Errorf("expected a limit of 1024 bytes, got %d entries, %d bytes", size, maxBytes)
+	}
+}
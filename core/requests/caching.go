@@ -9,9 +9,14 @@ package requests
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -23,16 +28,33 @@ import (
 
 var (
 	cacheSeed uint64
-	cache     *LRUCache
-
-	// excludedCachePaths lists API endpoints for which responses are never cached,
-	// regardless of any other factors.
-	excludedCachePaths = []string{
-		"/ajax/discovery/artworks",
-		"/ajax/discovery/novels",
-		"/ajax/discovery/users",
-		"/ajax/illust/new",
-	}
+	cache     Cache
+)
+
+// Cache is the interface implemented by upstream response cache backends.
+//
+// Values stored in a Cache are expected to be of type CachedItem.
+type Cache interface {
+	// Add adds or updates a value, returning whether an eviction happened.
+	Add(key string, value any) bool
+	// Get retrieves a value and marks it as recently used.
+	Get(key string) (any, bool)
+	// Peek retrieves a value without modifying the usage order.
+	Peek(key string) (any, bool)
+	// Remove deletes a value, returning whether it was present.
+	Remove(key string) bool
+	// Keys returns all keys, from the oldest to the newest.
+	Keys() []string
+	// Len returns the number of stored values.
+	Len() int
+	// Bytes returns the total size of the stored values.
+	Bytes() int64
+}
+
+// Compile-time checks that both backends implement Cache.
+var (
+	_ Cache = (*LRUCache)(nil)
+	_ Cache = (*DiskCache)(nil)
 )
 
 // CachedItem represents a cached HTTP response along with its expiration time and original URL.
@@ -49,11 +71,18 @@ type CachePolicy struct {
 	ShouldUseCache bool
 	// The cached response if available and valid.
 	CachedResponse *SimpleHTTPResponse
+	// How long a fresh response should be cached for.
+	TTL time.Duration
+	// Whether CachedResponse has expired but is still within the
+	// stale-while-revalidate grace period, and should be refreshed.
+	Stale bool
 }
 
 // Setup initializes the API response cache based on parameters in GlobalConfig.
 //
-// It sets up an LRU cache with a specified size and logs the cache parameters.
+// It sets up the configured cache backend with a specified size and logs the cache parameters.
+// If Cache.MaxBytes is set, the cache is bounded by the total size of the stored responses
+// instead of Cache.Size.
 // If caching is disabled in the configuration, it skips initialization.
 func Setup() {
 	if !config.GlobalConfig.Cache.Enabled {
@@ -64,23 +93,126 @@ func Setup() {
 
 	var err error
 
-	// Initialize the LRU cache with the configured parameters.
-	cache, err = NewLRUCache(config.GlobalConfig.Cache.Size)
-	if err != nil {
-		audit.GlobalAuditor.Logger.Panicf("Failed to create cache: %v", err)
+	size, maxBytes := cacheLimits()
+
+	cacheStats.reset()
+
+	switch config.GlobalConfig.Cache.Backend {
+	case config.DiskCacheBackend:
+		cache, err = newDiskCacheWithEvict(config.GlobalConfig.Cache.Path, size, maxBytes, recordEviction)
+		if err != nil {
+			audit.GlobalAuditor.Logger.Panicf("Failed to create cache: %v", err)
+		}
+
+		// The seed must be stable across restarts for persisted entries to be found again.
+		cacheSeed, err = loadOrCreateCacheSeed(config.GlobalConfig.Cache.Path)
+		if err != nil {
+			audit.GlobalAuditor.Logger.Panicf("Failed to load cache key seed: %v", err)
+		}
+	default:
+		// Initialize the LRU cache with the configured parameters.
+		cache, err = newLRUCacheWithEvict(size, maxBytes, recordEviction)
+		if err != nil {
+			audit.GlobalAuditor.Logger.Panicf("Failed to create cache: %v", err)
+		}
+
+		cacheSeed, err = generateCacheSeed()
+		if err != nil {
+			audit.GlobalAuditor.Logger.Panicf("Failed to generate cache key seed: %v", err)
+		}
 	}
 
+	// Account for any entries loaded from a persistent backend.
+	for _, key := range cache.Keys() {
+		if item, ok := cache.Peek(key); ok {
+			if cachedItem, ok := item.(CachedItem); ok {
+				cacheStats.recordStore(key, cachedItem.URL, len(cachedItem.Response.Body))
+			}
+		}
+	}
+
+	audit.GlobalAuditor.Logger.Infow("Cache initialized",
+		"backend", config.GlobalConfig.Cache.Backend,
+		"size", size,
+		"maxBytes", maxBytes,
+		"ttl", config.GlobalConfig.Cache.TTL,
+	)
+}
+
+// cacheLimits returns the maximum number of entries and the maximum total size in bytes
+// of the cache, where 0 means no limit.
+//
+// Cache.Size is ignored if Cache.MaxBytes is set.
+func cacheLimits() (int, int64) {
+	size, maxBytes := config.GlobalConfig.Cache.Size, config.GlobalConfig.Cache.MaxBytes
+	if maxBytes > 0 {
+		size = 0
+	}
+
+	return size, maxBytes
+}
+
+// recordEviction is the eviction callback for the cache backends created by Setup.
+func recordEviction(key string, _ any) {
+	cacheStats.recordRemove(key, true)
+}
+
+// removeCacheEntry removes the entry stored under key from the cache and its statistics.
+func removeCacheEntry(key string) bool {
+	cacheStats.recordRemove(key, false)
+
+	return cache.Remove(key)
+}
+
+// generateCacheSeed returns a random seed for generateCacheKey.
+func generateCacheSeed() (uint64, error) {
 	// Create a byte slice to hold the random seed.
 	var seedBytes [8]byte
 
 	// Read 8 random bytes from the crypto/rand reader.
-	_, err = rand.Read(seedBytes[:])
-	if err != nil {
-		audit.GlobalAuditor.Logger.Panicf("Failed to generate cache key seed: %v", err)
+	if _, err := rand.Read(seedBytes[:]); err != nil {
+		return 0, err
 	}
 
 	// Convert the byte slice to a uint64 seed using little endian.
-	cacheSeed = binary.LittleEndian.Uint64(seedBytes[:])
+	return binary.LittleEndian.Uint64(seedBytes[:]), nil
+}
+
+// loadOrCreateCacheSeed reads the cache key seed stored in dir,
+// generating and storing a new one if it doesn't exist yet.
+//
+// The seed file is only readable by the current user, as knowing it
+// would allow cache keys to be precomputed.
+func loadOrCreateCacheSeed(dir string) (uint64, error) {
+	seedPath := filepath.Join(dir, diskCacheSeedFilename)
+
+	data, err := os.ReadFile(seedPath)
+	if err == nil {
+		if len(data) != 8 {
+			return 0, fmt.Errorf("%s has an invalid length of %d bytes", seedPath, len(data))
+		}
+
+		return binary.LittleEndian.Uint64(data), nil
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+
+	seed, err := generateCacheSeed()
+	if err != nil {
+		return 0, err
+	}
+
+	var seedBytes [8]byte
+
+	binary.LittleEndian.PutUint64(seedBytes[:], seed)
+
+	if err := os.WriteFile(seedPath, seedBytes[:], diskCacheFilePermissions); err != nil {
+		return 0, err
+	}
+
+	return seed, nil
 }
 
 // The `generateCacheKey` function securely binds cached responses to both the request URL and the full authenticated
@@ -109,6 +241,9 @@ func generateCacheKey(url, userToken string) string {
 //
 // It returns a CachePolicy struct indicating whether to fetch from cache,
 // whether to store the response in cache, and the cached response if available.
+//
+// The TTL for the URL is taken from the first matching rule in Cache.Rules,
+// falling back to Cache.TTL; rules marked as noCache disable caching entirely.
 func determineCachePolicy(rawURL, userToken string, headers http.Header) CachePolicy {
 	if !config.GlobalConfig.Cache.Enabled {
 		return CachePolicy{}
@@ -121,11 +256,10 @@ func determineCachePolicy(rawURL, userToken string, headers http.Header) CachePo
 
 	urlPath := path.Clean(parsedURL.Path)
 
-	// Check if the path is excluded from caching
-	for _, exclPath := range excludedCachePaths {
-		if strings.HasPrefix(urlPath, exclPath) {
-			return CachePolicy{}
-		}
+	// Check if the URL is excluded from caching
+	ttl, cacheable := config.GlobalConfig.CacheTTLFor(urlPath, parsedURL.RawQuery)
+	if !cacheable {
+		return CachePolicy{}
 	}
 
 	// Retrieve the Cache-Control header from the downstream request and check for "no-cache"
@@ -143,19 +277,33 @@ func determineCachePolicy(rawURL, userToken string, headers http.Header) CachePo
 		item, ok := cachedItem.(CachedItem)
 		if !ok {
 			// The cached item is not of the expected type, remove the invalid entry
-			cache.Remove(cacheKey)
+			removeCacheEntry(cacheKey)
 
 			return CachePolicy{}
 		}
 
-		if time.Now().Before(item.ExpiresAt) {
+		now := time.Now()
+
+		if now.Before(item.ExpiresAt) {
+			return CachePolicy{
+				ShouldUseCache: true,
+				CachedResponse: item.Response,
+				TTL:            ttl,
+			}
+		}
+
+		// Expired, but still within the grace period
+		if now.Before(item.ExpiresAt.Add(config.GlobalConfig.Cache.StaleWhileRevalidate)) {
 			return CachePolicy{
 				ShouldUseCache: true,
 				CachedResponse: item.Response,
+				TTL:            ttl,
+				Stale:          true,
 			}
 		}
+
 		// Cache expired
-		cache.Remove(cacheKey)
+		removeCacheEntry(cacheKey)
 	}
 
 	// Determine if the API response should use the cache based on headers
@@ -163,32 +311,32 @@ func determineCachePolicy(rawURL, userToken string, headers http.Header) CachePo
 
 	return CachePolicy{
 		ShouldUseCache: shouldUseCache,
+		TTL:            ttl,
 	}
 }
 
-// manageCaching decides if a cached response is available, or if the new response should be stored and used.
-func manageCaching(rawURL, userToken string, headers http.Header, freshResp *SimpleHTTPResponse) *SimpleHTTPResponse {
-	policy := determineCachePolicy(rawURL, userToken, headers)
+// manageCaching stores the fresh response in the cache if policy allows it, and returns it.
+//
+// policy is the one determined before the request was made, so that the cache isn't
+// read again. A stale cached response is always replaced by the fresh response.
+func manageCaching(rawURL, userToken string, policy CachePolicy, freshResp *SimpleHTTPResponse) *SimpleHTTPResponse {
+	// If we should cache this fresh response, store it now.
+	if policy.ShouldUseCache && freshResp != nil {
+		cacheKey := generateCacheKey(rawURL, userToken)
 
-	// If there is a cached response and we're allowed to use it, return it immediately.
-	if policy.ShouldUseCache && policy.CachedResponse != nil {
-		return policy.CachedResponse
-	}
+		// Record the entry first, as a response too large for the cache is evicted as soon as it's added.
+		cacheStats.recordStore(cacheKey, rawURL, len(freshResp.Body))
 
-	// Otherwise, if we should cache this fresh response, store it now.
-	if policy.ShouldUseCache && freshResp != nil {
-		ttl := config.GlobalConfig.Cache.TTL
 		cache.Add(
-			generateCacheKey(rawURL, userToken),
+			cacheKey,
 			CachedItem{
 				Response:  freshResp,
-				ExpiresAt: time.Now().Add(ttl),
+				ExpiresAt: time.Now().Add(policy.TTL),
 				URL:       rawURL,
 			},
 		)
 	}
 
-	// Return the fresh response if no cached copy was used.
 	return freshResp
 }
 
@@ -243,7 +391,7 @@ func InvalidateURLs(urlPrefixes []string) (int, []string) {
 			continue
 		}
 
-		cache.Remove(key)
+		removeCacheEntry(key)
 
 		invalidated++
 
@@ -0,0 +1,290 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Implementation of a persistent, file-per-key cache backend.
+
+Each entry is stored as a JSON-encoded CachedItem in its own file, named after
+the cache key. Expiration times are stored alongside the response, so TTLs
+carry over when the process restarts.
+
+An in-memory LRUCache is used as an index to track usage order and to bound
+the number of files kept on disk.
+*/
+package requests
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/core/atomicfile"
+	"github.com/goccy/go-json"
+)
+
+const (
+	diskCacheDirPermissions  os.FileMode = 0o700
+	diskCacheFilePermissions os.FileMode = 0o600
+
+	// diskCacheSeedFilename is the name of the file holding the cache key seed.
+	diskCacheSeedFilename string = "seed"
+)
+
+// DiskCache implements Cache by storing each entry in a separate file under a directory.
+//
+// The directory may be shared between multiple PixivFE instances. Entries written by another
+// instance are picked up on Get, though each instance only evicts the entries it knows about.
+//
+// Only values of type CachedItem are persisted; other values are ignored by Add.
+type DiskCache struct {
+	dir   string    // Directory where entries are stored
+	index *LRUCache // Tracks known keys in usage order and the size of their response bodies; values are unused
+}
+
+// NewDiskCache creates a new DiskCache that stores at most size entries in dir.
+//
+// The directory is created if it doesn't exist. Existing entries are loaded into the index,
+// oldest first, and entries that have already expired or can't be decoded are removed.
+func NewDiskCache(dir string, size int) (*DiskCache, error) {
+	return newDiskCacheWithEvict(dir, size, 0, nil)
+}
+
+// NewByteLimitedDiskCache creates a new DiskCache that stores at most maxBytes bytes
+// of response bodies in dir, regardless of the number of entries.
+//
+// It returns an error if maxBytes is not a positive integer.
+func NewByteLimitedDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if maxBytes <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	return newDiskCacheWithEvict(dir, 0, maxBytes, nil)
+}
+
+// newDiskCacheWithEvict creates a new DiskCache bounded by size entries and maxBytes bytes
+// of response bodies, where a limit of 0 is ignored, that calls onEvict after an entry
+// is evicted to make room for a new one, once its file has been removed.
+//
+// As with newLRUCacheWithEvict, onEvict must not call back into the cache.
+func newDiskCacheWithEvict(
+	dir string,
+	size int,
+	maxBytes int64,
+	onEvict func(key string, value any),
+) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, diskCacheDirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	cache := &DiskCache{dir: dir}
+
+	index, err := newLRUCacheWithEvict(size, maxBytes, func(key string, value any) {
+		cache.removeFile(key)
+
+		if onEvict != nil {
+			onEvict(key, value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cache.index = index
+
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// Add stores a CachedItem on disk and marks it as the most recently used entry.
+//
+// The boolean return indicates whether an eviction actually happened.
+func (c *DiskCache) Add(key string, value any) bool {
+	item, ok := value.(CachedItem)
+	if !ok || !isValidDiskCacheKey(key) {
+		return false
+	}
+
+	if err := c.writeFile(key, item); err != nil {
+		audit.GlobalAuditor.Logger.Errorf("Failed to write cache entry %s: %v", key, err)
+
+		return false
+	}
+
+	return c.index.addWithSize(key, struct{}{}, entrySize(item))
+}
+
+// Get retrieves the CachedItem for a given key and marks it as the most recently used entry.
+func (c *DiskCache) Get(key string) (any, bool) {
+	item, ok := c.Peek(key)
+	if !ok {
+		c.index.Remove(key)
+
+		return nil, false
+	}
+
+	c.index.addWithSize(key, struct{}{}, entrySize(item))
+
+	return item, true
+}
+
+// Peek retrieves the CachedItem for a given key without modifying the usage order.
+func (c *DiskCache) Peek(key string) (any, bool) {
+	if !isValidDiskCacheKey(key) {
+		return nil, false
+	}
+
+	item, err := c.readFile(key)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			audit.GlobalAuditor.Logger.Warnf("Failed to read cache entry %s: %v", key, err)
+		}
+
+		return nil, false
+	}
+
+	return item, true
+}
+
+// Remove deletes the entry associated with the given key.
+//
+// It returns true if the key was found and removed, or false otherwise.
+func (c *DiskCache) Remove(key string) bool {
+	if !isValidDiskCacheKey(key) {
+		return false
+	}
+
+	c.index.Remove(key)
+
+	return c.removeFile(key)
+}
+
+// Keys returns a slice of all keys known to this instance, from the oldest to the newest.
+func (c *DiskCache) Keys() []string {
+	return c.index.Keys()
+}
+
+// Len returns the number of entries known to this instance.
+func (c *DiskCache) Len() int {
+	return c.index.Len()
+}
+
+// Bytes returns the total size of the response bodies of the entries known to this instance.
+func (c *DiskCache) Bytes() int64 {
+	return c.index.Bytes()
+}
+
+// load populates the index from the entries already present in the cache directory.
+func (c *DiskCache) load() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory %s: %w", c.dir, err)
+	}
+
+	type diskEntry struct {
+		key     string
+		modTime time.Time
+	}
+
+	found := make([]diskEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if entry.IsDir() || !isValidDiskCacheKey(name) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		found = append(found, diskEntry{key: name, modTime: info.ModTime()})
+	}
+
+	// Add the oldest entries first so that they end up at the back of the index.
+	slices.SortFunc(found, func(a, b diskEntry) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	now := time.Now()
+	loaded := 0
+
+	for _, entry := range found {
+		item, err := c.readFile(entry.key)
+		if err != nil || !now.Before(item.ExpiresAt) {
+			c.removeFile(entry.key)
+
+			continue
+		}
+
+		c.index.addWithSize(entry.key, struct{}{}, entrySize(item))
+
+		loaded++
+	}
+
+	audit.GlobalAuditor.Logger.Infof("Loaded %d cache entries from %s", loaded, c.dir)
+
+	return nil
+}
+
+// readFile reads and decodes the entry stored under key.
+func (c *DiskCache) readFile(key string) (CachedItem, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return CachedItem{}, err
+	}
+
+	var item CachedItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return CachedItem{}, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+
+	if item.Response == nil {
+		return CachedItem{}, ErrIncompatibleRespBody
+	}
+
+	return item, nil
+}
+
+// writeFile encodes item and atomically writes it to the file for key.
+func (c *DiskCache) writeFile(key string, item CachedItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	return atomicfile.WriteFile(c.dir, key, data, diskCacheFilePermissions)
+}
+
+// removeFile deletes the file for key, returning true if it existed.
+func (c *DiskCache) removeFile(key string) bool {
+	return removeCacheFile(c.dir, key)
+}
+
+// removeCacheFile deletes the file for key in dir, returning true if it existed.
+func removeCacheFile(dir, key string) bool {
+	err := os.Remove(filepath.Join(dir, key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		audit.GlobalAuditor.Logger.Warnf("Failed to remove cache entry %s: %v", key, err)
+	}
+
+	return err == nil
+}
+
+// isValidDiskCacheKey reports whether key is safe to use as a filename.
+//
+// Keys generated by generateCacheKey are lowercase hexadecimal strings,
+// so anything else is rejected to rule out path traversal.
+func isValidDiskCacheKey(key string) bool {
+	return key != "" && strings.Trim(key, "0123456789abcdef") == ""
+}
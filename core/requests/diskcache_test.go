@@ -0,0 +1,172 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "codeberg.org/pixivfe/pixivfe/core/requests" //nolint:revive
+)
+
+// newTestItem returns a CachedItem for url that expires after ttl.
+func newTestItem(url string, ttl time.Duration) CachedItem {
+	return CachedItem{
+		Response:  &SimpleHTTPResponse{StatusCode: 200, Body: []byte(url)},
+		ExpiresAt: time.Now().Add(ttl),
+		URL:       url,
+	}
+}
+
+// TestDiskCache_AddAndGet verifies that an entry can be stored and read back.
+func TestDiskCache_AddAndGet(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewDiskCache(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Add("abc", newTestItem("https://www.pixiv.net/ajax/illust/1", time.Hour))
+
+	value, ok := cache.Get("abc")
+	if !ok {
+		t.Fatal("expected to retrieve value for key 'abc'")
+	}
+
+	item, ok := value.(CachedItem)
+	if !ok {
+		t.Fatalf("expected CachedItem, got %T", value)
+	}
+
+	if item.URL != "https://www.pixiv.net/ajax/illust/1" || string(item.Response.Body) != item.URL {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	// Values that aren't a CachedItem are never stored.
+	cache.Add("def", "not a cached item")
+
+	if _, ok := cache.Get("def"); ok {
+		t.Error("expected non-CachedItem value to be ignored")
+	}
+}
+
+// TestDiskCache_Persistence checks that entries and their expiry survive a new DiskCache
+// being created for the same directory, and that expired entries are dropped on load.
+func TestDiskCache_Persistence(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	cache, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fresh := newTestItem("https://www.pixiv.net/ajax/user/1", time.Hour)
+	cache.Add("a1", fresh)
+	cache.Add("b2", newTestItem("https://www.pixiv.net/ajax/user/2", -time.Minute))
+
+	reopened, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reopened.Len() != 1 {
+		t.Errorf("expected 1 entry after reload, got %d", reopened.Len())
+	}
+
+	value, ok := reopened.Get("a1")
+	if !ok {
+		t.Fatal("expected 'a1' to persist")
+	}
+
+	if !value.(CachedItem).ExpiresAt.Equal(fresh.ExpiresAt) {
+		t.Errorf("expected ExpiresAt %v, got %v", fresh.ExpiresAt, value.(CachedItem).ExpiresAt)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "b2")); !os.IsNotExist(err) {
+		t.Error("expected expired entry 'b2' to be removed from disk")
+	}
+}
+
+// TestDiskCache_Eviction ensures that evicted entries are removed from disk.
+func TestDiskCache_Eviction(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	cache, err := NewDiskCache(dir, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Add("1", newTestItem("one", time.Hour))
+	cache.Add("2", newTestItem("two", time.Hour))
+
+	if evicted := cache.Add("3", newTestItem("three", time.Hour)); !evicted {
+		t.Error("expected eviction when adding third key to size 2 cache")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "1")); !os.IsNotExist(err) {
+		t.Error("expected evicted entry '1' to be removed from disk")
+	}
+
+	if !cache.Remove("2") {
+		t.Error("expected to remove existing key '2'")
+	}
+
+	if cache.Remove("2") {
+		t.Error("expected false when removing a non-existent key")
+	}
+}
+
+// TestDiskCache_ByteLimited verifies that a byte-limited cache evicts entries once their bodies exceed the limit.
+func TestDiskCache_ByteLimited(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewByteLimitedDiskCache(t.TempDir(), 0); err != ErrInvalidSize {
+		t.Errorf("expected ErrInvalidSize for a limit of 0, got %v", err)
+	}
+
+	dir := t.TempDir()
+
+	cache, err := NewByteLimitedDiskCache(dir, 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Add("1", newTestItem("four", time.Hour))
+	cache.Add("2", newTestItem("four", time.Hour))
+
+	if evicted := cache.Add("3", newTestItem("four", time.Hour)); !evicted {
+		t.Error("expected eviction when exceeding the byte limit")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "1")); !os.IsNotExist(err) {
+		t.Error("expected evicted entry '1' to be removed from disk")
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", cache.Len())
+	}
+}
+
+// TestDiskCache_InvalidKey confirms that keys which aren't safe filenames are rejected.
+func TestDiskCache_InvalidKey(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewDiskCache(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Add("../escape", newTestItem("escape", time.Hour))
+
+	if cache.Len() != 0 {
+		t.Errorf("expected invalid key to be rejected, got %d entries", cache.Len())
+	}
+}
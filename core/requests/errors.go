@@ -0,0 +1,87 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/tokenmanager"
+)
+
+// Sentinel errors matched by StatusError, for use with errors.Is.
+var (
+	ErrNotFound     = errors.New("upstream resource not found")
+	ErrForbidden    = errors.New("upstream resource forbidden")
+	ErrUnauthorized = errors.New("upstream request unauthorized")
+	ErrRateLimited  = errors.New("upstream request rate limited")
+)
+
+// StatusError is returned when the pixiv API responds with a status code other than 200.
+//
+// Use errors.Is with ErrNotFound, ErrForbidden, ErrUnauthorized or ErrRateLimited
+// to check for specific status codes.
+type StatusError struct {
+	StatusCode int
+	message    error // Localized message shown to users
+}
+
+// newStatusError creates a StatusError with a message for statusCode, localized
+// to the locale of ctx.
+func newStatusError(ctx context.Context, statusCode int) *StatusError {
+	var message error
+
+	switch statusCode {
+	case http.StatusNotFound:
+		message = i18n.ErrorContext(ctx, "The requested content was not found on pixiv. It may have been deleted or made private.")
+	case http.StatusForbidden:
+		message = i18n.ErrorContext(ctx, "Access to the requested content was denied by pixiv.")
+	default:
+		message = i18n.ErrorfContext(ctx, "HTTP status code: %d", statusCode)
+	}
+
+	return &StatusError{
+		StatusCode: statusCode,
+		message:    message,
+	}
+}
+
+func (e *StatusError) Error() string {
+	return e.message.Error()
+}
+
+// Is reports whether target is the sentinel error corresponding to e.StatusCode.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// tokenStatusFor returns how a non-OK upstream status code should affect the token
+// used for the request.
+//
+// Rate limiting and server errors put the token into backoff, while 401 indicates
+// that the token is invalid or expired. Other status codes, such as 404 for deleted
+// content, aren't caused by the token, so it's left unchanged.
+func tokenStatusFor(statusCode int) (tokenmanager.TokenStatus, bool) {
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError:
+		return tokenmanager.TimedOut, true
+	case statusCode == http.StatusUnauthorized:
+		return tokenmanager.Invalid, true
+	default:
+		return tokenmanager.Good, false
+	}
+}
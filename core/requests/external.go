@@ -22,11 +22,17 @@ const (
 	NoToken     string = "NoToken"     // Don't set PHPSESSID at all
 )
 
+// maxContentBytes is the largest response body that FetchContent reads into memory.
+//
+// Original images on pixiv are limited to 32 MiB, and ugoira archives stay well below this.
+const maxContentBytes int64 = 64 << 20 // 64 MiB
+
 var (
 	ErrInvalidJSON              = errors.New("response contained invalid JSON")
 	ErrAPIResponseError         = errors.New("API response indicated error")
 	ErrIncompatibleRespBody     = errors.New("incompatible response body")
 	ErrMissingRequiredPHPSESSID = errors.New("PHPSESSID cookie is required for POST requests")
+	ErrContentTooLarge          = errors.New("response body is too large")
 )
 
 // FetchJSON makes a GET request and validates the response is valid JSON.
@@ -123,6 +129,41 @@ func PerformGETReader(
 	return bytes.NewReader(response.Body), nil
 }
 
+// FetchContent makes a GET request for content served outside of the pixiv API,
+// such as images, with headers added to the request.
+//
+// Unlike PerformGET, no token is sent and the response isn't cached.
+//
+// Returns a StatusError if the response status code isn't 200, or an error wrapping
+// ErrContentTooLarge if the response body is larger than maxContentBytes.
+func FetchContent(ctx context.Context, url string, headers map[string]string) ([]byte, error) {
+	return FetchContentLimited(ctx, url, headers, maxContentBytes)
+}
+
+// FetchContentLimited is like FetchContent, but fails with an error wrapping
+// ErrContentTooLarge if the response body is larger than maxBytes instead.
+func FetchContentLimited(ctx context.Context, url string, headers map[string]string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+
+	for key, value := range headers {
+		req.Header.Add(key, value)
+	}
+
+	resp, err := makeRequest(ctx, req, url, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(ctx, resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
 // PerformPOST performs a POST request.
 func PerformPOST(
 	ctx context.Context,
@@ -148,10 +189,30 @@ func PerformPOST(
 	})
 }
 
+// forwardedRequestHeaders lists the client request headers that ProxyHandler
+// passes on upstream.
+var forwardedRequestHeaders = []string{
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Range",
+	"Range",
+}
+
+// ProxyHandler proxies r to the path of r.URL under baseURL, with headers added to
+// the upstream request.
+//
+// Validators and ranges sent by the client are forwarded, so that upstream can
+// respond with 304 Not Modified or 206 Partial Content. HEAD requests are made
+// upstream as is, without downloading the body.
 func ProxyHandler(w http.ResponseWriter, r *http.Request, baseURL string, headers map[string]string) error {
 	targetURL := fmt.Sprintf("%s/%s?%s", baseURL, r.URL.Path, r.URL.Query().Encode())
 
-	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, targetURL, nil)
+	method := http.MethodGet
+	if r.Method == http.MethodHead {
+		method = http.MethodHead
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), method, targetURL, nil)
 	if err != nil {
 		if isContextError(err) {
 			return nil
@@ -160,6 +221,12 @@ func ProxyHandler(w http.ResponseWriter, r *http.Request, baseURL string, header
 		return fmt.Errorf("failed to create request for %s: %w", baseURL, err)
 	}
 
+	for _, key := range forwardedRequestHeaders {
+		for _, value := range r.Header.Values(key) {
+			req.Header.Add(key, value)
+		}
+	}
+
 	for key, value := range headers {
 		req.Header.Add(key, value)
 	}
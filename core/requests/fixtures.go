@@ -0,0 +1,272 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core/atomicfile"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+	"github.com/goccy/go-json"
+)
+
+const (
+	fixtureDirPermissions  os.FileMode = 0o755
+	fixtureFilePermissions os.FileMode = 0o644
+)
+
+// ErrFixtureNotFound is returned in replay mode when no fixture was recorded for a request.
+var ErrFixtureNotFound = errors.New("no fixture recorded for request")
+
+// recordedHeaders lists the response headers stored in fixtures.
+//
+// Other headers, such as Set-Cookie, are left out so that fixtures
+// can be committed without leaking account details.
+var recordedHeaders = []string{"Content-Type", "Content-Encoding"}
+
+// fixture describes a recorded upstream response.
+//
+// The response body is stored separately, in a file next to the fixture
+// with the .body extension, so that it can be inspected and edited as is.
+type fixture struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+}
+
+// fixtureTransport is an http.RoundTripper that records upstream responses
+// to a fixture directory, or replays them without making network requests.
+//
+// Fixtures are matched by request method and URL. Request headers and bodies,
+// including the token used, aren't taken into account.
+type fixtureTransport struct {
+	dir    string            // Directory where fixtures are stored
+	replay bool              // Whether to serve fixtures instead of recording them
+	next   http.RoundTripper // Transport used to make requests when recording
+}
+
+// SetupFixtures swaps the transport of utils.HTTPClient for one that records
+// or replays upstream responses, according to Development.UpstreamMode in GlobalConfig.
+//
+// It does nothing if Development.UpstreamMode is unset.
+func SetupFixtures() error {
+	mode := config.GlobalConfig.Development.UpstreamMode
+	if mode == config.NetworkUpstreamMode {
+		return nil
+	}
+
+	dir := config.GlobalConfig.Development.FixturePath
+
+	if mode == config.RecordUpstreamMode {
+		if err := os.MkdirAll(dir, fixtureDirPermissions); err != nil {
+			return fmt.Errorf("failed to create fixture directory %s: %w", dir, err)
+		}
+	}
+
+	utils.HTTPClient.Transport = &fixtureTransport{
+		dir:    dir,
+		replay: mode == config.ReplayUpstreamMode,
+		next:   utils.HTTPClient.Transport,
+	}
+
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := fixtureKey(req.Method, req.URL.String())
+
+	if t.replay {
+		return t.load(req, key)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(t.dir, atomicfile.TempPattern)
+	if err != nil {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		transport:  t,
+		tmp:        tmp,
+		fixture:    newFixture(req, resp.StatusCode, resp.Header),
+		key:        key,
+	}
+
+	return resp, nil
+}
+
+// recordingBody is a response body that is copied to a temporary file as it's
+// read, so that large bodies such as images aren't held in memory.
+//
+// The fixture is only saved once the body has been read in full. Bodies that
+// are closed early are discarded.
+type recordingBody struct {
+	io.ReadCloser
+
+	transport *fixtureTransport
+	tmp       *os.File // Temporary file for the body, nil once it's saved or discarded
+	fixture   fixture
+	key       string
+}
+
+// Read implements io.Reader.
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if b.tmp != nil && n > 0 {
+		if _, writeErr := b.tmp.Write(p[:n]); writeErr != nil {
+			audit.GlobalAuditor.Logger.Errorf("Failed to record fixture %s: %v", b.key, writeErr)
+			b.discard()
+		}
+	}
+
+	if b.tmp != nil && errors.Is(err, io.EOF) {
+		if saveErr := b.save(); saveErr != nil {
+			audit.GlobalAuditor.Logger.Errorf("Failed to record fixture %s: %v", b.key, saveErr)
+		}
+	}
+
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *recordingBody) Close() error {
+	b.discard()
+
+	return b.ReadCloser.Close()
+}
+
+// save moves the body into place and writes the fixture.
+//
+// The body is moved before the fixture is written, so that a fixture
+// is only ever found once its body is complete.
+func (b *recordingBody) save() error {
+	tmpName := b.tmp.Name()
+
+	err := b.tmp.Chmod(fixtureFilePermissions)
+	if closeErr := b.tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	b.tmp = nil
+
+	if err == nil {
+		err = os.Rename(tmpName, filepath.Join(b.transport.dir, b.key+".body"))
+	}
+
+	if err != nil {
+		os.Remove(tmpName)
+
+		return fmt.Errorf("failed to save fixture body: %w", err)
+	}
+
+	return b.transport.saveFixture(b.key, b.fixture)
+}
+
+// discard removes the temporary file of a body that wasn't read in full.
+func (b *recordingBody) discard() {
+	if b.tmp == nil {
+		return
+	}
+
+	b.tmp.Close()
+	os.Remove(b.tmp.Name())
+
+	b.tmp = nil
+}
+
+// load builds a response for req from the fixture stored under key.
+func (t *fixtureTransport) load(req *http.Request, key string) (*http.Response, error) {
+	data, err := os.ReadFile(filepath.Join(t.dir, key+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrFixtureNotFound, req.Method, req.URL)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", key, err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(t.dir, key+".body"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture body: %w", err)
+	}
+
+	header := f.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// newFixture returns the fixture describing a response to req.
+func newFixture(req *http.Request, statusCode int, header http.Header) fixture {
+	f := fixture{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: statusCode,
+		Header:     make(http.Header),
+	}
+
+	for _, name := range recordedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			f.Header[name] = values
+		}
+	}
+
+	return f
+}
+
+// saveFixture stores f under key. Its body must already be in place.
+func (t *fixtureTransport) saveFixture(key string, f fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+
+	return atomicfile.WriteFile(t.dir, key+".json", data, fixtureFilePermissions)
+}
+
+// fixtureKey returns the filename, without extension, of the fixture for a request.
+func fixtureKey(method, url string) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+
+	return hex.EncodeToString(sum[:])
+}
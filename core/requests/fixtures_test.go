@@ -0,0 +1,90 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/core/atomicfile"
+)
+
+// TestFixtureTransport verifies that responses recorded by fixtureTransport
+// are replayed without making network requests.
+func TestFixtureTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "secret"})
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":true}`))
+	}))
+
+	dir := t.TempDir()
+	url := server.URL + "/ajax/illust/1?lang=en"
+
+	record := &fixtureTransport{dir: dir, next: http.DefaultTransport}
+	client := &http.Client{Transport: record}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error while recording: %v", err)
+	}
+
+	// The body is recorded as it's read.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// Bodies that aren't read in full aren't recorded.
+	resp, err = client.Get(server.URL + "/ajax/illust/2")
+	if err != nil {
+		t.Fatalf("unexpected error while recording: %v", err)
+	}
+
+	resp.Body.Close()
+
+	// Replaying must not depend on the upstream server.
+	server.Close()
+
+	replay := &fixtureTransport{dir: dir, replay: true}
+	client = &http.Client{Transport: replay}
+
+	resp, err = client.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error while replaying: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read replayed body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	if string(body) != `{"error":true}` {
+		t.Errorf("unexpected replayed body %q", body)
+	}
+
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", got)
+	}
+
+	if got := resp.Header.Get("Set-Cookie"); got != "" {
+		t.Errorf("expected Set-Cookie not to be recorded, got %q", got)
+	}
+
+	if _, err := client.Get(server.URL + "/ajax/illust/2"); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("expected ErrFixtureNotFound for an unrecorded request, got %v", err)
+	}
+
+	if tmp, _ := filepath.Glob(filepath.Join(dir, atomicfile.TempPattern)); len(tmp) != 0 {
+		t.Errorf("expected temporary files to be removed, got %v", tmp)
+	}
+}
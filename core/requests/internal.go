@@ -12,7 +12,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"maps"
 	"math/rand"
 	"mime/multipart"
 	"net/http"
@@ -25,12 +24,108 @@ import (
 	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
 	"codeberg.org/pixivfe/pixivfe/server/tokenmanager"
 	"codeberg.org/pixivfe/pixivfe/server/utils"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
-var ErrUnsupportedPayloadType = errors.New("unsupported payload type")
+// upstreamRequestTimeout bounds upstream requests that are detached from the
+// downstream request's context, such as coalesced and background requests.
+const upstreamRequestTimeout = 30 * time.Second
+
+var (
+	ErrUnsupportedPayloadType = errors.New("unsupported payload type")
+
+	// inflightRequests coalesces concurrent identical GET requests.
+	inflightRequests singleflight.Group
+)
 
 // handleRequest handles HTTP requests using the provided RequestOptions.
+//
+// GET requests are served from the cache when possible. Stale cached responses
+// are served immediately while a refresh runs in the background, and cache misses
+// for the same URL and user token share a single upstream request.
 func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPResponse, error) {
+	if opts.Method != http.MethodGet {
+		return performRequest(ctx, opts, CachePolicy{})
+	}
+
+	userToken := opts.Cookies["PHPSESSID"]
+
+	policy := determineCachePolicy(opts.URL, userToken, opts.IncomingHeaders)
+	if policy.ShouldUseCache && policy.CachedResponse != nil {
+		cacheStats.recordHit(opts.URL)
+
+		if policy.Stale {
+			revalidateInBackground(ctx, opts, policy)
+		}
+
+		return policy.CachedResponse, nil
+	}
+
+	if policy.ShouldUseCache {
+		cacheStats.recordMiss(opts.URL)
+	}
+
+	// Requests using a random token are expected to return different results each time.
+	if userToken == RandomToken {
+		return performRequest(ctx, opts, policy)
+	}
+
+	return performCoalescedRequest(ctx, opts, policy)
+}
+
+// performCoalescedRequest performs a GET request, sharing a single upstream request
+// between concurrent callers for the same URL and user token.
+//
+// The shared request is detached from the caller's cancellation, so that a caller
+// going away doesn't fail the request for everyone else waiting on it.
+func performCoalescedRequest(ctx context.Context, opts RequestOptions, policy CachePolicy) (*SimpleHTTPResponse, error) {
+	key := generateCacheKey(opts.URL, opts.Cookies["PHPSESSID"])
+
+	result := inflightRequests.DoChan(key, func() (any, error) {
+		detachedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), upstreamRequestTimeout)
+		defer cancel()
+
+		return performRequest(detachedCtx, opts, policy)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("context canceled: %w", ctx.Err())
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		resp, ok := res.Val.(*SimpleHTTPResponse)
+		if !ok {
+			return nil, ErrIncompatibleRespBody
+		}
+
+		return resp, nil
+	}
+}
+
+// revalidateInBackground refreshes a stale cached response without blocking the caller.
+func revalidateInBackground(ctx context.Context, opts RequestOptions, policy CachePolicy) {
+	detachedCtx := context.WithoutCancel(ctx)
+
+	go func() {
+		if _, err := performCoalescedRequest(detachedCtx, opts, policy); err != nil {
+			audit.GlobalAuditor.Logger.Warnw("Failed to revalidate stale cache entry",
+				"url", opts.URL,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// performRequest sends a request upstream, updates the token status
+// and stores successful GET responses in the cache as allowed by policy,
+// the cache policy that handleRequest determined for the request.
+func performRequest(ctx context.Context, opts RequestOptions, policy CachePolicy) (*SimpleHTTPResponse, error) {
 	var (
 		req               *http.Request
 		reqBody           io.Reader
@@ -41,7 +136,7 @@ func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPRespons
 
 	userToken := opts.Cookies["PHPSESSID"]
 
-	token, err := retrieveToken(tokenManager, userToken)
+	token, err := retrieveToken(ctx, tokenManager, userToken)
 
 	// Determine special cases for token
 	if userToken == RandomToken {
@@ -50,14 +145,6 @@ func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPRespons
 		return nil, err
 	}
 
-	// For GET requests, determine if caching should be used
-	if opts.Method == http.MethodGet {
-		policy := determineCachePolicy(opts.URL, userToken, opts.IncomingHeaders)
-		if policy.ShouldUseCache && policy.CachedResponse != nil {
-			return policy.CachedResponse, nil
-		}
-	}
-
 	if opts.Method == http.MethodPost {
 		switch v := opts.Payload.(type) {
 		case string:
@@ -78,7 +165,7 @@ func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPRespons
 
 	req, err = http.NewRequestWithContext(ctx, opts.Method, opts.URL, reqBody)
 	if err != nil {
-		return nil, i18n.Errorf("failed to create request: %w", err)
+		return nil, i18n.ErrorfContext(ctx, "failed to create request: %w", err)
 	}
 
 	req.Header.Add("User-Agent", config.GetRandomUserAgent())
@@ -107,7 +194,7 @@ func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPRespons
 		}
 	}
 
-	resp, err := makeRequest(ctx, req, opts.URL)
+	resp, err := makeRequest(ctx, req, opts.URL, 0)
 	if err != nil {
 		// If making the request itself failed, don't mark the token provided by tokenManager as timed out
 		return nil, err
@@ -119,20 +206,20 @@ func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPRespons
 		tokenManager.MarkTokenStatus(token, tokenmanager.Good)
 
 		if opts.Method == http.MethodGet {
-			// manageCaching will either return a cached response (if available)
-			// or the new response after optionally storing it.
-			return manageCaching(opts.URL, userToken, opts.IncomingHeaders, resp), nil
+			return manageCaching(opts.URL, userToken, policy, resp), nil
 		}
 
 		return resp, nil
 	}
 
 	// Handle non-OK status codes
-	err = i18n.Errorf("HTTP status code: %d", resp.StatusCode)
+	err = newStatusError(ctx, resp.StatusCode)
 
-	// Mark the token provided by tokenManager as timed out if the
-	// request succeeded, but returned a non-OK response
-	tokenManager.MarkTokenStatus(token, tokenmanager.TimedOut)
+	// Update the status of the token provided by tokenManager if the
+	// non-OK response was caused by it
+	if status, ok := tokenStatusFor(resp.StatusCode); ok {
+		tokenManager.MarkTokenStatus(token, status)
+	}
 
 	select {
 	case <-ctx.Done():
@@ -143,35 +230,44 @@ func handleRequest(ctx context.Context, opts RequestOptions) (*SimpleHTTPRespons
 }
 
 // makeRequest executes the HTTP request and processes the response.
+//
+// If maxBytes is positive, responses with a larger body fail with ErrContentTooLarge.
 func makeRequest(
 	ctx context.Context,
 	req *http.Request,
 	url string,
+	maxBytes int64,
 ) (*SimpleHTTPResponse, error) {
 	start := time.Now()
 
-	resp, err := utils.HTTPClient.Do(req)
+	ctx, tracingSpan := startUpstreamSpan(ctx, req)
+	defer tracingSpan.End()
+
+	resp, err := utils.HTTPClient.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, i18n.Errorf("failed to make HTTP request: %w", err)
+		endUpstreamSpan(tracingSpan, 0, err)
+		recordUpstreamSpan(ctx, req, url, start, 0, err, nil)
+
+		return nil, i18n.ErrorfContext(ctx, "failed to make HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, i18n.Errorf("failed to read response body: %w", err)
+	var reader io.Reader = resp.Body
+	if maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, maxBytes+1)
 	}
 
-	span := audit.Span{
-		Component:  audit.ComponentUpstream,
-		Duration:   time.Since(start),
-		RequestID:  requestcontext.FromContext(ctx).RequestID,
-		Method:     req.Method,
-		URL:        url,
-		StatusCode: resp.StatusCode,
-		Error:      err,
-		Body:       body,
+	body, err := io.ReadAll(reader)
+	if err == nil && maxBytes > 0 && int64(len(body)) > maxBytes {
+		err = fmt.Errorf("%w: more than %d bytes", ErrContentTooLarge, maxBytes)
+	}
+
+	endUpstreamSpan(tracingSpan, resp.StatusCode, err)
+	recordUpstreamSpan(ctx, req, url, start, resp.StatusCode, err, body)
+
+	if err != nil {
+		return nil, i18n.ErrorfContext(ctx, "failed to read response body: %w", err)
 	}
-	audit.GlobalAuditor.LogAndRecord(span)
 
 	return &SimpleHTTPResponse{
 		StatusCode: resp.StatusCode,
@@ -180,21 +276,32 @@ func makeRequest(
 }
 
 // retrieveToken obtains a valid token for the request.
-func retrieveToken(tokenManager *tokenmanager.TokenManager, userToken string) (*tokenmanager.Token, error) {
+func retrieveToken(ctx context.Context, tokenManager *tokenmanager.TokenManager, userToken string) (*tokenmanager.Token, error) {
 	if userToken != "" {
 		return &tokenmanager.Token{Value: userToken}, nil
 	}
 
 	token := tokenManager.GetToken()
 	if token == nil {
-		tokenManager.ResetAllTokens()
+		if tokenManager.Len() == 0 {
+			return nil, i18n.ErrorfContext(ctx,
+				`No tokens are configured.
+Please provide at least one token in PIXIVFE_TOKEN.
+Please refer the following documentation for additional information:
+- https://pixivfe-docs.pages.dev/hosting/api-authentication/`,
+			)
+		}
+
+		// GetToken falls back to timed out tokens, so it only returns nil
+		// once every token has been rejected as invalid.
+		_, _, invalid := tokenManager.StatusCounts()
 
-		return nil, i18n.Errorf(
-			`All tokens (%d) are timed out, resetting all tokens to their initial good state.
-Consider providing additional tokens in PIXIVFE_TOKEN or reviewing token management configuration.
+		return nil, i18n.ErrorfContext(ctx,
+			`All tokens (%d) were rejected by pixiv as invalid or expired.
+Please replace the tokens provided in PIXIVFE_TOKEN.
 Please refer the following documentation for additional information:
 - https://pixivfe-docs.pages.dev/hosting/api-authentication/`,
-			len(config.GlobalConfig.Basic.Token),
+			invalid,
 		)
 	}
 
@@ -243,41 +350,127 @@ func createMultipartFormData(fields map[string]string) (*bytes.Buffer, string, e
 	return body, writer.FormDataContentType(), nil
 }
 
+// proxiedResponseHeaders lists the upstream response headers that proxyRequest
+// passes on to the client.
+//
+// Other headers, such as Set-Cookie and hop-by-hop headers, are dropped.
+var proxiedResponseHeaders = []string{
+	"Accept-Ranges",
+	"Cache-Control",
+	"Content-Encoding",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+}
+
+// proxyRequest makes r upstream and writes the response to w, including its
+// status code, such as 304 Not Modified or 206 Partial Content.
 func proxyRequest(w http.ResponseWriter, r *http.Request) error {
 	start := time.Now()
 
-	resp, err := utils.HTTPClient.Do(r)
+	ctx, tracingSpan := startUpstreamSpan(r.Context(), r)
+	defer tracingSpan.End()
+
+	resp, err := utils.HTTPClient.Do(r.WithContext(ctx))
 	if err != nil {
-		return i18n.Errorf("failed to proxy request: %w", err)
+		endUpstreamSpan(tracingSpan, 0, err)
+		recordUpstreamSpan(r.Context(), r, r.URL.String(), start, 0, err, nil)
+
+		return i18n.ErrorfContext(r.Context(), "failed to proxy request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	maps.Copy(w.Header(), resp.Header)
+	// Upstream headers replace those set by middleware, such as Cache-Control
+	for _, key := range proxiedResponseHeaders {
+		if values := resp.Header.Values(key); len(values) > 0 {
+			w.Header()[http.CanonicalHeaderKey(key)] = values
+		}
+	}
 
 	w.WriteHeader(resp.StatusCode)
 
-	_, err = io.Copy(w, resp.Body)
+	if r.Method != http.MethodHead {
+		_, err = io.Copy(w, resp.Body)
+	}
+	endUpstreamSpan(tracingSpan, resp.StatusCode, err)
+	recordUpstreamSpan(r.Context(), r, r.URL.String(), start, resp.StatusCode, err, nil)
+
 	if err != nil {
-		return i18n.Errorf("failed to copy response body: %w", err)
-	}
-
-	if !audit.ShouldSkipUpstreamLogging(r.URL.Hostname()) {
-		span := audit.Span{
-			Component:  audit.ComponentUpstream,
-			Duration:   time.Since(start),
-			RequestID:  requestcontext.FromContext(r.Context()).RequestID,
-			Method:     r.Method,
-			URL:        r.URL.String(),
-			StatusCode: resp.StatusCode,
-			Error:      err,
-			Body:       make([]byte, 0),
-		}
-		audit.GlobalAuditor.LogAndRecord(span)
+		return i18n.ErrorfContext(r.Context(), "failed to copy response body: %w", err)
 	}
 
 	return nil
 }
 
+// recordUpstreamSpan logs an upstream request that started at start and records it
+// in the metrics.
+//
+// Requests to hosts that skip upstream logging, such as the image hosts, are only
+// recorded in the metrics. A statusCode of 0 means that no response was received.
+func recordUpstreamSpan(
+	ctx context.Context,
+	req *http.Request,
+	url string,
+	start time.Time,
+	statusCode int,
+	err error,
+	body []byte,
+) {
+	span := audit.Span{
+		Component:  audit.ComponentUpstream,
+		Duration:   time.Since(start),
+		RequestID:  requestcontext.FromContext(ctx).RequestID,
+		Method:     req.Method,
+		URL:        url,
+		StatusCode: statusCode,
+		Error:      err,
+		Body:       body,
+	}
+
+	if audit.ShouldSkipUpstreamLogging(req.URL.Hostname()) {
+		audit.RecordSpanMetrics(span)
+
+		return
+	}
+
+	audit.GlobalAuditor.LogAndRecord(span)
+}
+
+// startUpstreamSpan starts a tracing span for an upstream request,
+// as a child of the span carried by ctx.
+//
+// Trace context is deliberately not propagated to upstream servers.
+func startUpstreamSpan(ctx context.Context, req *http.Request) (context.Context, trace.Span) {
+	return audit.Tracer().Start(ctx, req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+}
+
+// endUpstreamSpan records the outcome of an upstream request on span.
+//
+// A statusCode of 0 indicates that no response was received.
+func endUpstreamSpan(span trace.Span, statusCode int, err error) {
+	if statusCode != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	}
+
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case statusCode >= http.StatusBadRequest:
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+}
+
 // isContextError checks if an error is due to context cancellation or deadline exceeded.
 func isContextError(err error) bool {
 	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
@@ -0,0 +1,437 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package requests
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/server/tokenmanager"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTestUpstream starts a server that counts requests and responds with body once release is closed,
+// and configures GlobalConfig with a token manager. GlobalConfig and the cache are restored on cleanup.
+func setupTestUpstream(t *testing.T, body string, release <-chan struct{}) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		<-release
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	originalConfig := config.GlobalConfig
+	originalCache := cache
+
+	t.Cleanup(func() {
+		config.GlobalConfig = originalConfig
+		cache = originalCache
+	})
+
+	config.GlobalConfig.TokenManager.TokenManager = tokenmanager.NewTokenManager(
+		[]string{"token"}, 5, time.Second, time.Minute, "round-robin")
+
+	return server, &hits
+}
+
+// TestHandleRequest_Coalescing verifies that concurrent identical GET requests share one upstream request.
+func TestHandleRequest_Coalescing(t *testing.T) {
+	release := make(chan struct{})
+	server, hits := setupTestUpstream(t, "ok", release)
+
+	config.GlobalConfig.Cache.Enabled = false
+
+	const callers = 10
+
+	var wg sync.WaitGroup
+
+	results := make(chan string, callers)
+
+	for range callers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := PerformGET(context.Background(), server.URL+"/ajax/illust/1", nil, http.Header{})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+
+				return
+			}
+
+			results <- string(resp.Body)
+		}()
+	}
+
+	// Give every caller a chance to join the in-flight request before it completes.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for body := range results {
+		if body != "ok" {
+			t.Errorf("expected body 'ok', got %q", body)
+		}
+	}
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("expected 1 upstream request, got %d", got)
+	}
+}
+
+// TestHandleRequest_StaleWhileRevalidate verifies that an expired entry within the grace period
+// is served immediately and refreshed in the background.
+func TestHandleRequest_StaleWhileRevalidate(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+
+	server, hits := setupTestUpstream(t, "fresh", release)
+
+	config.GlobalConfig.Cache.Enabled = true
+	config.GlobalConfig.Cache.TTL = time.Hour
+	config.GlobalConfig.Cache.StaleWhileRevalidate = time.Minute
+
+	lru, err := NewLRUCache(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache = lru
+
+	url := server.URL + "/ajax/illust/1"
+	key := generateCacheKey(url, "")
+
+	cache.Add(key, CachedItem{
+		Response:  &SimpleHTTPResponse{StatusCode: http.StatusOK, Body: []byte("stale")},
+		ExpiresAt: time.Now().Add(-time.Second),
+		URL:       url,
+	})
+
+	resp, err := PerformGET(context.Background(), url, nil, http.Header{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(resp.Body) != "stale" {
+		t.Errorf("expected stale response to be served, got %q", resp.Body)
+	}
+
+	// Wait for the background refresh to store the fresh response.
+	deadline := time.Now().Add(2 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if item, ok := cache.Peek(key); ok && string(item.(CachedItem).Response.Body) == "fresh" {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err = PerformGET(context.Background(), url, nil, http.Header{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(resp.Body) != "fresh" {
+		t.Errorf("expected refreshed response, got %q", resp.Body)
+	}
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("expected 1 upstream request, got %d", got)
+	}
+}
+
+// countingCache is a Cache that counts calls to Get.
+type countingCache struct {
+	Cache
+
+	gets atomic.Int32
+}
+
+func (c *countingCache) Get(key string) (any, bool) {
+	c.gets.Add(1)
+
+	return c.Cache.Get(key)
+}
+
+// TestHandleRequest_ReadsCacheOnce verifies that a cache miss reads the cache only once,
+// reusing the cache policy when storing the fresh response.
+func TestHandleRequest_ReadsCacheOnce(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+
+	server, _ := setupTestUpstream(t, "fresh", release)
+
+	config.GlobalConfig.Cache.Enabled = true
+	config.GlobalConfig.Cache.TTL = time.Hour
+
+	lru, err := NewLRUCache(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	counting := &countingCache{Cache: lru}
+	cache = counting
+
+	url := server.URL + "/ajax/illust/1"
+
+	if _, err := PerformGET(context.Background(), url, nil, http.Header{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := counting.gets.Load(); got != 1 {
+		t.Errorf("expected 1 cache read, got %d", got)
+	}
+
+	if _, ok := lru.Peek(generateCacheKey(url, "")); !ok {
+		t.Error("expected the fresh response to be cached")
+	}
+}
+
+// TestPerformGET_Tracing verifies that upstream requests create a child span of the
+// span carried by the context, without propagating trace context upstream.
+func TestPerformGET_Tracing(t *testing.T) {
+	// Only the configuration set up by setupTestUpstream is needed here.
+	release := make(chan struct{})
+	close(release)
+	setupTestUpstream(t, "ok", release)
+
+	config.GlobalConfig.Cache.Enabled = false
+
+	var traceparent atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("Traceparent"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	originalProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(originalProvider)
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	if _, err := PerformGET(ctx, server.URL+"/ajax/illust/1", nil, http.Header{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parent.End()
+
+	if got := traceparent.Load(); got != "" {
+		t.Errorf("expected no traceparent header upstream, got %q", got)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	upstream := spans[0]
+
+	if upstream.SpanKind() != trace.SpanKindClient {
+		t.Errorf("expected client span, got %v", upstream.SpanKind())
+	}
+
+	if upstream.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected upstream span to be a child of the parent span")
+	}
+}
+
+// TestPerformGET_StatusCodes verifies that non-OK responses return a StatusError
+// and update the status of the token only when the token caused the failure.
+func TestPerformGET_StatusCodes(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	setupTestUpstream(t, "ok", release)
+
+	config.GlobalConfig.Cache.Enabled = false
+
+	tests := []struct {
+		statusCode int
+		sentinel   error
+		wantStatus tokenmanager.TokenStatus
+	}{
+		{http.StatusNotFound, ErrNotFound, tokenmanager.Good},
+		{http.StatusForbidden, ErrForbidden, tokenmanager.Good},
+		{http.StatusUnauthorized, ErrUnauthorized, tokenmanager.Invalid},
+		{http.StatusTooManyRequests, ErrRateLimited, tokenmanager.TimedOut},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			t.Cleanup(server.Close)
+
+			tokenManager := tokenmanager.NewTokenManager(
+				[]string{"token"}, 5, time.Second, time.Minute, "round-robin")
+			config.GlobalConfig.TokenManager.TokenManager = tokenManager
+
+			_, err := PerformGET(context.Background(), server.URL+"/ajax/illust/1", nil, http.Header{})
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("expected error matching %v, got %v", tt.sentinel, err)
+			}
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.statusCode {
+				t.Errorf("expected StatusError with status code %d, got %v", tt.statusCode, err)
+			}
+
+			if got := tokenManager.Snapshot()[0].Status; got != tt.wantStatus {
+				t.Errorf("expected token status %v, got %v", tt.wantStatus, got)
+			}
+		})
+	}
+}
+
+// TestMakeRequest_MaxBytes verifies that response bodies larger than maxBytes are rejected.
+func TestMakeRequest_MaxBytes(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	server, _ := setupTestUpstream(t, "0123456789", release)
+
+	for _, tt := range []struct {
+		maxBytes int64
+		wantErr  bool
+	}{
+		{0, false},
+		{10, false},
+		{9, true},
+	} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		resp, err := makeRequest(context.Background(), req, server.URL, tt.maxBytes)
+
+		switch {
+		case tt.wantErr && !errors.Is(err, ErrContentTooLarge):
+			t.Errorf("maxBytes %d: expected ErrContentTooLarge, got %v", tt.maxBytes, err)
+		case !tt.wantErr && (err != nil || string(resp.Body) != "0123456789"):
+			t.Errorf("maxBytes %d: expected the whole body, got %v (error %v)", tt.maxBytes, resp, err)
+		}
+	}
+}
+
+// TestRetrieveToken_NoTokens verifies that an empty token pool is reported as unconfigured.
+func TestRetrieveToken_NoTokens(t *testing.T) {
+	tokenManager := tokenmanager.NewTokenManager(nil, 5, time.Second, time.Minute, "round-robin")
+
+	_, err := retrieveToken(context.Background(), tokenManager, "")
+	if err == nil || !strings.Contains(err.Error(), "No tokens are configured") {
+		t.Errorf("expected a no tokens configured error, got %v", err)
+	}
+
+	token, err := retrieveToken(context.Background(), tokenManager, "user")
+	if err != nil || token.Value != "user" {
+		t.Errorf("expected the user token, got %v (error %v)", token, err)
+	}
+}
+
+// TestProxyHandler verifies that validators, ranges and HEAD requests are forwarded
+// upstream, and that only allowlisted response headers are passed on.
+func TestProxyHandler(t *testing.T) {
+	const content = "0123456789"
+
+	var methods []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		wantStatus int
+		wantBody   string
+	}{
+		{"full", http.MethodGet, nil, http.StatusOK, content},
+		{"not modified", http.MethodGet, http.Header{"If-None-Match": {`"v1"`}}, http.StatusNotModified, ""},
+		{"modified", http.MethodGet, http.Header{"If-None-Match": {`"v0"`}}, http.StatusOK, content},
+		{"range", http.MethodGet, http.Header{"Range": {"bytes=2-4"}}, http.StatusPartialContent, "234"},
+		{"head", http.MethodHead, nil, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods = nil
+
+			r := httptest.NewRequest(tt.method, "/proxy/example/file.mp4", nil)
+			r.URL.Path = "file.mp4"
+			maps.Copy(r.Header, tt.header)
+
+			w := httptest.NewRecorder()
+
+			if err := ProxyHandler(w, r, server.URL, nil); err != nil {
+				t.Fatalf("ProxyHandler() error = %v", err)
+			}
+
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+
+			if len(methods) != 1 || methods[0] != tt.method {
+				t.Errorf("upstream requests = %v, want one %s request", methods, tt.method)
+			}
+
+			if got := w.Header().Get("ETag"); got != `"v1"` {
+				t.Errorf("ETag = %q, want %q", got, `"v1"`)
+			}
+
+			for _, key := range []string{"Set-Cookie", "Keep-Alive"} {
+				if got := w.Header().Get(key); got != "" {
+					t.Errorf("%s = %q, want it stripped", key, got)
+				}
+			}
+		})
+	}
+
+	// Content-Length of a HEAD response describes the body that GET would return
+	r := httptest.NewRequest(http.MethodHead, "/proxy/example/file.mp4", nil)
+	r.URL.Path = "file.mp4"
+
+	w := httptest.NewRecorder()
+	if err := ProxyHandler(w, r, server.URL, nil); err != nil {
+		t.Fatalf("ProxyHandler() error = %v", err)
+	}
+
+	if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(content)) {
+		t.Errorf("HEAD Content-Length = %q, want %d", got, len(content))
+	}
+}
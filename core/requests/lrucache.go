@@ -4,6 +4,9 @@
 /*
 Implementation of a thread-safe fixed size LRU cache.
 
+The cache can be bounded by the number of entries, by the total size of
+the response bodies it holds, or both.
+
 Generics aren't used as we only need string keys.
 */
 package requests
@@ -19,16 +22,21 @@ var ErrInvalidSize = errors.New("must provide a positive size")
 // LRUCache implements a thread-safe fixed-size LRU cache using a combination of a doubly-linked list
 // (to track the usage order) and a map (for O(1) lookups of items).
 type LRUCache struct {
-	size      int                      // Maximum capacity of the cache
-	evictList *list.List               // A doubly-linked list to manage the eviction order
-	items     map[string]*list.Element // Maps string keys to their corresponding linked-list elements
-	lock      sync.RWMutex             // For thread-safe operations
+	size      int                         // Maximum number of entries, or 0 for no limit
+	maxBytes  int64                       // Maximum total size of all entries in bytes, or 0 for no limit
+	bytes     int64                       // Current total size of all entries in bytes
+	evictList *list.List                  // A doubly-linked list to manage the eviction order
+	items     map[string]*list.Element    // Maps string keys to their corresponding linked-list elements
+	onEvict   func(key string, value any) // Optional callback invoked when an item is evicted due to capacity
+	lock      sync.RWMutex                // For thread-safe operations
 }
 
-// cacheEntry holds the key/value pair stored in each linked-list element.
+// cacheEntry holds the key/value pair stored in each linked-list element,
+// along with the size accounted for it.
 type cacheEntry struct {
 	key   string
 	value any
+	size  int64
 }
 
 // NewLRUCache creates a new LRU cache with the specified maximum size.
@@ -39,42 +47,94 @@ func NewLRUCache(size int) (*LRUCache, error) {
 		return nil, ErrInvalidSize
 	}
 
+	return newLRUCacheWithEvict(size, 0, nil)
+}
+
+// NewByteLimitedLRUCache creates a new LRU cache that holds at most maxBytes bytes,
+// regardless of the number of entries.
+//
+// The size of an entry is the length of its response body (see entrySize).
+// It returns an error if maxBytes is not a positive integer.
+func NewByteLimitedLRUCache(maxBytes int64) (*LRUCache, error) {
+	if maxBytes <= 0 {
+		return nil, ErrInvalidSize
+	}
+
+	return newLRUCacheWithEvict(0, maxBytes, nil)
+}
+
+// newLRUCacheWithEvict creates a new LRU cache bounded by size entries and maxBytes bytes,
+// where a limit of 0 is ignored, that calls onEvict whenever an item is evicted to make room
+// for a new one.
+//
+// onEvict is called while the cache lock is held, so it must not call back into the cache.
+func newLRUCacheWithEvict(size int, maxBytes int64, onEvict func(key string, value any)) (*LRUCache, error) {
+	if size < 0 || maxBytes < 0 || (size == 0 && maxBytes == 0) {
+		return nil, ErrInvalidSize
+	}
+
 	return &LRUCache{
 		size:      size,
+		maxBytes:  maxBytes,
 		evictList: list.New(),
 		items:     make(map[string]*list.Element),
+		onEvict:   onEvict,
 	}, nil
 }
 
 // Add adds or updates a value to the cache.
 //
 // If the key already exists, its value is updated and the item is moved to the front (most recently used).
-// If the key is new and the cache is at capacity, eviction of the oldest item occurs.
+// If the cache is then over capacity, the oldest items are evicted until it no longer is.
 // The boolean return indicates whether an eviction actually happened.
+//
+// In byte-limited mode, a value larger than the whole budget is evicted immediately
+// instead of flushing every other entry, along with any previous value for the key.
 func (c *LRUCache) Add(key string, value any) bool {
+	return c.addWithSize(key, value, entrySize(value))
+}
+
+// addWithSize is like Add, but accounts size bytes for the value instead of calling entrySize.
+func (c *LRUCache) addWithSize(key string, value any, size int64) bool {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	// If the item already exists, move it to the front as "most recently used" and update its value.
+	if c.maxBytes > 0 && size > c.maxBytes {
+		if ent, ok := c.items[key]; ok {
+			c.removeElement(ent)
+		}
+
+		if c.onEvict != nil {
+			c.onEvict(key, value)
+		}
+
+		return true
+	}
+
 	if ent, ok := c.items[key]; ok {
+		// If the item already exists, move it to the front as "most recently used" and update its value.
 		c.evictList.MoveToFront(ent)
 
 		if cacheEnt, ok := ent.Value.(*cacheEntry); ok {
+			c.bytes += size - cacheEnt.size
 			cacheEnt.value = value
+			cacheEnt.size = size
 		}
-
-		return false
+	} else {
+		// Otherwise, create a new entry and place it at the front.
+		ent := &cacheEntry{key, value, size}
+		entry := c.evictList.PushFront(ent)
+		c.items[key] = entry
+		c.bytes += size
 	}
 
-	// Otherwise, create a new entry and place it at the front.
-	ent := &cacheEntry{key, value}
-	entry := c.evictList.PushFront(ent)
-	c.items[key] = entry
+	// While we've exceeded our capacity, remove the oldest item from the back of the list.
+	evicted := false
 
-	// If we've exceeded our capacity, remove the oldest item from the back of the list.
-	evicted := c.evictList.Len() > c.size
-	if evicted {
+	for c.overCapacity() {
 		c.removeOldest()
+
+		evicted = true
 	}
 
 	return evicted
@@ -157,11 +217,33 @@ func (c *LRUCache) Len() int {
 	return c.evictList.Len()
 }
 
+// Bytes returns the current total size of all items in the cache.
+func (c *LRUCache) Bytes() int64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.bytes
+}
+
+// overCapacity reports whether the cache holds more entries or bytes than allowed.
+func (c *LRUCache) overCapacity() bool {
+	return (c.size > 0 && c.evictList.Len() > c.size) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
 // removeOldest removes the oldest item from both the linked list and the map.
 func (c *LRUCache) removeOldest() {
 	ent := c.evictList.Back()
-	if ent != nil {
-		c.removeElement(ent)
+	if ent == nil {
+		return
+	}
+
+	c.removeElement(ent)
+
+	if c.onEvict != nil {
+		if kv, ok := ent.Value.(*cacheEntry); ok {
+			c.onEvict(kv.key, kv.value)
+		}
 	}
 }
 
@@ -174,5 +256,29 @@ func (c *LRUCache) removeElement(e *list.Element) {
 
 	if kv, ok := e.Value.(*cacheEntry); ok {
 		delete(c.items, kv.key)
+		c.bytes -= kv.size
 	}
 }
+
+// entrySize returns the number of bytes accounted for value in byte-limited mode.
+//
+// Only response bodies are counted, as they make up nearly all of the memory used by a cached response.
+// Values of other types are counted as zero bytes.
+func entrySize(value any) int64 {
+	switch v := value.(type) {
+	case CachedItem:
+		if v.Response != nil {
+			return int64(len(v.Response.Body))
+		}
+	case *SimpleHTTPResponse:
+		if v != nil {
+			return int64(len(v.Body))
+		}
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	}
+
+	return 0
+}
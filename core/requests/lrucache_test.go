@@ -45,6 +45,20 @@ func TestNewLRUCache(t *testing.T) {
 			t.Error("expected no cache to be returned on error")
 		}
 	})
+
+	t.Run("InvalidMaxBytes", func(t *testing.T) {
+		t.Parallel()
+
+		// Create a byte-limited LRU cache with a budget of 0 bytes, which should fail.
+		cache, err := NewByteLimitedLRUCache(0)
+		if err == nil {
+			t.Fatal("expected error when creating cache with 0 max bytes, got nil")
+		}
+
+		if cache != nil {
+			t.Error("expected no cache to be returned on error")
+		}
+	})
 }
 
 // TestLRUCache_AddAndGet verifies that adding a key to the cache and retrieving it works correctly,
@@ -256,3 +270,124 @@ func TestLRUCache_Len(t *testing.T) {
 		t.Errorf("expected cache length to remain 2 after eviction, got %d", cache.Len())
 	}
 }
+
+// TestLRUCache_MaxBytes verifies that a byte-limited cache evicts the oldest items
+// until the total size of the stored values is within budget.
+func TestLRUCache_MaxBytes(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewByteLimitedLRUCache(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cache.Add("a", "1234")
+	cache.Add("b", "1234")
+
+	if cache.Bytes() != 8 {
+		t.Errorf("expected 8 bytes, got %d", cache.Bytes())
+	}
+
+	// The number of entries isn't limited, so filling up the budget exactly evicts nothing.
+	cache.Add("c", "12")
+
+	if cache.Len() != 3 {
+		t.Errorf("expected 3 entries within budget, got %d", cache.Len())
+	}
+
+	// Adding 6 more bytes must evict both "a" and "b" to stay within 10 bytes.
+	evicted := cache.Add("d", "123456")
+	if !evicted {
+		t.Error("expected eviction when exceeding the byte budget")
+	}
+
+	keys := cache.Keys()
+	if len(keys) != 2 || keys[0] != "c" || keys[1] != "d" {
+		t.Errorf("expected keys [c d], got %v", keys)
+	}
+
+	if cache.Bytes() != 8 {
+		t.Errorf("expected 8 bytes after eviction, got %d", cache.Bytes())
+	}
+}
+
+// TestLRUCache_MaxBytesUpdate checks that growing an existing value is accounted for,
+// and may evict other items.
+func TestLRUCache_MaxBytesUpdate(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := NewByteLimitedLRUCache(10)
+
+	cache.Add("a", "1234")
+	cache.Add("b", "1234")
+
+	evicted := cache.Add("b", "12345678")
+	if !evicted {
+		t.Error("expected growing 'b' to evict 'a'")
+	}
+
+	if _, ok := cache.Peek("a"); ok {
+		t.Error("expected 'a' to be evicted")
+	}
+
+	if cache.Bytes() != 8 {
+		t.Errorf("expected 8 bytes, got %d", cache.Bytes())
+	}
+
+	// Shrinking a value should free up space in the budget.
+	cache.Add("b", "1")
+
+	if cache.Bytes() != 1 {
+		t.Errorf("expected 1 byte after shrinking 'b', got %d", cache.Bytes())
+	}
+
+	cache.Remove("b")
+
+	if cache.Bytes() != 0 {
+		t.Errorf("expected 0 bytes after removing 'b', got %d", cache.Bytes())
+	}
+}
+
+// TestLRUCache_MaxBytesOversized ensures that a value larger than the whole budget
+// is not stored and doesn't flush the other items.
+func TestLRUCache_MaxBytesOversized(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := NewByteLimitedLRUCache(10)
+
+	cache.Add("a", "1234")
+	cache.Add("big", "1234")
+
+	evicted := cache.Add("big", "12345678901")
+	if !evicted {
+		t.Error("expected oversized value to be reported as evicted")
+	}
+
+	if _, ok := cache.Peek("big"); ok {
+		t.Error("expected oversized value not to be stored")
+	}
+
+	if _, ok := cache.Peek("a"); !ok {
+		t.Error("expected 'a' to remain in the cache")
+	}
+
+	if cache.Bytes() != 4 {
+		t.Errorf("expected 4 bytes, got %d", cache.Bytes())
+	}
+}
+
+// TestLRUCache_MaxBytesCachedItem checks that only the response body of a CachedItem is accounted for.
+func TestLRUCache_MaxBytesCachedItem(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := NewByteLimitedLRUCache(1024)
+
+	cache.Add("item", CachedItem{
+		Response: &SimpleHTTPResponse{StatusCode: 200, Body: make([]byte, 100)},
+		URL:      "https://www.pixiv.net/ajax/illust/1",
+	})
+
+	if cache.Bytes() != 100 {
+		t.Errorf("expected 100 bytes, got %d", cache.Bytes())
+	}
+}
@@ -30,7 +30,8 @@ type TagSearchResult struct {
 		Name   string      `json:"tag"`
 		ID     json.Number `json:"id"`
 	} `json:"pixpedia"`
-	CoverArtwork Illust // Custom field to store extended info about the cover artwork
+	TagTranslation TagTranslationWrapper `json:"tagTranslation"`
+	CoverArtwork   Illust                // Custom field to store extended info about the cover artwork
 }
 
 // ArtworkSearchResponse defines the API response structure for /ajax/search/[category]
@@ -267,7 +268,7 @@ func getStandardSearch(r *http.Request, settings ArtworkSearchSettings) (*Artwor
 func getPopularSearch(r *http.Request, settings ArtworkSearchSettings) (*ArtworkSearchResponse, error) {
 	// Check if popular search is enabled
 	if !config.GlobalConfig.Feature.PopularSearchEnabled {
-		return nil, i18n.Errorf("Popular search is disabled by server configuration.")
+		return nil, i18n.ErrorfContext(r.Context(), "Popular search is disabled by server configuration.")
 	}
 
 	// Perform popular search
@@ -0,0 +1,100 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Translation returns the translation of the tag to show in locale, or "" if
+// there's none.
+//
+// Translations are tried in the order zh-TW → zh → en → romaji, starting from
+// the language of locale: traditional Chinese locales start at zh-TW,
+// simplified Chinese locales at zh, and all other locales at en. Korean
+// locales try the Korean translation first.
+//
+// Translations that are the same as the tag name are skipped.
+func (t Tag) Translation(locale string) string {
+	romaji := t.Romaji
+	if romaji == "" {
+		romaji = t.TagTranslations.Romaji
+	}
+
+	candidates := []string{t.TagTranslations.ZhTw, t.TagTranslations.Zh, t.TagTranslations.En, romaji}
+	start := 2
+
+	tag := language.Make(locale)
+
+	switch base, _ := tag.Base(); base.String() {
+	case "zh":
+		start = 1
+
+		if script, _ := tag.Script(); script.String() == "Hant" {
+			start = 0
+		}
+	case "ko":
+		candidates = append([]string{t.TagTranslations.Ko}, candidates[start:]...)
+		start = 0
+	}
+
+	for _, candidate := range candidates[start:] {
+		if candidate != "" && candidate != t.Name {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// CanonicalTagName returns the canonical (usually Japanese) name of the tag that
+// name is a translation of, according to tag, the details pixiv returned for the
+// name, and whether there's exactly one such tag.
+//
+// pixiv either resolves the name to the tag itself, or returns the translations
+// of the tags matching it. Names of tags themselves are never translated, and
+// nothing is returned when the name could be a translation of several tags.
+func CanonicalTagName(name string, tag TagSearchResult) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", false
+	}
+
+	var candidates []string
+
+	addCandidate := func(candidate string) {
+		if candidate != "" && !strings.EqualFold(candidate, name) && !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	addCandidate(tag.Name)
+
+	for tagName, translations := range tag.TagTranslation {
+		if strings.EqualFold(tagName, name) {
+			return "", false
+		}
+
+		for _, translation := range []string{
+			translations.En,
+			translations.Ko,
+			translations.Zh,
+			translations.ZhTw,
+			translations.Romaji,
+		} {
+			if strings.EqualFold(strings.TrimSpace(translation), name) {
+				addCandidate(tagName)
+			}
+		}
+	}
+
+	if len(candidates) != 1 {
+		return "", false
+	}
+
+	return candidates[0], true
+}
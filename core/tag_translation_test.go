@@ -0,0 +1,81 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import "testing"
+
+func TestTagTranslation(t *testing.T) {
+	manga := Tag{
+		Name:   "漫画",
+		Romaji: "mannga",
+		TagTranslations: TagTranslations{
+			En:   "manga",
+			Ko:   "만화",
+			ZhTw: "漫畫",
+		},
+	}
+
+	tests := []struct {
+		tag    Tag
+		locale string
+		want   string
+	}{
+		{manga, "zh-TW", "漫畫"},
+		{manga, "zh-CN", "manga"}, // No zh translation, so falls back to en
+		{manga, "ko", "만화"},
+		{manga, "en", "manga"},
+		{manga, "vi-VN", "manga"},
+		{Tag{Name: "漫画", TagTranslations: TagTranslations{Zh: "漫画", Romaji: "mannga"}}, "zh-CN", "mannga"},
+		{Tag{Name: "オリジナル"}, "en", ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.tag.Translation(tt.locale); got != tt.want {
+			t.Errorf("Translation(%q) of %+v = %q, want %q", tt.locale, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalTagName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       TagSearchResult
+		canonical string
+		ok        bool
+	}{
+		{"cat", TagSearchResult{Name: "猫", AlternativeName: "cat"}, "猫", true}, // Resolved by pixiv
+		{"cat", TagSearchResult{Name: "cat", AlternativeName: "cat", TagTranslation: TagTranslationWrapper{
+			"猫": {En: "Cat", ZhTw: "貓"},
+		}}, "猫", true},
+		{" 貓 ", TagSearchResult{Name: "貓", TagTranslation: TagTranslationWrapper{
+			"猫": {En: "cat", ZhTw: "貓"},
+		}}, "猫", true},
+		{"orijinaru", TagSearchResult{Name: "orijinaru", TagTranslation: TagTranslationWrapper{
+			"オリジナル": {En: "original", Romaji: "orijinaru"},
+		}}, "オリジナル", true},
+		{"cat", TagSearchResult{Name: "cat", TagTranslation: TagTranslationWrapper{ // Ambiguous
+			"猫":  {En: "cat"},
+			"ねこ": {En: "cat"},
+		}}, "", false},
+		{"cat", TagSearchResult{Name: "猫", TagTranslation: TagTranslationWrapper{ // Ambiguous
+			"ねこ": {En: "cat"},
+		}}, "", false},
+		{"original", TagSearchResult{Name: "original", TagTranslation: TagTranslationWrapper{ // A tag itself
+			"original": {En: "original"},
+			"オリジナル":    {En: "original"},
+		}}, "", false},
+		{"猫", TagSearchResult{Name: "猫", TagTranslation: TagTranslationWrapper{
+			"猫": {En: "cat"},
+		}}, "", false},
+		{"dog", TagSearchResult{Name: "dog"}, "", false},
+		{"", TagSearchResult{Name: "猫"}, "", false},
+	}
+
+	for _, tt := range tests {
+		canonical, ok := CanonicalTagName(tt.name, tt.tag)
+		if canonical != tt.canonical || ok != tt.ok {
+			t.Errorf("CanonicalTagName(%q, %+v) = %q, %v, want %q, %v", tt.name, tt.tag, canonical, ok, tt.canonical, tt.ok)
+		}
+	}
+}
@@ -319,6 +319,7 @@ type Thumbnails struct {
 	Webp_540        string     // 540x540 thumbnail, quality 10, WebP format
 	Webp_1200       string     // 1200x1200 thumbnail, quality 90, WebP format
 	Video           string     // Video URL for ugoira
+	Animation       string     // Animated image URL for ugoira, when rendered by PixivFE instead of served as a video
 	IllustType      IllustType // Artwork type
 }
 
@@ -0,0 +1,149 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"codeberg.org/pixivfe/pixivfe/core/ugoira"
+	"codeberg.org/pixivfe/pixivfe/server/session"
+	"github.com/goccy/go-json"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// ugoiraRenderTimeout bounds fetching and rendering an ugoira, which isn't
+	// canceled along with the request that started it so that it can be cached.
+	ugoiraRenderTimeout = 2 * time.Minute
+
+	// ugoiraCacheTTL is how long rendered ugoira are kept in the cache.
+	ugoiraCacheTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// inflightUgoira coalesces concurrent renders of the same ugoira.
+	inflightUgoira singleflight.Group
+
+	// ugoiraRenderSlots limits how many ugoira are rendered at once, as rendering
+	// is CPU-bound and holds every frame archive in memory.
+	ugoiraRenderSlots = make(chan struct{}, max(runtime.NumCPU()/2, 1))
+
+	// ugoiraCache stores rendered ugoira on disk, and is created on first use.
+	ugoiraCache = sync.OnceValues(func() (*requests.BlobCache, error) {
+		return requests.NewBlobCache(config.GlobalConfig.Ugoira.CachePath, config.GlobalConfig.Ugoira.CacheMaxBytes, ugoiraCacheTTL)
+	})
+)
+
+// UgoiraMeta is the metadata of an ugoira, as returned by the ugoira_meta endpoint.
+type UgoiraMeta struct {
+	Src         string         `json:"src"`         // Frame archive, with frames up to 600x600
+	OriginalSrc string         `json:"originalSrc"` // Frame archive, with frames at their original size
+	MimeType    string         `json:"mime_type"`   // Media type of the frames
+	Frames      []ugoira.Frame `json:"frames"`
+}
+
+// GetUgoiraMeta retrieves the metadata of an ugoira artwork.
+func GetUgoiraMeta(r *http.Request, artworkID string) (*UgoiraMeta, error) {
+	url := GetUgoiraMetaURL(artworkID)
+
+	cookies := map[string]string{
+		"PHPSESSID": session.GetUserToken(r),
+	}
+
+	rawResp, err := requests.FetchJSONBodyField(r.Context(), url, cookies, r.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta UgoiraMeta
+	if err := json.Unmarshal(rawResp, &meta); err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
+
+// RenderUgoira returns an ugoira artwork rendered as an animated image in format,
+// from the cache if it was rendered before.
+//
+// Frames are fetched from pixiv directly rather than through the image proxy, at
+// the size shown on the artwork page.
+//
+// The metadata is fetched with the token of the user, as some ugoira are only
+// visible when logged in, so renders are cached separately for each token.
+func RenderUgoira(r *http.Request, artworkID string, format ugoira.Format) ([]byte, error) {
+	key := ugoiraCacheKey(artworkID, format, session.GetUserToken(r))
+
+	return cachedRender(r, ugoiraCache, &inflightUgoira, key, ugoiraRenderTimeout, func(ctx context.Context) ([]byte, error) {
+		return renderUgoira(r.WithContext(ctx), artworkID, format)
+	})
+}
+
+// renderUgoira fetches the frames of an ugoira artwork and renders them in format.
+func renderUgoira(r *http.Request, artworkID string, format ugoira.Format) ([]byte, error) {
+	meta, err := GetUgoiraMeta(r, artworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The frame archive is only fetched from pixiv's image servers, as its URL
+	// comes from the response to the metadata request
+	archive, err := FetchProxiedContent(r, meta.Src)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case ugoiraRenderSlots <- struct{}{}:
+		defer func() { <-ugoiraRenderSlots }()
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+
+	return ugoira.Render(format, archive, meta.Frames)
+}
+
+// UgoiraRenditionURL returns the URL that an ugoira artwork is played from, and
+// whether it's an animated image rendered by the built-in renderer rather than
+// a video from the ugoira proxy.
+func UgoiraRenditionURL(r *http.Request, artworkID string) (string, bool) {
+	proxy := session.GetUgoiraProxy(r)
+
+	if proxy.Host == "" && proxy.Path == config.BuiltInUgoiraRendererPath {
+		return fmt.Sprintf("%s/%s.%s", config.BuiltInUgoiraRendererPath, artworkID, config.GlobalConfig.Ugoira.Format), true
+	}
+
+	return session.GetProxyPrefix(proxy) + "/ugoira/" + artworkID, false
+}
+
+// setUgoiraRendition sets the URL that an ugoira artwork is played from on its
+// first image.
+func setUgoiraRendition(r *http.Request, illust *Illust) {
+	if len(illust.Images) == 0 {
+		return
+	}
+
+	if url, animated := UgoiraRenditionURL(r, illust.ID); animated {
+		illust.Images[0].Animation = url
+	} else {
+		illust.Images[0].Video = url
+	}
+}
+
+// ugoiraCacheKey returns the key that an ugoira rendered in format for a user
+// with token is cached under, which is a valid BlobCache key.
+func ugoiraCacheKey(artworkID string, format ugoira.Format, token string) string {
+	sum := sha256.Sum256([]byte(artworkID + "." + string(format) + "\x00" + token))
+
+	return hex.EncodeToString(sum[:])
+}
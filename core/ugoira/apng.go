@@ -0,0 +1,235 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ugoira
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"math"
+)
+
+// pngSignature starts every PNG file.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// PNG color types.
+const (
+	pngColorRGB  = 2
+	pngColorRGBA = 6
+)
+
+// PNG filter types, which are applied to each row before compression.
+const (
+	pngFilterNone = iota
+	pngFilterSub
+	pngFilterUp
+	pngFilterAverage
+	pngFilterPaeth
+	pngFilterCount
+)
+
+// apngEncoder encodes an APNG image, as specified at https://www.w3.org/TR/png-3/.
+//
+// The first frame is also the default image shown by decoders without APNG support.
+type apngEncoder struct {
+	buf      bytes.Buffer
+	width    int
+	height   int
+	bpp      int    // Bytes per pixel
+	sequence uint32 // Sequence number of the next fcTL or fdAT chunk
+
+	// Buffers reused for every frame
+	filtered bytes.Buffer
+	prev     []byte   // Previous row, before filtering
+	rows     [][]byte // Current row with each filter applied
+}
+
+func newAPNGEncoder(width, height, frameCount int, alpha bool) *apngEncoder {
+	e := &apngEncoder{width: width, height: height, bpp: 3}
+
+	colorType := byte(pngColorRGB)
+	if alpha {
+		colorType, e.bpp = pngColorRGBA, 4
+	}
+
+	e.prev = make([]byte, width*e.bpp)
+	e.rows = make([][]byte, pngFilterCount)
+
+	for i := range e.rows {
+		e.rows[i] = make([]byte, 1+width*e.bpp)
+		e.rows[i][0] = byte(i)
+	}
+
+	e.buf.WriteString(pngSignature)
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8] = 8 // Bit depth
+	ihdr[9] = colorType
+	e.writeChunk("IHDR", ihdr)
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(frameCount))
+	// A play count of 0 loops forever
+	e.writeChunk("acTL", actl)
+
+	return e
+}
+
+func (e *apngEncoder) encodeFrame(img *image.NRGBA, delay int) error {
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], e.nextSequence())
+	binary.BigEndian.PutUint32(fctl[4:], uint32(e.width))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(e.height))
+	// The frame covers the whole image, so its offset is 0
+	binary.BigEndian.PutUint16(fctl[20:], uint16(min(delay, math.MaxUint16)))
+	binary.BigEndian.PutUint16(fctl[22:], 1000) // The delay is in milliseconds
+	// Neither disposing nor blending frames is needed, as each covers the whole image
+	e.writeChunk("fcTL", fctl)
+
+	data, err := e.compress(img)
+	if err != nil {
+		return err
+	}
+
+	if e.sequence == 1 {
+		e.writeChunk("IDAT", data)
+	} else {
+		fdat := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, e.nextSequence())
+		e.writeChunk("fdAT", append(fdat, data...))
+	}
+
+	return nil
+}
+
+func (e *apngEncoder) finish() ([]byte, error) {
+	e.writeChunk("IEND", nil)
+
+	return e.buf.Bytes(), nil
+}
+
+func (e *apngEncoder) nextSequence() uint32 {
+	e.sequence++
+
+	return e.sequence - 1
+}
+
+// compress returns the image data of img, filtered and compressed.
+func (e *apngEncoder) compress(img *image.NRGBA) ([]byte, error) {
+	e.filtered.Reset()
+
+	zw, err := zlib.NewWriterLevel(&e.filtered, zlib.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	clear(e.prev)
+
+	for y := range e.height {
+		row := e.rows[pngFilterNone][1:]
+
+		pix := img.Pix[y*img.Stride:]
+		for x := range e.width {
+			copy(row[x*e.bpp:(x+1)*e.bpp], pix[x*4:x*4+e.bpp])
+		}
+
+		if _, err := zw.Write(e.filterRow(row)); err != nil {
+			return nil, err
+		}
+
+		copy(e.prev, row)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(e.filtered.Bytes()), nil
+}
+
+// filterRow returns row with the filter that's likely to compress it best applied,
+// preceded by the filter type.
+//
+// As recommended by the PNG specification, this is the filter for which the sum of
+// the absolute differences is the smallest.
+func (e *apngEncoder) filterRow(row []byte) []byte {
+	bpp, prev := e.bpp, e.prev
+
+	for i := range row {
+		var left, upLeft byte
+		if i >= bpp {
+			left, upLeft = row[i-bpp], prev[i-bpp]
+		}
+
+		up := prev[i]
+
+		e.rows[pngFilterSub][1+i] = row[i] - left
+		e.rows[pngFilterUp][1+i] = row[i] - up
+		e.rows[pngFilterAverage][1+i] = row[i] - byte((int(left)+int(up))/2)
+		e.rows[pngFilterPaeth][1+i] = row[i] - paeth(left, up, upLeft)
+	}
+
+	best, bestSum := pngFilterNone, math.MaxInt
+
+	for filter, filtered := range e.rows {
+		sum := 0
+		for _, b := range filtered[1:] {
+			sum += abs8(b)
+		}
+
+		if sum < bestSum {
+			best, bestSum = filter, sum
+		}
+	}
+
+	return e.rows[best]
+}
+
+// writeChunk writes a PNG chunk of type typ.
+func (e *apngEncoder) writeChunk(typ string, data []byte) {
+	var header [8]byte
+
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+
+	e.buf.Write(header[:])
+	e.buf.Write(data)
+	e.buf.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+}
+
+// paeth returns the predictor of the Paeth filter.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := absInt(p-int(a)), absInt(p-int(b)), absInt(p-int(c))
+
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+// abs8 returns the absolute value of b as a signed byte.
+func abs8(b byte) int {
+	return absInt(int(int8(b)))
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
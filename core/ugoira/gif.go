@@ -0,0 +1,124 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ugoira
+
+import (
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// gifEncoder encodes an animated GIF image, as specified at
+// https://www.w3.org/Graphics/GIF/spec-gif89a.txt.
+//
+// image/gif isn't used as it needs every frame to be held in memory at once.
+//
+// Each frame has its own palette, and is dithered to it.
+type gifEncoder struct {
+	buf       bytes.Buffer
+	width     int
+	height    int
+	quantizer *quantizer
+	indices   []byte // Palette indices of the current frame, reused for every frame
+}
+
+func newGIFEncoder(width, height int) *gifEncoder {
+	e := &gifEncoder{
+		width:     width,
+		height:    height,
+		quantizer: newQuantizer(width),
+		indices:   make([]byte, width*height),
+	}
+
+	e.buf.WriteString("GIF89a")
+
+	// Logical screen descriptor, without a global color table
+	e.writeUint16(width)
+	e.writeUint16(height)
+	e.buf.Write([]byte{0x70, 0, 0}) // 8 bits per primary color, background color, aspect ratio
+
+	// The NETSCAPE2.0 application extension makes the image loop, here forever
+	e.buf.Write([]byte{0x21, 0xff, 11})
+	e.buf.WriteString("NETSCAPE2.0")
+	e.buf.Write([]byte{3, 1, 0, 0, 0})
+
+	return e
+}
+
+func (e *gifEncoder) encodeFrame(img *image.NRGBA, delay int) error {
+	palette := e.quantizer.quantize(img, e.indices)
+
+	// Graphic control extension, with the delay in hundredths of seconds.
+	// Delays shorter than 20 ms are played as 100 ms by browsers.
+	e.buf.Write([]byte{0x21, 0xf9, 4, 0x04}) // Leave the frame in place
+	e.writeUint16(max((delay+5)/10, 2))
+	e.buf.Write([]byte{0, 0})
+
+	// Image descriptor, with a local color table
+	tableBits := max(bits.Len(uint(len(palette)-1)), 1)
+
+	e.buf.WriteByte(0x2c)
+	e.writeUint16(0)
+	e.writeUint16(0)
+	e.writeUint16(e.width)
+	e.writeUint16(e.height)
+	e.buf.WriteByte(0x80 | byte(tableBits-1))
+
+	for i := range 1 << tableBits {
+		var c color.RGBA
+		if i < len(palette) {
+			c = palette[i]
+		}
+
+		e.buf.Write([]byte{c.R, c.G, c.B})
+	}
+
+	// Image data, compressed with LZW into sub-blocks
+	litWidth := max(tableBits, 2)
+	e.buf.WriteByte(byte(litWidth))
+
+	lw := lzw.NewWriter(gifBlockWriter{&e.buf}, lzw.LSB, litWidth)
+
+	if _, err := lw.Write(e.indices); err != nil {
+		return err
+	}
+
+	if err := lw.Close(); err != nil {
+		return err
+	}
+
+	e.buf.WriteByte(0) // Block terminator
+
+	return nil
+}
+
+func (e *gifEncoder) finish() ([]byte, error) {
+	e.buf.WriteByte(0x3b) // Trailer
+
+	return e.buf.Bytes(), nil
+}
+
+func (e *gifEncoder) writeUint16(v int) {
+	e.buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(min(v, math.MaxUint16))))
+}
+
+// gifBlockWriter writes data as GIF data sub-blocks of at most 255 bytes, each
+// preceded by its size.
+type gifBlockWriter struct {
+	buf *bytes.Buffer
+}
+
+func (b gifBlockWriter) Write(p []byte) (int, error) {
+	for block := range slices.Chunk(p, 255) {
+		b.buf.WriteByte(byte(len(block)))
+		b.buf.Write(block)
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,257 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ugoira
+
+import (
+	"image"
+	"image/color"
+	"slices"
+)
+
+const (
+	// maxPaletteSize is the number of colors that a GIF palette can hold.
+	maxPaletteSize = 256
+
+	// quantizerBits is the number of bits per channel that colors are reduced to
+	// when building palettes.
+	quantizerBits = 5
+	quantizerBins = 1 << (3 * quantizerBits)
+)
+
+// quantizer reduces the colors of images to a palette chosen for each image.
+//
+// Palettes are built with the median cut algorithm, and images are dithered to
+// them with the Floyd-Steinberg algorithm. image/draw isn't used for dithering as
+// it searches the whole palette for every pixel.
+//
+// Transparency is ignored.
+type quantizer struct {
+	bins   [quantizerBins]quantizerBin
+	lookup [quantizerBins]int16 // Index of the palette color closest to each bin, or -1 if unknown
+
+	// Errors diffused to the current and the next rows, for each channel, with a
+	// pixel of padding on each side
+	errCur  []int32
+	errNext []int32
+}
+
+// quantizerBin accumulates the pixels whose colors fall into a bin.
+type quantizerBin struct {
+	count   uint32
+	r, g, b uint64 // Sums of the channels of the pixels
+}
+
+// quantizerBox is a box of the color space, holding the used bins in it.
+type quantizerBox struct {
+	bins  []uint16
+	count uint64
+}
+
+func newQuantizer(width int) *quantizer {
+	return &quantizer{
+		errCur:  make([]int32, (width+2)*3),
+		errNext: make([]int32, (width+2)*3),
+	}
+}
+
+// quantize returns a palette for img, and stores the index of the palette color
+// of each pixel of img into indices.
+func (q *quantizer) quantize(img *image.NRGBA, indices []byte) []color.RGBA {
+	palette := q.palette(img)
+	q.dither(img, palette, indices)
+
+	return palette
+}
+
+// palette returns the palette of at most maxPaletteSize colors that represents
+// img best.
+func (q *quantizer) palette(img *image.NRGBA) []color.RGBA {
+	clear(q.bins[:])
+
+	var used []uint16
+
+	bounds := img.Rect
+	for y := range bounds.Dy() {
+		row := img.Pix[y*img.Stride : y*img.Stride+bounds.Dx()*4]
+
+		for i := 0; i < len(row); i += 4 {
+			key := binKey(row[i], row[i+1], row[i+2])
+
+			bin := &q.bins[key]
+			if bin.count == 0 {
+				used = append(used, key)
+			}
+
+			bin.count++
+			bin.r += uint64(row[i])
+			bin.g += uint64(row[i+1])
+			bin.b += uint64(row[i+2])
+		}
+	}
+
+	boxes := []quantizerBox{{bins: used, count: uint64(bounds.Dx() * bounds.Dy())}}
+
+	// Repeatedly split the box holding the most pixels
+	for len(boxes) < maxPaletteSize {
+		largest := -1
+
+		for i, box := range boxes {
+			if len(box.bins) > 1 && (largest == -1 || box.count > boxes[largest].count) {
+				largest = i
+			}
+		}
+
+		if largest == -1 {
+			break
+		}
+
+		a, b := q.split(boxes[largest])
+		boxes[largest] = a
+		boxes = append(boxes, b)
+	}
+
+	palette := make([]color.RGBA, len(boxes))
+
+	for i, box := range boxes {
+		var count, r, g, b uint64
+
+		for _, key := range box.bins {
+			bin := &q.bins[key]
+			count += uint64(bin.count)
+			r, g, b = r+bin.r, g+bin.g, b+bin.b
+		}
+
+		palette[i] = color.RGBA{uint8(r / count), uint8(g / count), uint8(b / count), 0xff}
+	}
+
+	return palette
+}
+
+// split splits box in two along its longest side, so that each half holds about
+// as many pixels.
+func (q *quantizer) split(box quantizerBox) (quantizerBox, quantizerBox) {
+	// Find the channel with the largest range
+	lo := [3]uint16{0xffff, 0xffff, 0xffff}
+	hi := [3]uint16{}
+
+	for _, key := range box.bins {
+		for c, v := range binChannels(key) {
+			lo[c], hi[c] = min(lo[c], v), max(hi[c], v)
+		}
+	}
+
+	channel := 0
+	for c := range 3 {
+		if hi[c]-lo[c] > hi[channel]-lo[channel] {
+			channel = c
+		}
+	}
+
+	slices.SortFunc(box.bins, func(a, b uint16) int {
+		return int(binChannels(a)[channel]) - int(binChannels(b)[channel])
+	})
+
+	// Split at the median pixel, keeping at least one bin on each side
+	var count uint64
+
+	mid := 1
+
+	for i, key := range box.bins[:len(box.bins)-1] {
+		count += uint64(q.bins[key].count)
+		mid = i + 1
+
+		if count*2 >= box.count {
+			break
+		}
+	}
+
+	return quantizerBox{bins: box.bins[:mid], count: count},
+		quantizerBox{bins: box.bins[mid:], count: box.count - count}
+}
+
+// dither stores the index of the palette color of each pixel of img into indices,
+// diffusing the difference between the colors of the pixels and of the palette to
+// the neighboring pixels.
+func (q *quantizer) dither(img *image.NRGBA, palette []color.RGBA, indices []byte) {
+	for i := range q.lookup {
+		q.lookup[i] = -1
+	}
+
+	clear(q.errNext)
+
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+
+	for y := range height {
+		q.errCur, q.errNext = q.errNext, q.errCur
+		clear(q.errNext)
+
+		row := img.Pix[y*img.Stride:]
+
+		for x := range width {
+			var want [3]int32
+
+			for c := range 3 {
+				// Errors are stored multiplied by 16
+				want[c] = clampChannel(int32(row[x*4+c]) + q.errCur[(x+1)*3+c]/16)
+			}
+
+			index := q.closest(palette, uint8(want[0]), uint8(want[1]), uint8(want[2]))
+			indices[y*width+x] = byte(index)
+
+			got := palette[index]
+
+			for c, v := range [3]uint8{got.R, got.G, got.B} {
+				diff := want[c] - int32(v)
+
+				q.errCur[(x+2)*3+c] += diff * 7
+				q.errNext[x*3+c] += diff * 3
+				q.errNext[(x+1)*3+c] += diff * 5
+				q.errNext[(x+2)*3+c] += diff
+			}
+		}
+	}
+}
+
+// closest returns the index of the palette color closest to the given color,
+// as found for the bin of the color.
+func (q *quantizer) closest(palette []color.RGBA, r, g, b uint8) int {
+	key := binKey(r, g, b)
+
+	if index := q.lookup[key]; index >= 0 {
+		return int(index)
+	}
+
+	best, bestDist := 0, int32(-1)
+
+	for i, c := range palette {
+		dr, dg, db := int32(c.R)-int32(r), int32(c.G)-int32(g), int32(c.B)-int32(b)
+
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	q.lookup[key] = int16(best)
+
+	return best
+}
+
+// binKey returns the key of the bin that a color falls into.
+func binKey(r, g, b uint8) uint16 {
+	const shift = 8 - quantizerBits
+
+	return uint16(r>>shift)<<(2*quantizerBits) | uint16(g>>shift)<<quantizerBits | uint16(b>>shift)
+}
+
+// binChannels returns the channels of the color of the bin with the given key,
+// in the reduced range.
+func binChannels(key uint16) [3]uint16 {
+	const mask = 1<<quantizerBits - 1
+
+	return [3]uint16{key >> (2 * quantizerBits), key >> quantizerBits & mask, key & mask}
+}
+
+func clampChannel(v int32) int32 {
+	return min(max(v, 0), 0xff)
+}
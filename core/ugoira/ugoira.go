@@ -0,0 +1,222 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package ugoira renders ugoira, the animations of pixiv, as animated images.
+
+pixiv distributes the frames of an ugoira as a ZIP archive of JPEG or PNG images,
+along with the delay of each frame in its metadata. Render decodes the frames one
+at a time and encodes them as an animated WebP, GIF or APNG image, which browsers
+can play without any script.
+
+Every format is encoded losslessly, except for GIF, which is limited to 256 colors
+per frame.
+*/
+package ugoira
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	// Register the formats of the frames for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Format is a format that ugoira can be rendered as.
+type Format string
+
+const (
+	WebP Format = "webp"
+	GIF  Format = "gif"
+	APNG Format = "apng"
+)
+
+// Formats lists every format that ugoira can be rendered as.
+var Formats = []Format{WebP, GIF, APNG}
+
+// maxPixels bounds the size of the frames that are rendered, as each is decoded
+// into memory in full, along with a canvas of the size of the first one.
+const maxPixels = 16 << 20
+
+var (
+	// ErrUnknownFormat is returned for formats that ugoira can't be rendered as.
+	ErrUnknownFormat = errors.New("unknown ugoira format")
+
+	// ErrFrameTooLarge is returned for frames with more than maxPixels pixels.
+	ErrFrameTooLarge = errors.New("ugoira frame is too large to render")
+)
+
+// ParseFormat returns the Format named s, such as "webp".
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == s {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, s)
+}
+
+// MediaType returns the media type of images in format f.
+func (f Format) MediaType() string {
+	switch f {
+	case WebP:
+		return "image/webp"
+	case GIF:
+		return "image/gif"
+	case APNG:
+		return "image/apng"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Frame is a frame of an ugoira, as listed in its metadata.
+type Frame struct {
+	File  string `json:"file"`  // Name of the image of the frame in the archive
+	Delay int    `json:"delay"` // How long the frame is shown for, in milliseconds
+}
+
+// encoder encodes the frames of an animated image, one at a time.
+type encoder interface {
+	// encodeFrame encodes the next frame, shown for delay milliseconds.
+	//
+	// img is reused for the next frame once encodeFrame returns.
+	encodeFrame(img *image.NRGBA, delay int) error
+
+	// finish returns the encoded image, once every frame has been encoded.
+	finish() ([]byte, error)
+}
+
+// Render renders the frames of an ugoira, from archive as distributed by pixiv,
+// as an animated image in format that loops forever.
+//
+// Every frame is drawn at the size of the first one.
+func Render(format Format, archive []byte, frames []Frame) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("ugoira has no frames")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ugoira archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var (
+		enc    encoder
+		canvas *frameCanvas
+	)
+
+	for i, frame := range frames {
+		f, ok := files[frame.File]
+		if !ok {
+			return nil, fmt.Errorf("frame %s is missing from the ugoira archive", frame.File)
+		}
+
+		img, imageFormat, err := decodeFrame(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode frame %s: %w", frame.File, err)
+		}
+
+		if i == 0 {
+			bounds := img.Bounds()
+			canvas = newFrameCanvas(bounds.Dx(), bounds.Dy())
+
+			// JPEG frames are always opaque, so there's no need to store transparency
+			alpha := imageFormat != "jpeg"
+
+			switch format {
+			case WebP:
+				enc = newWebPEncoder(bounds.Dx(), bounds.Dy(), alpha)
+			case GIF:
+				enc = newGIFEncoder(bounds.Dx(), bounds.Dy())
+			case APNG:
+				enc = newAPNGEncoder(bounds.Dx(), bounds.Dy(), len(frames), alpha)
+			default:
+				return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+			}
+		}
+
+		if err := enc.encodeFrame(canvas.draw(img), frame.Delay); err != nil {
+			return nil, fmt.Errorf("failed to encode frame %s: %w", frame.File, err)
+		}
+	}
+
+	return enc.finish()
+}
+
+// decodeFrame decodes the image of a frame, returning its format name as
+// image.Decode does.
+//
+// Its size is checked before decoding it, so that frames with more than
+// maxPixels pixels are rejected without allocating memory for them.
+func decodeFrame(f *zip.File) (image.Image, string, error) {
+	config, err := decodeFrameConfig(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d", ErrFrameTooLarge, config.Width, config.Height)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+
+	return image.Decode(rc)
+}
+
+// decodeFrameConfig decodes the dimensions of the image of a frame.
+func decodeFrameConfig(f *zip.File) (image.Config, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer rc.Close()
+
+	config, _, err := image.DecodeConfig(rc)
+
+	return config, err
+}
+
+// frameCanvas converts frames to non-premultiplied RGBA images of the same size,
+// reusing the same memory for every frame.
+type frameCanvas struct {
+	rgba  *image.RGBA
+	nrgba *image.NRGBA // Shares its pixels with rgba
+}
+
+func newFrameCanvas(width, height int) *frameCanvas {
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	return &frameCanvas{
+		rgba:  rgba,
+		nrgba: &image.NRGBA{Pix: rgba.Pix, Stride: rgba.Stride, Rect: rgba.Rect},
+	}
+}
+
+// draw draws img onto the canvas and returns it.
+func (c *frameCanvas) draw(img image.Image) *image.NRGBA {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		// Premultiplied and non-premultiplied colors are the same when opaque,
+		// and draw is much faster at converting to premultiplied colors
+		draw.Draw(c.rgba, c.rgba.Rect, img, img.Bounds().Min, draw.Src)
+	} else {
+		draw.Draw(c.nrgba, c.nrgba.Rect, img, img.Bounds().Min, draw.Src)
+	}
+
+	return c.nrgba
+}
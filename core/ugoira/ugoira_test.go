@@ -0,0 +1,274 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ugoira
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+)
+
+// testFrames returns an ugoira archive of PNG frames with the given delays, and
+// the frames.
+func testFrames(t *testing.T, delays ...int) ([]byte, []Frame, []*image.NRGBA) {
+	t.Helper()
+
+	var (
+		buf    bytes.Buffer
+		frames []Frame
+		images []*image.NRGBA
+	)
+
+	zw := zip.NewWriter(&buf)
+
+	for i, delay := range delays {
+		img := image.NewNRGBA(image.Rect(0, 0, 7, 5))
+
+		for y := range 5 {
+			for x := range 7 {
+				img.SetNRGBA(x, y, color.NRGBA{uint8(x * 40), uint8(y * 60), uint8(i * 100), 0xff})
+			}
+		}
+
+		name := fmt.Sprintf("%06d.png", i)
+
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := png.Encode(w, img); err != nil {
+			t.Fatal(err)
+		}
+
+		frames = append(frames, Frame{File: name, Delay: delay})
+		images = append(images, img)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes(), frames, images
+}
+
+func TestRenderGIF(t *testing.T) {
+	archive, frames, images := testFrames(t, 100, 50, 10)
+
+	data, err := Render(GIF, archive, frames)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode the rendered GIF: %v", err)
+	}
+
+	if g.LoopCount != 0 {
+		t.Errorf("LoopCount = %d, want 0", g.LoopCount)
+	}
+
+	if want := []int{10, 5, 2}; fmt.Sprint(g.Delay) != fmt.Sprint(want) {
+		t.Errorf("Delay = %v, want %v", g.Delay, want)
+	}
+
+	// Frames have fewer colors than a palette holds, so they're kept as is
+	for i, frame := range g.Image {
+		if !sameImage(frame, images[i]) {
+			t.Errorf("frame %d differs from the original", i)
+		}
+	}
+}
+
+func TestRenderAPNG(t *testing.T) {
+	archive, frames, images := testFrames(t, 100, 50)
+
+	data, err := Render(APNG, archive, frames)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	// Decoders without APNG support show the first frame
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode the rendered APNG: %v", err)
+	}
+
+	if !sameImage(img, images[0]) {
+		t.Errorf("default image differs from the first frame")
+	}
+
+	chunks := pngChunks(t, data)
+
+	want := []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "IEND"}
+	if fmt.Sprint(chunks) != fmt.Sprint(want) {
+		t.Errorf("chunks = %v, want %v", chunks, want)
+	}
+}
+
+func TestRenderWebP(t *testing.T) {
+	archive, frames, _ := testFrames(t, 100, 50)
+
+	data, err := Render(WebP, archive, frames)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		t.Fatalf("data doesn't start with a WebP header")
+	}
+
+	if size := binary.LittleEndian.Uint32(data[4:]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(data)-8)
+	}
+
+	var (
+		chunks    []string
+		durations []int
+	)
+
+	for rest := data[12:]; len(rest) > 0; {
+		fourCC, size := string(rest[:4]), int(binary.LittleEndian.Uint32(rest[4:]))
+		payload := rest[8 : 8+size]
+
+		switch fourCC {
+		case "VP8X":
+			if payload[0] != 0x12 { // Animation and alpha
+				t.Errorf("VP8X flags = %#x", payload[0])
+			}
+		case "ANMF":
+			durations = append(durations, int(payload[12])|int(payload[13])<<8|int(payload[14])<<16)
+
+			if frame := string(payload[16:20]); frame != "VP8L" || payload[24] != 0x2f {
+				t.Errorf("ANMF frame data = %q, want a VP8L bitstream", frame)
+			}
+		}
+
+		chunks = append(chunks, fourCC)
+		rest = rest[8+size+size%2:]
+	}
+
+	if want := []string{"VP8X", "ANIM", "ANMF", "ANMF"}; fmt.Sprint(chunks) != fmt.Sprint(want) {
+		t.Errorf("chunks = %v, want %v", chunks, want)
+	}
+
+	if want := []int{100, 50}; fmt.Sprint(durations) != fmt.Sprint(want) {
+		t.Errorf("durations = %v, want %v", durations, want)
+	}
+}
+
+func TestRenderMissingFrame(t *testing.T) {
+	archive, frames, _ := testFrames(t, 100)
+	frames = append(frames, Frame{File: "missing.png", Delay: 100})
+
+	if _, err := Render(WebP, archive, frames); err == nil {
+		t.Errorf("Render() error = nil, want an error for the missing frame")
+	}
+}
+
+// TestRenderFrameTooLarge verifies that frames are rejected by their dimensions,
+// before they're decoded.
+func TestRenderFrameTooLarge(t *testing.T) {
+	// A PNG that declares 8192x8192 pixels, without any image data
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 8192)
+	binary.BigEndian.PutUint32(ihdr[4:], 8192)
+	ihdr[8] = 8 // Bit depth
+	ihdr[9] = 6 // Color type: RGBA
+
+	var header bytes.Buffer
+
+	header.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&header, binary.BigEndian, uint32(len(ihdr)))
+	header.WriteString("IHDR")
+	header.Write(ihdr)
+	_ = binary.Write(&header, binary.BigEndian, crc32.ChecksumIEEE(append([]byte("IHDR"), ihdr...)))
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("000000.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Render(WebP, buf.Bytes(), []Frame{{File: "000000.png", Delay: 100}})
+	if !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Render() error = %v, want %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range Formats {
+		if got, err := ParseFormat(string(format)); err != nil || got != format {
+			t.Errorf("ParseFormat(%q) = %q, %v", format, got, err)
+		}
+	}
+
+	if _, err := ParseFormat("mp4"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseFormat(\"mp4\") error = %v, want ErrUnknownFormat", err)
+	}
+
+	// The configuration validates Ugoira.Format against its own list
+	if len(config.UgoiraFormats) != len(Formats) {
+		t.Errorf("config.UgoiraFormats = %q, want %q", config.UgoiraFormats, Formats)
+	}
+
+	for _, name := range config.UgoiraFormats {
+		if _, err := ParseFormat(name); err != nil {
+			t.Errorf("config.UgoiraFormats lists %q: %v", name, err)
+		}
+	}
+}
+
+func sameImage(a image.Image, b *image.NRGBA) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+
+	for y := range b.Rect.Dy() {
+		for x := range b.Rect.Dx() {
+			if color.NRGBAModel.Convert(a.At(x, y)) != b.NRGBAAt(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// pngChunks returns the types of the chunks of a PNG file.
+func pngChunks(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	var chunks []string
+
+	for rest := data[len(pngSignature):]; len(rest) > 0; {
+		size := int(binary.BigEndian.Uint32(rest))
+		chunks = append(chunks, string(rest[4:8]))
+		rest = rest[12+size:]
+	}
+
+	return chunks
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ugoira
+
+import (
+	"image"
+
+	"codeberg.org/pixivfe/pixivfe/core/webp"
+)
+
+// webpEncoder encodes an animated WebP image, with each frame encoded losslessly.
+type webpEncoder struct {
+	animation *webp.Animation
+}
+
+func newWebPEncoder(width, height int, alpha bool) *webpEncoder {
+	return &webpEncoder{animation: webp.NewAnimation(width, height, alpha)}
+}
+
+func (e *webpEncoder) encodeFrame(img *image.NRGBA, delay int) error {
+	e.animation.AddFrame(img, delay)
+
+	return nil
+}
+
+func (e *webpEncoder) finish() ([]byte, error) {
+	return e.animation.Bytes(), nil
+}
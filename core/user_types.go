@@ -7,6 +7,7 @@ Types for core/user.go
 package core
 
 import (
+	"context"
 	"net/url"
 	"slices"
 	"sort"
@@ -62,8 +63,9 @@ func NewUserWorkCategory(value string) UserWorkCategory {
 	}
 }
 
-// Validate checks if the current work category is valid.
-func (cat *UserWorkCategory) Validate() error {
+// Validate checks if the current work category is valid, with the error
+// localized to the locale of ctx.
+func (cat *UserWorkCategory) Validate(ctx context.Context) error {
 	validValues := []string{
 		CategoryValueEmpty,
 		CategoryValueArtworks,
@@ -83,7 +85,7 @@ func (cat *UserWorkCategory) Validate() error {
 	if slices.Contains(validValues, cat.Value) {
 		return nil
 	}
-	return i18n.Errorf(`Invalid work category: %#v.`, cat.Value)
+	return i18n.ErrorfContext(ctx, `Invalid work category: %#v.`, cat.Value)
 }
 
 // SetPageLimit sets the maximum number of pages for the category.
@@ -0,0 +1,204 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webp
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// bitWriter writes values of up to 32 bits, least significant bit first.
+type bitWriter struct {
+	buf  []byte
+	bits uint64
+	n    uint // Number of pending bits
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	w.bits |= uint64(v) << w.n
+	w.n += n
+
+	for w.n >= 8 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits >>= 8
+		w.n -= 8
+	}
+}
+
+func (w *bitWriter) writeBool(b bool) {
+	if b {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+}
+
+// bytes returns the written bits, padded with zeros to a whole byte.
+func (w *bitWriter) bytes() []byte {
+	if w.n > 0 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits, w.n = 0, 0
+	}
+
+	return w.buf
+}
+
+// prefixCode is a canonical prefix code, whose codes are stored bit-reversed to be
+// written least significant bit first.
+//
+// A code with a single symbol has a length of 0, as it doesn't need to be written.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+// newPrefixCode returns the canonical prefix code with the given code lengths.
+func newPrefixCode(lengths []uint8) prefixCode {
+	code := prefixCode{
+		lengths: slices.Clone(lengths),
+		codes:   make([]uint16, len(lengths)),
+	}
+
+	var used int
+
+	for _, length := range lengths {
+		if length > 0 {
+			used++
+		}
+	}
+
+	if used <= 1 {
+		clear(code.lengths)
+
+		return code
+	}
+
+	var counts [vp8lMaxCodeLength + 1]int
+	for _, length := range lengths {
+		counts[length]++
+	}
+
+	counts[0] = 0
+
+	var next [vp8lMaxCodeLength + 1]int
+
+	for length, c := 0, 0; length < vp8lMaxCodeLength; length++ {
+		c = (c + counts[length]) << 1
+		next[length+1] = c
+	}
+
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		c := next[length]
+		next[length]++
+
+		code.codes[symbol] = uint16(bits.Reverse16(uint16(c)) >> (16 - length))
+	}
+
+	return code
+}
+
+func (c prefixCode) write(w *bitWriter, symbol int) {
+	w.write(uint32(c.codes[symbol]), uint(c.lengths[symbol]))
+}
+
+// huffmanLengths returns the lengths of the Huffman code for symbols occurring as
+// often as given by histogram, limited to maxLength.
+//
+// When the code is too long, the counts are flattened until it fits, which keeps
+// the code complete at a small cost in size.
+func huffmanLengths(histogram []uint32, maxLength int) []uint8 {
+	counts := slices.Clone(histogram)
+
+	for {
+		lengths := unlimitedHuffmanLengths(counts)
+
+		if int(slices.Max(lengths)) <= maxLength {
+			return lengths
+		}
+
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = max(count/2, 1)
+			}
+		}
+	}
+}
+
+// unlimitedHuffmanLengths returns the lengths of the Huffman code for symbols
+// occurring as often as given by histogram. A code with a single symbol has a
+// length of 1.
+func unlimitedHuffmanLengths(histogram []uint32) []uint8 {
+	type node struct {
+		count       uint64
+		left, right int // Children, or -1 for leaves
+	}
+
+	lengths := make([]uint8, len(histogram))
+
+	var leaves []int
+
+	for symbol, count := range histogram {
+		if count > 0 {
+			leaves = append(leaves, symbol)
+		}
+	}
+
+	switch len(leaves) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[leaves[0]] = 1
+
+		return lengths
+	}
+
+	slices.SortStableFunc(leaves, func(a, b int) int {
+		return int(histogram[a]) - int(histogram[b])
+	})
+
+	// Leaves are the first nodes, followed by the internal nodes in the order
+	// they were merged, which is also by increasing count
+	nodes := make([]node, 0, 2*len(leaves)-1)
+	for _, symbol := range leaves {
+		nodes = append(nodes, node{count: uint64(histogram[symbol]), left: -1, right: -1})
+	}
+
+	nextLeaf, nextInternal := 0, len(leaves)
+
+	// pop returns the unmerged node with the lowest count
+	pop := func() int {
+		if nextLeaf < len(leaves) && (nextInternal >= len(nodes) || nodes[nextLeaf].count <= nodes[nextInternal].count) {
+			nextLeaf++
+
+			return nextLeaf - 1
+		}
+
+		nextInternal++
+
+		return nextInternal - 1
+	}
+
+	for range len(leaves) - 1 {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, left: a, right: b})
+	}
+
+	// Children always come before their parent, so depths are known from the root
+	depths := make([]uint8, len(nodes))
+
+	for i := len(nodes) - 1; i >= len(leaves); i-- {
+		depths[nodes[i].left] = depths[i] + 1
+		depths[nodes[i].right] = depths[i] + 1
+	}
+
+	for i, symbol := range leaves {
+		lengths[symbol] = depths[i]
+	}
+
+	return lengths
+}
@@ -0,0 +1,440 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webp
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	vp8lSignature = 0x2f
+
+	vp8lTransformPredictor     = 0
+	vp8lTransformSubtractGreen = 2
+
+	// vp8lPredictorBits is the log2 of the size of the blocks that share a
+	// predictor mode, with 2 as the smallest size that can be encoded.
+	vp8lPredictorBits  = 4
+	vp8lPredictorModes = 14
+
+	// Sizes of the alphabets of the prefix codes of each channel, and of the
+	// backward reference distances, which aren't used.
+	vp8lGreenAlphabet    = 256 + 24
+	vp8lChannelAlphabet  = 256
+	vp8lDistanceAlphabet = 40
+
+	vp8lMaxCodeLength           = 15
+	vp8lMaxCodeLengthCodeLength = 7
+	vp8lCodeLengthCodes         = 19
+)
+
+// vp8lCodeLengthCodeOrder is the order in which the lengths of the code length
+// code are stored.
+var vp8lCodeLengthCodeOrder = [vp8lCodeLengthCodes]int{
+	17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// vp8lEncoder encodes images as VP8L bitstreams, the lossless format of WebP, as
+// specified by RFC 9649.
+//
+// Images are encoded with the subtract green and predictor transforms, and prefix
+// codes for the pixels that remain. Backward references and color caches aren't
+// used.
+type vp8lEncoder struct {
+	width  int
+	height int
+
+	// Buffers reused for every image
+	argb      []uint32
+	residuals []uint32
+	modes     []uint32
+}
+
+func newVP8LEncoder(width, height int) *vp8lEncoder {
+	blocksX := subSampleSize(width, vp8lPredictorBits)
+	blocksY := subSampleSize(height, vp8lPredictorBits)
+
+	return &vp8lEncoder{
+		width:     width,
+		height:    height,
+		argb:      make([]uint32, width*height),
+		residuals: make([]uint32, width*height),
+		modes:     make([]uint32, blocksX*blocksY),
+	}
+}
+
+// encode returns the VP8L bitstream of img, which must be of the size of the encoder.
+func (e *vp8lEncoder) encode(img *image.NRGBA, alpha bool) []byte {
+	for y := range e.height {
+		row := img.Pix[y*img.Stride:]
+
+		for x := range e.width {
+			r, g, b, a := uint32(row[x*4]), uint32(row[x*4+1]), uint32(row[x*4+2]), uint32(row[x*4+3])
+
+			// Subtract green, as colors are often correlated
+			r, b = (r-g)&0xff, (b-g)&0xff
+
+			e.argb[y*e.width+x] = a<<24 | r<<16 | g<<8 | b
+		}
+	}
+
+	e.predict()
+
+	var w bitWriter
+
+	w.write(vp8lSignature, 8)
+	w.write(uint32(e.width-1), 14)
+	w.write(uint32(e.height-1), 14)
+	w.writeBool(alpha)
+	w.write(0, 3) // Version
+
+	// Transforms are listed in the order that they were applied in
+	w.writeBool(true)
+	w.write(vp8lTransformSubtractGreen, 2)
+
+	w.writeBool(true)
+	w.write(vp8lTransformPredictor, 2)
+	w.write(vp8lPredictorBits-2, 3)
+	writeEntropyImage(&w, e.modes, false)
+
+	w.writeBool(false) // No more transforms
+
+	writeEntropyImage(&w, e.residuals, true)
+
+	return w.bytes()
+}
+
+// predict chooses the predictor mode of each block, and stores the difference
+// between each pixel and its prediction into residuals.
+func (e *vp8lEncoder) predict() {
+	blockSize := 1 << vp8lPredictorBits
+	blocksX := subSampleSize(e.width, vp8lPredictorBits)
+
+	for by := 0; by*blockSize < e.height; by++ {
+		for bx := 0; bx*blockSize < e.width; bx++ {
+			x0, y0 := bx*blockSize, by*blockSize
+			x1, y1 := min(x0+blockSize, e.width), min(y0+blockSize, e.height)
+
+			best, bestCost := 0, math.MaxInt
+
+			for mode := range vp8lPredictorModes {
+				cost := 0
+
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += residualCost(e.argb[y*e.width+x], e.prediction(mode, x, y))
+					}
+				}
+
+				if cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			e.modes[by*blocksX+bx] = 0xff000000 | uint32(best)<<8
+
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					pos := y*e.width + x
+					e.residuals[pos] = subPixels(e.argb[pos], e.prediction(best, x, y))
+				}
+			}
+		}
+	}
+}
+
+// prediction returns the prediction of the pixel at x, y with the given mode.
+func (e *vp8lEncoder) prediction(mode, x, y int) uint32 {
+	pos := y*e.width + x
+
+	// The top row and the left column are predicted regardless of the mode
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return e.argb[pos-1]
+	case x == 0:
+		return e.argb[pos-e.width]
+	}
+
+	// For the rightmost column, this is the leftmost pixel of the current row
+	left, top, topLeft, topRight := e.argb[pos-1], e.argb[pos-e.width], e.argb[pos-e.width-1], e.argb[pos-e.width+1]
+
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return left
+	case 2:
+		return top
+	case 3:
+		return topRight
+	case 4:
+		return topLeft
+	case 5:
+		return average2(average2(left, topRight), top)
+	case 6:
+		return average2(left, topLeft)
+	case 7:
+		return average2(left, top)
+	case 8:
+		return average2(topLeft, top)
+	case 9:
+		return average2(top, topRight)
+	case 10:
+		return average2(average2(left, topLeft), average2(top, topRight))
+	case 11:
+		return selectPixel(left, top, topLeft)
+	case 12:
+		return mapChannels(func(l, t, tl int32) int32 { return l + t - tl }, left, top, topLeft)
+	default:
+		avg := average2(left, top)
+
+		return mapChannels(func(a, tl, _ int32) int32 { return a + (a-tl)/2 }, avg, topLeft, 0)
+	}
+}
+
+// writeEntropyImage writes pixels as an entropy-coded image, with a prefix code
+// for each channel. Only the main image can have meta prefix codes.
+func writeEntropyImage(w *bitWriter, pixels []uint32, main bool) {
+	w.writeBool(false) // No color cache
+
+	if main {
+		w.writeBool(false) // No meta prefix codes
+	}
+
+	green := make([]uint32, vp8lGreenAlphabet)
+	red := make([]uint32, vp8lChannelAlphabet)
+	blue := make([]uint32, vp8lChannelAlphabet)
+	alpha := make([]uint32, vp8lChannelAlphabet)
+
+	for _, p := range pixels {
+		green[p>>8&0xff]++
+		red[p>>16&0xff]++
+		blue[p&0xff]++
+		alpha[p>>24]++
+	}
+
+	greenCode := writePrefixCode(w, green)
+	redCode := writePrefixCode(w, red)
+	blueCode := writePrefixCode(w, blue)
+	alphaCode := writePrefixCode(w, alpha)
+	writePrefixCode(w, make([]uint32, vp8lDistanceAlphabet))
+
+	for _, p := range pixels {
+		greenCode.write(w, int(p>>8&0xff))
+		redCode.write(w, int(p>>16&0xff))
+		blueCode.write(w, int(p&0xff))
+		alphaCode.write(w, int(p>>24))
+	}
+}
+
+// writePrefixCode writes a prefix code for symbols occurring as often as given by
+// histogram, and returns it.
+func writePrefixCode(w *bitWriter, histogram []uint32) prefixCode {
+	var symbols []int
+
+	for symbol, count := range histogram {
+		if count > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	// Codes of up to two symbols below 256 can be written as simple codes
+	if len(symbols) <= 2 && (len(symbols) == 0 || symbols[len(symbols)-1] < 256) {
+		if len(symbols) == 0 {
+			symbols = []int{0}
+		}
+
+		w.writeBool(true)
+		w.write(uint32(len(symbols)-1), 1)
+
+		if symbols[0] < 2 {
+			w.writeBool(false)
+			w.write(uint32(symbols[0]), 1)
+		} else {
+			w.writeBool(true)
+			w.write(uint32(symbols[0]), 8)
+		}
+
+		lengths := make([]uint8, len(histogram))
+		for _, symbol := range symbols {
+			lengths[symbol] = 1
+		}
+
+		if len(symbols) == 2 {
+			w.write(uint32(symbols[1]), 8)
+		}
+
+		return newPrefixCode(lengths)
+	}
+
+	lengths := huffmanLengths(histogram, vp8lMaxCodeLength)
+
+	w.writeBool(false)
+	writeCodeLengths(w, lengths)
+
+	return newPrefixCode(lengths)
+}
+
+// writeCodeLengths writes the code lengths of a normal prefix code, compressed
+// with runs and with a prefix code of their own.
+func writeCodeLengths(w *bitWriter, lengths []uint8) {
+	type token struct {
+		symbol    int // A code length, or 16, 17 or 18 for runs
+		extra     uint32
+		extraBits uint
+	}
+
+	var tokens []token
+
+	for i := 0; i < len(lengths); {
+		length := lengths[i]
+
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == length {
+			run++
+		}
+
+		i += run
+
+		if length == 0 {
+			for run >= 3 {
+				if run >= 11 {
+					n := min(run, 138)
+					tokens = append(tokens, token{18, uint32(n - 11), 7})
+					run -= n
+				} else {
+					n := min(run, 10)
+					tokens = append(tokens, token{17, uint32(n - 3), 3})
+					run -= n
+				}
+			}
+		} else {
+			// Repeat the previous length after writing it once
+			tokens = append(tokens, token{symbol: int(length)})
+			run--
+
+			for run >= 3 {
+				n := min(run, 6)
+				tokens = append(tokens, token{16, uint32(n - 3), 2})
+				run -= n
+			}
+		}
+
+		for range run {
+			tokens = append(tokens, token{symbol: int(length)})
+		}
+	}
+
+	histogram := make([]uint32, vp8lCodeLengthCodes)
+	for _, t := range tokens {
+		histogram[t.symbol]++
+	}
+
+	codeLengths := huffmanLengths(histogram, vp8lMaxCodeLengthCodeLength)
+
+	count := 4
+	for i, symbol := range vp8lCodeLengthCodeOrder {
+		if codeLengths[symbol] > 0 {
+			count = max(count, i+1)
+		}
+	}
+
+	w.write(uint32(count-4), 4)
+
+	for _, symbol := range vp8lCodeLengthCodeOrder[:count] {
+		w.write(uint32(codeLengths[symbol]), 3)
+	}
+
+	w.writeBool(false) // Lengths are written for every symbol
+
+	code := newPrefixCode(codeLengths)
+
+	for _, t := range tokens {
+		code.write(w, t.symbol)
+		w.write(t.extra, t.extraBits)
+	}
+}
+
+// subSampleSize returns the size of a dimension of an image sub-sampled by
+// 2^bits, as for the image of the predictor modes.
+func subSampleSize(size, bits int) int {
+	return (size + 1<<bits - 1) >> bits
+}
+
+// residualCost estimates how costly the difference between a pixel and its
+// prediction is to encode, as the sum of the absolute differences of each channel.
+func residualCost(pixel, prediction uint32) int {
+	residual := subPixels(pixel, prediction)
+
+	return abs8(byte(residual)) + abs8(byte(residual>>8)) + abs8(byte(residual>>16)) + abs8(byte(residual>>24))
+}
+
+// subPixels subtracts each channel of b from that of a, modulo 256.
+func subPixels(a, b uint32) uint32 {
+	var result uint32
+
+	for shift := 0; shift < 32; shift += 8 {
+		result |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+
+	return result
+}
+
+// average2 returns the average of each channel of a and b, rounded down.
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// selectPixel returns whichever of left and top is closest to the gradient
+// prediction left + top - topLeft, preferring top.
+func selectPixel(left, top, topLeft uint32) uint32 {
+	var distLeft, distTop int
+
+	for shift := 0; shift < 32; shift += 8 {
+		l, t, tl := int(left>>shift&0xff), int(top>>shift&0xff), int(topLeft>>shift&0xff)
+
+		// The distances of the prediction to left and top respectively
+		distLeft += absInt(t - tl)
+		distTop += absInt(l - tl)
+	}
+
+	if distLeft < distTop {
+		return left
+	}
+
+	return top
+}
+
+// mapChannels returns the pixel whose channels are the result of f applied to the
+// channels of a, b and c, clamped to the range of a channel.
+func mapChannels(f func(a, b, c int32) int32, a, b, c uint32) uint32 {
+	var result uint32
+
+	for shift := 0; shift < 32; shift += 8 {
+		v := f(int32(a>>shift&0xff), int32(b>>shift&0xff), int32(c>>shift&0xff))
+		result |= uint32(clampChannel(v)) << shift
+	}
+
+	return result
+}
+
+// abs8 returns the absolute value of b as a signed byte.
+func abs8(b byte) int {
+	return absInt(int(int8(b)))
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+func clampChannel(v int32) int32 {
+	return min(max(v, 0), 0xff)
+}
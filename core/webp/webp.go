@@ -0,0 +1,131 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package webp encodes still and animated WebP images, as specified by RFC 9649.
+
+Images are always encoded losslessly, as a lossy encoder is beyond the scope of
+PixivFE. golang.org/x/image/webp can be used to decode them.
+*/
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// Flags of the VP8X chunk.
+const (
+	flagAnimation = 0x02
+	flagAlpha     = 0x10
+)
+
+// Encode writes img to w as a still WebP image.
+func Encode(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+	}
+
+	bitstream := newVP8LEncoder(bounds.Dx(), bounds.Dy()).encode(nrgba, !nrgba.Opaque())
+
+	var buf bytes.Buffer
+
+	buf.WriteString("RIFF")
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(4+8+len(bitstream)+len(bitstream)%2)))
+	buf.WriteString("WEBP")
+	writeChunk(&buf, "VP8L", bitstream)
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// Animation encodes an animated WebP image that loops forever, one frame at a
+// time.
+type Animation struct {
+	buf    bytes.Buffer
+	width  int
+	height int
+	alpha  bool
+	vp8l   *vp8lEncoder
+}
+
+// NewAnimation returns an Animation of the given size, where alpha tells whether
+// its frames may be transparent.
+func NewAnimation(width, height int, alpha bool) *Animation {
+	a := &Animation{
+		width:  width,
+		height: height,
+		alpha:  alpha,
+		vp8l:   newVP8LEncoder(width, height),
+	}
+
+	// The size of the RIFF chunk is set once every frame has been encoded
+	a.buf.WriteString("RIFF\x00\x00\x00\x00WEBP")
+
+	flags := byte(flagAnimation)
+	if alpha {
+		flags |= flagAlpha
+	}
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+	putUint24(vp8x[4:], width-1)
+	putUint24(vp8x[7:], height-1)
+	writeChunk(&a.buf, "VP8X", vp8x)
+
+	// A transparent background, and a loop count of 0 to loop forever
+	writeChunk(&a.buf, "ANIM", make([]byte, 6))
+
+	return a
+}
+
+// AddFrame encodes img, which must be of the size of the animation, as the next
+// frame, shown for duration milliseconds.
+func (a *Animation) AddFrame(img *image.NRGBA, duration int) {
+	bitstream := a.vp8l.encode(img, a.alpha)
+
+	var frame bytes.Buffer
+
+	header := make([]byte, 16)
+	// The frame covers the whole image, so its offset is 0
+	putUint24(header[6:], a.width-1)
+	putUint24(header[9:], a.height-1)
+	putUint24(header[12:], min(duration, 1<<24-1))
+	header[15] = 0x02 // Don't blend the frame with the previous one, nor dispose it
+	frame.Write(header)
+
+	writeChunk(&frame, "VP8L", bitstream)
+	writeChunk(&a.buf, "ANMF", frame.Bytes())
+}
+
+// Bytes returns the encoded image, once every frame has been added.
+func (a *Animation) Bytes() []byte {
+	data := a.buf.Bytes()
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	return data
+}
+
+// writeChunk writes a RIFF chunk to buf, padded to an even size.
+func writeChunk(buf *bytes.Buffer, fourCC string, data []byte) {
+	buf.WriteString(fourCC)
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+	buf.Write(data)
+
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// putUint24 stores v into b as a 24-bit little-endian integer.
+func putUint24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testImage returns an image of the given size, filled with noise, gradients or
+// flat areas depending on kind, to exercise every prefix code and predictor.
+func testImage(width, height, kind int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	rng := rand.New(rand.NewSource(int64(width*height + kind)))
+
+	for y := range height {
+		for x := range width {
+			var c color.NRGBA
+
+			switch kind {
+			case 0:
+				c = color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+			case 1:
+				c = color.NRGBA{uint8(x * 3), uint8(y * 2), uint8(x + y), 0xff}
+			default:
+				c = color.NRGBA{uint8(x / 10 * 40), 0, 0x80, 0xff}
+			}
+
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func TestEncode(t *testing.T) {
+	for _, size := range []image.Point{{1, 1}, {3, 5}, {17, 33}, {200, 150}} {
+		for kind := range 3 {
+			t.Run(fmt.Sprintf("%dx%d/%d", size.X, size.Y, kind), func(t *testing.T) {
+				img := testImage(size.X, size.Y, kind)
+
+				var buf bytes.Buffer
+				if err := Encode(&buf, img); err != nil {
+					t.Fatalf("Encode() error = %v", err)
+				}
+
+				decoded, err := webp.Decode(&buf)
+				if err != nil {
+					t.Fatalf("failed to decode the encoded image: %v", err)
+				}
+
+				assertSameImage(t, decoded, img)
+			})
+		}
+	}
+}
+
+func TestAnimation(t *testing.T) {
+	frames := []*image.NRGBA{testImage(40, 30, 1), testImage(40, 30, 2)}
+
+	animation := NewAnimation(40, 30, false)
+	for i, frame := range frames {
+		animation.AddFrame(frame, 100*(i+1))
+	}
+
+	data := animation.Bytes()
+
+	if size := binary.LittleEndian.Uint32(data[4:]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(data)-8)
+	}
+
+	var decoded int
+
+	// Decode each frame as a still image, as animations can't be decoded
+	for rest := data[12:]; len(rest) > 0; {
+		fourCC, size := string(rest[:4]), int(binary.LittleEndian.Uint32(rest[4:]))
+
+		if fourCC == "ANMF" {
+			if duration := int(rest[20]) | int(rest[21])<<8 | int(rest[22])<<16; duration != 100*(decoded+1) {
+				t.Errorf("frame %d duration = %d", decoded, duration)
+			}
+
+			var still bytes.Buffer
+
+			still.WriteString("RIFF")
+			still.Write(binary.LittleEndian.AppendUint32(nil, uint32(4+size-16)))
+			still.WriteString("WEBP")
+			still.Write(rest[8+16 : 8+size])
+
+			img, err := webp.Decode(&still)
+			if err != nil {
+				t.Fatalf("failed to decode frame %d: %v", decoded, err)
+			}
+
+			assertSameImage(t, img, frames[decoded])
+
+			decoded++
+		}
+
+		rest = rest[8+size+size%2:]
+	}
+
+	if decoded != len(frames) {
+		t.Errorf("decoded %d frames, want %d", decoded, len(frames))
+	}
+}
+
+func assertSameImage(t *testing.T, got image.Image, want *image.NRGBA) {
+	t.Helper()
+
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+	}
+
+	for y := range want.Rect.Dy() {
+		for x := range want.Rect.Dx() {
+			if c := color.NRGBAModel.Convert(got.At(x, y)); c != want.NRGBAAt(x, y) {
+				t.Fatalf("pixel at %d,%d = %v, want %v", x, y, c, want.NRGBAAt(x, y))
+			}
+		}
+	}
+}
@@ -4,7 +4,10 @@
 /*
 Package main is a crawler to find i18n function calls in Go files.
 
-Matches the pattern: i18n.$FUNC("$MSG", $...ARGS)
+Matches the patterns: i18n.$FUNC("$MSG", $...ARGS) and
+i18n.$FUNCContext($CTX, "$MSG", $...ARGS)
+
+Messages of i18n.MessageContext are marked as ICU MessageFormat.
 */
 package main
 
@@ -18,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 // Match represents an i18n function call found in the code.
@@ -26,6 +30,7 @@ type Match struct {
 	Msg    string `json:"msg"`
 	Line   int    `json:"line"`
 	Offset int    `json:"offset"`
+	ICU    bool   `json:"icu,omitempty"` // Whether Msg is in ICU MessageFormat
 }
 
 func main() {
@@ -93,10 +98,15 @@ func extractI18nCall(n ast.Node, fset *token.FileSet, filePath string) *Match {
 	if id, ok := sel.X.(*ast.Ident); !ok || id.Name != "i18n" {
 		return nil
 	}
-	if len(call.Args) == 0 {
+	// Functions translating against the locale of a context take it first
+	msgArg := 0
+	if strings.HasSuffix(sel.Sel.Name, "Context") {
+		msgArg = 1
+	}
+	if len(call.Args) <= msgArg {
 		return nil
 	}
-	lit, ok := call.Args[0].(*ast.BasicLit)
+	lit, ok := call.Args[msgArg].(*ast.BasicLit)
 	if !ok || lit.Kind != token.STRING {
 		return nil
 	}
@@ -105,5 +115,5 @@ func extractI18nCall(n ast.Node, fset *token.FileSet, filePath string) *Match {
 		return nil
 	}
 	pos := fset.Position(call.Lparen)
-	return &Match{File: filePath, Msg: msg, Line: pos.Line, Offset: pos.Offset}
+	return &Match{File: filePath, Msg: msg, Line: pos.Line, Offset: pos.Offset, ICU: sel.Sel.Name == "MessageContext"}
 }
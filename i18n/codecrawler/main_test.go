@@ -47,6 +47,9 @@ func example() {
 	i18n.Plural("count message", 5)
 	notI18n.T("should not match")
 	i18n.T(variable) // should not match - not a string literal
+	i18n.TrContext(ctx, "context message")
+	i18n.TrContext(ctx, variable) // should not match - not a string literal
+	i18n.MessageContext(ctx, "{count, plural, one {# work} other {# works}}", args)
 }
 `
 
@@ -62,9 +65,12 @@ func example() {
 	expectedMatches := []struct {
 		msg  string
 		line int
+		icu  bool
 	}{
-		{"test message", 6},
-		{"count message", 7},
+		{"test message", 6, false},
+		{"count message", 7, false},
+		{"context message", 10, false},
+		{"{count, plural, one {# work} other {# works}}", 12, true},
 	}
 
 	if len(matches) != len(expectedMatches) {
@@ -78,6 +84,9 @@ func example() {
 		if matches[i].Line != expected.line {
 			t.Errorf("Match %d: expected line %d, got %d", i, expected.line, matches[i].Line)
 		}
+		if matches[i].ICU != expected.icu {
+			t.Errorf("Match %d: expected ICU %v, got %v", i, expected.icu, matches[i].ICU)
+		}
 	}
 }
 
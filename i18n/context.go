@@ -0,0 +1,80 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+This file contains functions for carrying a locale in a context, as well as
+functions for translating user-facing errors, formatted strings and messages
+against it.
+
+The locale is set for each request by middleware.SetLocale. It's kept
+here rather than in requestcontext, as requestcontext depends on this package.
+*/
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// localeKeyType defines a unique type for the locale key.
+type localeKeyType struct{}
+
+// localeKey is the key that the locale is stored under in a context.Context.
+var localeKey = localeKeyType{}
+
+// WithLocale returns a copy of ctx that carries locale, for translations made
+// with it to resolve against.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFrom returns the locale carried by ctx, or BaseLocale if it carries none.
+func LocaleFrom(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey).(string); ok && locale != "" {
+		return locale
+	}
+
+	return BaseLocale
+}
+
+// ErrorContext returns an error with text translated to the locale of ctx.
+func ErrorContext(ctx context.Context, text string) error {
+	text = lookupSkipStack2(LocaleFrom(ctx), text)
+
+	return errors.New(text) //nolint:err113 // Intentionally creates dynamic errors.
+}
+
+// ErrorfContext returns an error formatted from format translated to the locale of ctx.
+func ErrorfContext(ctx context.Context, format string, a ...any) error {
+	format = lookupSkipStack2(LocaleFrom(ctx), format)
+
+	return fmt.Errorf(format, a...) //nolint:err113 // Intentionally creates dynamic errors.
+}
+
+// SprintfContext formats a string from format translated to the locale of ctx.
+func SprintfContext(ctx context.Context, format string, a ...any) string {
+	format = lookupSkipStack2(LocaleFrom(ctx), format)
+
+	return fmt.Sprintf(format, a...)
+}
+
+// TrContext returns a translation of text to the locale of ctx.
+func TrContext(ctx context.Context, text string) string {
+	return lookupSkipStack2(LocaleFrom(ctx), text)
+}
+
+// MessageContext formats the MessageFormat message translated to the locale of
+// ctx, with args.
+//
+// The untranslated message is used if its translation isn't valid MessageFormat.
+func MessageContext(ctx context.Context, message string, args Args) string {
+	locale := LocaleFrom(ctx)
+
+	translated := lookupSkipStack2(locale, message)
+	if _, err := parseMessageCached(translated); err != nil {
+		translated = message
+	}
+
+	return FormatMessage(locale, translated, args)
+}
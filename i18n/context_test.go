@@ -0,0 +1,60 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package i18n
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestLocaleFrom(t *testing.T) {
+	if got := LocaleFrom(context.Background()); got != BaseLocale {
+		t.Errorf("LocaleFrom() without a locale = %q, want %q", got, BaseLocale)
+	}
+
+	if got := LocaleFrom(WithLocale(context.Background(), "")); got != BaseLocale {
+		t.Errorf("LocaleFrom() with an empty locale = %q, want %q", got, BaseLocale)
+	}
+
+	if got := LocaleFrom(WithLocale(context.Background(), "vi-VN")); got != "vi-VN" {
+		t.Errorf("LocaleFrom() = %q, want %q", got, "vi-VN")
+	}
+}
+
+func TestTrContext(t *testing.T) {
+	locales["test-A"] = map[string]string{SuccintID("i18n/context_test.go", "Hello"): "Hello A"}
+	locales["test-B"] = map[string]string{SuccintID("i18n/context_test.go", "Hello"): "Hello B"}
+
+	t.Cleanup(func() {
+		delete(locales, "test-A")
+		delete(locales, "test-B")
+	})
+
+	tests := map[string]string{
+		"test-A":   "Hello A",
+		"test-B":   "Hello B",
+		BaseLocale: "Hello",
+		"unknown":  "Hello",
+	}
+
+	// Translations in different locales made at the same time don't affect each other
+	var wg sync.WaitGroup
+
+	for locale, want := range tests {
+		for range 50 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if got := TrContext(WithLocale(context.Background(), locale), "Hello"); got != want {
+					t.Errorf("TrContext() in %s = %q, want %q", locale, got, want)
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+}
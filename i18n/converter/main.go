@@ -8,18 +8,50 @@ and outputs the result as JSON to stdout.
 
 Used to convert translation data into a format suitable for use
 by the i18n package.
+
+Messages marked as ICU MessageFormat by the crawlers are checked to be valid,
+as they're uploaded to Crowdin as-is.
+
+With -check, the tool instead reads a source translation file from stdin, such
+as i18n/locale/en/code.json, and checks that the ICU MessageFormat messages in
+the translation file given to -check, as downloaded from Crowdin, are valid and
+only use arguments of their source message.
 */
 package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"os"
+	"slices"
 
 	"codeberg.org/pixivfe/pixivfe/i18n"
 	"github.com/soluble-ai/go-jnode"
 )
 
 func main() {
+	check := flag.String("check", "", "translation file to check against the source translation file from stdin")
+	flag.Parse()
+
+	if *check != "" {
+		problems, err := checkTranslations(*check)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	root := &jnode.Node{}
 
 	err := json.NewDecoder(os.Stdin).Decode(root)
@@ -33,6 +65,14 @@ func main() {
 		o := root.Get(i)
 		msg := o.ToMap()["msg"].(string)
 		file := o.ToMap()["file"].(string)
+
+		if icu, _ := o.ToMap()["icu"].(bool); icu {
+			if _, err := i18n.MessageArgs(msg); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %q: %v\n", file, msg, err)
+				os.Exit(1)
+			}
+		}
+
 		translationMap[i18n.SuccintID(file, msg)] = msg
 	}
 
@@ -41,3 +81,52 @@ func main() {
 	encoder.SetIndent("", "  ")
 	encoder.Encode(translationMap)
 }
+
+// checkTranslations checks the translations in path against the source
+// translations from stdin, returning a description of each problem found.
+//
+// Only translations of source messages that are ICU MessageFormat with
+// arguments are checked.
+func checkTranslations(path string) ([]string, error) {
+	var source, translations map[string]string
+
+	if err := json.NewDecoder(os.Stdin).Decode(&source); err != nil {
+		return nil, fmt.Errorf("failed to decode source translations: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(&translations); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	var problems []string
+
+	for key, translation := range translations {
+		sourceArgs, err := i18n.MessageArgs(source[key])
+		if err != nil || len(sourceArgs) == 0 {
+			continue
+		}
+
+		args, err := i18n.MessageArgs(translation)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s: %v", path, key, err))
+
+			continue
+		}
+
+		for _, arg := range args {
+			if !slices.Contains(sourceArgs, arg) {
+				problems = append(problems, fmt.Sprintf("%s: %s: unknown argument %q", path, key, arg))
+			}
+		}
+	}
+
+	slices.Sort(problems)
+
+	return problems, nil
+}
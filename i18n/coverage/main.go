@@ -0,0 +1,239 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+This file provides a tool that reports the translation coverage of each locale
+in i18n/locale against the strings extracted by the crawlers.
+
+The output of codecrawler and templatecrawler is given to -code and -template,
+and compared to the code.json and template.json of each locale, by SuccintID:
+
+  - Missing IDs are extracted strings without a translation.
+  - Untranslated IDs are extracted strings translated as the source string, as
+    Crowdin fills in untranslated strings this way.
+  - Orphaned IDs are translations of strings that aren't extracted anymore.
+
+Coverage is the percentage of extracted strings that are translated. For the
+base locale, which holds the source strings, it's the percentage of extracted
+strings that are present, as the base locale is generated by ./build.sh i18n.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"codeberg.org/pixivfe/pixivfe/i18n"
+)
+
+// Report is the translation coverage of a locale.
+type Report struct {
+	Locale       string   `json:"locale"`
+	Total        int      `json:"total"`
+	Translated   int      `json:"translated"`
+	Coverage     float64  `json:"coverage"`
+	Missing      []string `json:"missing"`
+	Untranslated []string `json:"untranslated"`
+	Orphaned     []string `json:"orphaned"`
+}
+
+func main() {
+	codeStrings := flag.String("code", "i18n/code_strings.json", "output of codecrawler")
+	templateStrings := flag.String("template", "i18n/template_strings.json", "output of templatecrawler")
+	localeDir := flag.String("locales", "i18n/locale", "directory of the locales")
+	summary := flag.Bool("summary", false, "only report the coverage of each locale")
+	asJSON := flag.Bool("json", false, "output the reports as JSON")
+	flag.Parse()
+
+	reports, sources, err := run(*codeStrings, *templateStrings, *localeDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	printReports(os.Stdout, reports, sources, *summary)
+}
+
+// run reads the extracted strings and reports the coverage of each locale in
+// localeDir, returning the reports and the extracted strings by ID.
+func run(codeStrings, templateStrings, localeDir string) ([]Report, map[string]string, error) {
+	code, err := readExtracted(codeStrings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := readExtracted(templateStrings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(localeDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", localeDir, err)
+	}
+
+	var reports []Report
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		locale := entry.Name()
+		report := Report{Locale: locale}
+
+		for file, source := range map[string]map[string]string{"code.json": code, "template.json": template} {
+			translations, err := readTranslations(filepath.Join(localeDir, locale, file))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			report.add(compare(locale, source, translations))
+		}
+
+		report.finish()
+		reports = append(reports, report)
+	}
+
+	sources := maps.Clone(code)
+	maps.Copy(sources, template)
+
+	return reports, sources, nil
+}
+
+// compare reports the coverage of the translations of locale against source,
+// both keyed by SuccintID.
+func compare(locale string, source, translations map[string]string) Report {
+	report := Report{Locale: locale, Total: len(source)}
+
+	for id, msg := range source {
+		translation, exist := translations[id]
+
+		switch {
+		case !exist:
+			report.Missing = append(report.Missing, id)
+		case translation == msg && locale != i18n.BaseLocale:
+			report.Untranslated = append(report.Untranslated, id)
+		default:
+			report.Translated++
+		}
+	}
+
+	for id := range translations {
+		if _, exist := source[id]; !exist {
+			report.Orphaned = append(report.Orphaned, id)
+		}
+	}
+
+	report.finish()
+
+	return report
+}
+
+// add merges other into r.
+func (r *Report) add(other Report) {
+	r.Total += other.Total
+	r.Translated += other.Translated
+	r.Missing = append(r.Missing, other.Missing...)
+	r.Untranslated = append(r.Untranslated, other.Untranslated...)
+	r.Orphaned = append(r.Orphaned, other.Orphaned...)
+}
+
+// finish sorts the IDs of r and computes its coverage.
+func (r *Report) finish() {
+	slices.Sort(r.Missing)
+	slices.Sort(r.Untranslated)
+	slices.Sort(r.Orphaned)
+
+	r.Coverage = 100
+
+	if r.Total > 0 {
+		r.Coverage = float64(r.Translated) / float64(r.Total) * 100
+	}
+}
+
+func printReports(w io.Writer, reports []Report, sources map[string]string, summary bool) {
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s: %d/%d translated (%.1f%%), %d missing, %d untranslated, %d orphaned\n",
+			r.Locale, r.Translated, r.Total, r.Coverage, len(r.Missing), len(r.Untranslated), len(r.Orphaned))
+
+		if summary {
+			continue
+		}
+
+		for _, id := range r.Missing {
+			fmt.Fprintf(w, "  missing       %s %q\n", id, sources[id])
+		}
+
+		for _, id := range r.Untranslated {
+			fmt.Fprintf(w, "  untranslated  %s %q\n", id, sources[id])
+		}
+
+		for _, id := range r.Orphaned {
+			fmt.Fprintf(w, "  orphaned      %s\n", id)
+		}
+	}
+}
+
+// readExtracted reads the output of a crawler, returning the strings by ID.
+func readExtracted(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var matches []struct {
+		File string `json:"file"`
+		Msg  string `json:"msg"`
+	}
+
+	if err := json.NewDecoder(file).Decode(&matches); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	extracted := make(map[string]string, len(matches))
+	for _, match := range matches {
+		extracted[i18n.SuccintID(match.File, match.Msg)] = match.Msg
+	}
+
+	return extracted, nil
+}
+
+// readTranslations reads a translation file, treating a missing one as empty.
+func readTranslations(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var translations map[string]string
+
+	if err := json.NewDecoder(file).Decode(&translations); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return translations, nil
+}
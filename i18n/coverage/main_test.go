@@ -0,0 +1,54 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/i18n"
+)
+
+func TestCompare(t *testing.T) {
+	source := map[string]string{
+		"a.go:translated":   "Hello",
+		"a.go:translated2":  "Goodbye",
+		"a.go:missing":      "World",
+		"a.go:untranslated": "pixiv",
+	}
+
+	translations := map[string]string{
+		"a.go:translated":   "Xin chào",
+		"a.go:translated2":  "Tạm biệt",
+		"a.go:untranslated": "pixiv",
+		"b.go:orphaned":     "Chào",
+	}
+
+	report := compare("vi-VN", source, translations)
+
+	if report.Total != 4 || report.Translated != 2 {
+		t.Errorf("compare() translated %d/%d, want 2/4", report.Translated, report.Total)
+	}
+
+	if report.Coverage != 50 {
+		t.Errorf("compare() coverage = %v, want 50", report.Coverage)
+	}
+
+	if want := []string{"a.go:missing"}; !slices.Equal(report.Missing, want) {
+		t.Errorf("compare() missing = %v, want %v", report.Missing, want)
+	}
+
+	if want := []string{"a.go:untranslated"}; !slices.Equal(report.Untranslated, want) {
+		t.Errorf("compare() untranslated = %v, want %v", report.Untranslated, want)
+	}
+
+	if want := []string{"b.go:orphaned"}; !slices.Equal(report.Orphaned, want) {
+		t.Errorf("compare() orphaned = %v, want %v", report.Orphaned, want)
+	}
+
+	// The source strings of the base locale are expected to match
+	if base := compare(i18n.BaseLocale, source, source); base.Translated != len(source) || len(base.Untranslated) != 0 {
+		t.Errorf("compare() of the base locale translated %d/%d, want all", base.Translated, base.Total)
+	}
+}
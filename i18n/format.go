@@ -0,0 +1,274 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+This file formats dates and numbers according to the CLDR data of each locale.
+
+Patterns use a subset of the CLDR date field symbols: y (year), M (month
+number), MMMM (month name), d (day), EEEE (weekday name), H (24-hour hour),
+h (12-hour hour), mm (minute) and a (day period). Text in single quotes is
+copied as-is. Plural patterns use {0} for the number.
+*/
+package i18n
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// RelativeUnit is a unit of relative time, such as "5 minutes ago".
+type RelativeUnit int
+
+const (
+	Minute RelativeUnit = iota
+	Hour
+	Day
+	Week
+	Month
+	Year
+)
+
+// Plural holds the patterns of a message by CLDR plural category.
+//
+// plural.Other must always be set, as it's used for missing categories.
+type Plural map[plural.Form]string
+
+// Formats holds the CLDR formatting data of a locale.
+type Formats struct {
+	Tag        language.Tag
+	Months     [12]string
+	Weekdays   [7]string // Starting on Sunday, as with time.Weekday
+	DayPeriods [2]string // AM and PM
+
+	DateTime  string // Pattern of a full date and time
+	MonthYear string // Pattern of a month of a year
+	Time      string // Pattern of a time
+
+	JustNow   string
+	Yesterday string
+	At        string // Joins Yesterday to a time
+	Ago       string // Follows a relative time, if not part of it
+
+	Relative map[RelativeUnit]Plural
+	Ordinal  Plural
+}
+
+// formats holds Formats by locale.
+var formats = map[string]*Formats{
+	"en": {
+		Tag: language.English,
+		Months: [12]string{
+			"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December",
+		},
+		Weekdays:   [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		DayPeriods: [2]string{"AM", "PM"},
+		DateTime:   "EEEE, d MMMM y, 'at' h:mm a",
+		MonthYear:  "MMMM y",
+		Time:       "h:mm a",
+		JustNow:    "Just now",
+		Yesterday:  "Yesterday",
+		At:         "at",
+		Ago:        "ago",
+		Relative: map[RelativeUnit]Plural{
+			Minute: {plural.One: "{0} minute", plural.Other: "{0} minutes"},
+			Hour:   {plural.One: "{0} hour", plural.Other: "{0} hours"},
+			Day:    {plural.One: "{0} day", plural.Other: "{0} days"},
+			Week:   {plural.One: "{0} week", plural.Other: "{0} weeks"},
+			Month:  {plural.One: "{0} month", plural.Other: "{0} months"},
+			Year:   {plural.One: "{0} year", plural.Other: "{0} years"},
+		},
+		Ordinal: Plural{plural.One: "{0}st", plural.Two: "{0}nd", plural.Few: "{0}rd", plural.Other: "{0}th"},
+	},
+	"vi-VN": {
+		Tag: language.Vietnamese,
+		Months: [12]string{
+			"tháng 1", "tháng 2", "tháng 3", "tháng 4", "tháng 5", "tháng 6",
+			"tháng 7", "tháng 8", "tháng 9", "tháng 10", "tháng 11", "tháng 12",
+		},
+		Weekdays:   [7]string{"Chủ Nhật", "Thứ Hai", "Thứ Ba", "Thứ Tư", "Thứ Năm", "Thứ Sáu", "Thứ Bảy"},
+		DayPeriods: [2]string{"SA", "CH"},
+		DateTime:   "EEEE, d MMMM, y 'lúc' HH:mm",
+		MonthYear:  "MMMM 'năm' y",
+		Time:       "HH:mm",
+		JustNow:    "Vừa xong",
+		Yesterday:  "Hôm qua",
+		At:         "lúc",
+		Ago:        "trước",
+		Relative: map[RelativeUnit]Plural{
+			Minute: {plural.Other: "{0} phút"},
+			Hour:   {plural.Other: "{0} giờ"},
+			Day:    {plural.Other: "{0} ngày"},
+			Week:   {plural.Other: "{0} tuần"},
+			Month:  {plural.Other: "{0} tháng"},
+			Year:   {plural.Other: "{0} năm"},
+		},
+		Ordinal: Plural{plural.One: "thứ nhất", plural.Other: "thứ {0}"},
+	},
+	"zh-CN": {
+		Tag: language.SimplifiedChinese,
+		Months: [12]string{
+			"一月", "二月", "三月", "四月", "五月", "六月",
+			"七月", "八月", "九月", "十月", "十一月", "十二月",
+		},
+		Weekdays:   [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		DayPeriods: [2]string{"上午", "下午"},
+		DateTime:   "y年M月d日EEEE HH:mm",
+		MonthYear:  "y年M月",
+		Time:       "HH:mm",
+		JustNow:    "刚刚",
+		Yesterday:  "昨天",
+		Relative: map[RelativeUnit]Plural{
+			Minute: {plural.Other: "{0}分钟前"},
+			Hour:   {plural.Other: "{0}小时前"},
+			Day:    {plural.Other: "{0}天前"},
+			Week:   {plural.Other: "{0}周前"},
+			Month:  {plural.Other: "{0}个月前"},
+			Year:   {plural.Other: "{0}年前"},
+		},
+		Ordinal: Plural{plural.Other: "第{0}"},
+	},
+	"zh-TW": {
+		Tag: language.TraditionalChinese,
+		Months: [12]string{
+			"1月", "2月", "3月", "4月", "5月", "6月",
+			"7月", "8月", "9月", "10月", "11月", "12月",
+		},
+		Weekdays:   [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		DayPeriods: [2]string{"上午", "下午"},
+		DateTime:   "y年M月d日 EEEE ah:mm",
+		MonthYear:  "y年M月",
+		Time:       "ah:mm",
+		JustNow:    "剛剛",
+		Yesterday:  "昨天",
+		Relative: map[RelativeUnit]Plural{
+			Minute: {plural.Other: "{0} 分鐘前"},
+			Hour:   {plural.Other: "{0} 小時前"},
+			Day:    {plural.Other: "{0} 天前"},
+			Week:   {plural.Other: "{0} 週前"},
+			Month:  {plural.Other: "{0} 個月前"},
+			Year:   {plural.Other: "{0} 年前"},
+		},
+		Ordinal: Plural{plural.Other: "第{0}"},
+	},
+}
+
+// FormatsFor returns the Formats of locale, or those of BaseLocale if locale has none.
+func FormatsFor(locale string) *Formats {
+	if f, ok := formats[locale]; ok {
+		return f
+	}
+
+	return formats[BaseLocale]
+}
+
+// Number formats n with the digit grouping of the locale.
+func (f *Formats) Number(n int) string {
+	return message.NewPrinter(f.Tag).Sprintf("%d", n)
+}
+
+// Cardinal returns the pattern of p for the plural category of n, with the
+// number filled in.
+func (f *Formats) Cardinal(p Plural, n int) string {
+	return f.fill(p, plural.Cardinal, n)
+}
+
+// OrdinalNumber returns n as an ordinal number, such as "1st".
+func (f *Formats) OrdinalNumber(n int) string {
+	return f.fill(f.Ordinal, plural.Ordinal, n)
+}
+
+// fill selects the pattern of p for the plural category of n under rules, and
+// replaces {0} with n.
+func (f *Formats) fill(p Plural, rules *plural.Rules, n int) string {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	pattern, ok := p[rules.MatchPlural(f.Tag, abs, 0, 0, 0, 0)]
+	if !ok {
+		pattern = p[plural.Other]
+	}
+
+	return strings.ReplaceAll(pattern, "{0}", f.Number(n))
+}
+
+// Date formats t according to pattern.
+func (f *Formats) Date(t time.Time, pattern string) string {
+	var sb strings.Builder
+
+	runes := []rune(pattern)
+
+	for i := 0; i < len(runes); {
+		symbol := runes[i]
+
+		// Quoted text
+		if symbol == '\'' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+
+			sb.WriteString(string(runes[i+1 : end]))
+
+			i = end + 1
+
+			continue
+		}
+
+		count := 1
+		for i+count < len(runes) && runes[i+count] == symbol {
+			count++
+		}
+
+		i += count
+
+		switch symbol {
+		case 'y':
+			sb.WriteString(strconv.Itoa(t.Year()))
+		case 'M':
+			if count >= 4 {
+				sb.WriteString(f.Months[t.Month()-1])
+			} else {
+				sb.WriteString(pad(int(t.Month()), count))
+			}
+		case 'd':
+			sb.WriteString(pad(t.Day(), count))
+		case 'E':
+			sb.WriteString(f.Weekdays[t.Weekday()])
+		case 'H':
+			sb.WriteString(pad(t.Hour(), count))
+		case 'h':
+			hour := t.Hour() % 12
+			if hour == 0 {
+				hour = 12
+			}
+
+			sb.WriteString(pad(hour, count))
+		case 'm':
+			sb.WriteString(pad(t.Minute(), count))
+		case 'a':
+			sb.WriteString(f.DayPeriods[t.Hour()/12])
+		default:
+			sb.WriteString(strings.Repeat(string(symbol), count))
+		}
+	}
+
+	return sb.String()
+}
+
+// pad formats n with leading zeros up to width digits.
+func pad(n, width int) string {
+	s := strconv.Itoa(n)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+
+	return s
+}
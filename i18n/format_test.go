@@ -0,0 +1,119 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormats(t *testing.T) {
+	date := time.Date(2024, time.March, 4, 15, 4, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale   string
+		number   string
+		ordinals []string // Of 1, 2, 3, 4, 11 and 22
+		minutes  []string // Of 1 and 5
+		dateTime string
+	}{
+		{
+			locale:   "en",
+			number:   "-1,234,567",
+			ordinals: []string{"1st", "2nd", "3rd", "4th", "11th", "22nd"},
+			minutes:  []string{"1 minute", "5 minutes"},
+			dateTime: "Monday, 4 March 2024, at 3:04 PM",
+		},
+		{
+			locale:   "vi-VN",
+			number:   "-1.234.567",
+			ordinals: []string{"thứ nhất", "thứ 2", "thứ 3", "thứ 4", "thứ 11", "thứ 22"},
+			minutes:  []string{"1 phút", "5 phút"},
+			dateTime: "Thứ Hai, 4 tháng 3, 2024 lúc 15:04",
+		},
+		{
+			locale:   "zh-CN",
+			number:   "-1,234,567",
+			ordinals: []string{"第1", "第2", "第3", "第4", "第11", "第22"},
+			minutes:  []string{"1分钟前", "5分钟前"},
+			dateTime: "2024年3月4日星期一 15:04",
+		},
+		{
+			locale:   "zh-TW",
+			number:   "-1,234,567",
+			ordinals: []string{"第1", "第2", "第3", "第4", "第11", "第22"},
+			minutes:  []string{"1 分鐘前", "5 分鐘前"},
+			dateTime: "2024年3月4日 星期一 下午3:04",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			f := FormatsFor(tt.locale)
+
+			if got := f.Number(-1234567); got != tt.number {
+				t.Errorf("Number() = %q, want %q", got, tt.number)
+			}
+
+			for i, n := range []int{1, 2, 3, 4, 11, 22} {
+				if got := f.OrdinalNumber(n); got != tt.ordinals[i] {
+					t.Errorf("OrdinalNumber(%d) = %q, want %q", n, got, tt.ordinals[i])
+				}
+			}
+
+			for i, n := range []int{1, 5} {
+				if got := f.Cardinal(f.Relative[Minute], n); got != tt.minutes[i] {
+					t.Errorf("Cardinal(%d) = %q, want %q", n, got, tt.minutes[i])
+				}
+			}
+
+			if got := f.Date(date, f.DateTime); got != tt.dateTime {
+				t.Errorf("Date() = %q, want %q", got, tt.dateTime)
+			}
+		})
+	}
+}
+
+func TestFormatsForUnknownLocale(t *testing.T) {
+	if FormatsFor("xx") != FormatsFor(BaseLocale) {
+		t.Error("FormatsFor() of an unknown locale didn't return the formats of BaseLocale")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	t.Cleanup(func() { matcher = nil })
+
+	for _, locale := range []string{"vi-VN", "zh-CN", "zh-TW"} {
+		if _, exist := locales[locale]; !exist {
+			locales[locale] = map[string]string{}
+
+			t.Cleanup(func() { delete(locales, locale) })
+		}
+	}
+
+	if _, exist := locales[BaseLocale]; !exist {
+		locales[BaseLocale] = map[string]string{}
+
+		t.Cleanup(func() { delete(locales, BaseLocale) })
+	}
+
+	setupMatcher()
+
+	tests := map[string]string{
+		"":                    BaseLocale,
+		"vi":                  "vi-VN",
+		"zh-Hant":             "zh-TW",
+		"zh":                  "zh-CN",
+		"en-GB,en;q=0.9":      "en",
+		"ja,zh-CN;q=0.5":      "zh-CN",
+		"fr-FR,fr;q=0.5":      BaseLocale,
+		"not a language list": BaseLocale,
+	}
+
+	for acceptLanguage, want := range tests {
+		if got := Negotiate(acceptLanguage); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", acceptLanguage, got, want)
+		}
+	}
+}
@@ -2,9 +2,11 @@
 // SPDX-License-Identifier: AGPL-3.0-only
 
 /*
-This file contains global functions and variables
-for setting and retrieving the current locale, as well as
-functions for translating user-facing errors and formatted strings.
+This file contains the deprecated goroutine-local locale, and the functions
+that translate against it.
+
+Use the functions in context.go instead, which translate against the locale
+of the request that a context belongs to.
 */
 package i18n
 
@@ -17,8 +19,13 @@ import (
 
 var goroutineLocale = routine.NewInheritableThreadLocal[string]()
 
-const BaseLocale = "zh-CN"
+// BaseLocale is the locale of the strings in the source code and templates.
+const BaseLocale = "en"
 
+// GetLocale returns the locale of the current goroutine.
+//
+// Deprecated: Goroutines are reused across requests, so the locale may belong to
+// another request. Use LocaleFrom instead.
 func GetLocale() string {
 	locale := goroutineLocale.Get()
 	if locale == "" {
@@ -28,22 +35,28 @@ func GetLocale() string {
 	return locale
 }
 
+// SetLocale sets the locale of the current goroutine and the goroutines it starts.
+//
+// Deprecated: Use WithLocale instead.
 func SetLocale(locale string) {
 	goroutineLocale.Set(locale)
 }
 
+// Deprecated: Use ErrorContext instead.
 func Error(text string) error {
 	text = lookupSkipStack2(GetLocale(), text)
 
 	return errors.New(text) //nolint:err113 // Intentionally creates dynamic errors.
 }
 
+// Deprecated: Use ErrorfContext instead.
 func Errorf(format string, a ...any) error {
 	format = lookupSkipStack2(GetLocale(), format)
 
 	return fmt.Errorf(format, a...) //nolint:err113 // Intentionally creates dynamic errors.
 }
 
+// Deprecated: Use SprintfContext instead.
 func Sprintf(format string, a ...any) string {
 	format = lookupSkipStack2(GetLocale(), format)
 
@@ -51,6 +64,8 @@ func Sprintf(format string, a ...any) string {
 }
 
 // Tr returns a translation for the provided string.
+//
+// Deprecated: Use TrContext instead.
 func Tr(text string) string {
 	return lookupSkipStack2(GetLocale(), text)
 }
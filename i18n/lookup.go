@@ -16,6 +16,7 @@ import (
 	"maps"
 	"path"
 	"runtime"
+	"strings"
 
 	"codeberg.org/pixivfe/pixivfe/server/assets"
 	"github.com/zeebo/xxh3"
@@ -30,6 +31,14 @@ const (
 
 var locales = map[string]map[string]string{}
 
+// sourceRoot is the root directory of the module, which is trimmed from the files
+// of callers to match the relative paths that translations are keyed by.
+var sourceRoot = func() string {
+	_, file, _, _ := runtime.Caller(0)
+
+	return path.Dir(path.Dir(file)) + "/"
+}()
+
 func Setup() error {
 	fsI18n, err := fs.Sub(assets.FS, "i18n/locale")
 	if err != nil {
@@ -54,9 +63,18 @@ func Setup() error {
 		log.Printf("Loaded locale %s", entry.Name())
 	}
 
+	setupMatcher()
+
 	return nil
 }
 
+// HasLocale reports whether translations for locale were loaded by Setup.
+func HasLocale(locale string) bool {
+	_, exist := locales[locale]
+
+	return exist
+}
+
 func loadLocale(fsI18n fs.FS, locale string) (map[string]string, error) {
 	codeTranslations, err := loadLocaleHelper(fsI18n, locale, "code.json")
 	if err != nil {
@@ -115,8 +133,12 @@ func lookupSkipStack2(locale string, text string) string {
 		return text
 	}
 
-	translation, exist := translationMap[SuccintID(file, text)]
+	id := SuccintID(strings.TrimPrefix(file, sourceRoot), text)
+
+	translation, exist := translationMap[id]
 	if !exist {
+		reportMiss(locale, id, text)
+
 		return text
 	}
 
@@ -0,0 +1,474 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+This file implements the subset of ICU MessageFormat used by translations:
+
+  - {name} inserts an argument.
+  - {name, number} inserts a number argument with the digit grouping of the locale.
+  - {name, plural, [offset:N] =N {...} one {...} other {...}} selects a message
+    by the plural category of a number argument, with # replaced by the number.
+  - {name, selectordinal, one {...} other {...}} does the same by ordinal category.
+  - {name, select, value {...} other {...}} selects a message by the value of
+    an argument.
+
+An apostrophe followed by {, } or # (in plural messages) quotes text up to the
+next apostrophe, and two apostrophes make one.
+*/
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Args holds the arguments of a message by name.
+type Args map[string]any
+
+// ErrInvalidMessage is returned for messages that aren't valid MessageFormat.
+var ErrInvalidMessage = errors.New("invalid message format")
+
+// messageParts are the parts of a parsed message.
+type messageParts []messagePart
+
+// messagePart is literal text or an argument of a message.
+type messagePart struct {
+	text  string // Literal text, if arg is empty
+	pound bool   // Whether the part is # in a plural message
+
+	arg    string
+	kind   string                  // "", "number", "plural", "selectordinal" or "select"
+	offset int                     // Offset of plural arguments
+	cases  map[string]messageParts // Messages by selector, for plural and select arguments
+}
+
+// parsedMessages caches the parsed messages by source text.
+var parsedMessages sync.Map
+
+// FormatMessage formats message with args in locale, without translating it.
+//
+// message is returned as-is if it isn't valid MessageFormat.
+func FormatMessage(locale, message string, args Args) string {
+	parts, err := parseMessageCached(message)
+	if err != nil {
+		return message
+	}
+
+	var sb strings.Builder
+
+	parts.format(&sb, messageLocale(locale), args, nil)
+
+	return sb.String()
+}
+
+// MessageArgs returns the names of the arguments of message, sorted and without
+// duplicates, or ErrInvalidMessage if message isn't valid MessageFormat.
+func MessageArgs(message string) ([]string, error) {
+	parts, err := parseMessageCached(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	parts.args(&names)
+	slices.Sort(names)
+
+	return slices.Compact(names), nil
+}
+
+// messageLocale returns the formats and language of locale.
+func messageLocale(locale string) messageContext {
+	return messageContext{
+		formats: FormatsFor(locale),
+		tag:     language.Make(locale),
+	}
+}
+
+type messageContext struct {
+	formats *Formats
+	tag     language.Tag
+}
+
+func parseMessageCached(message string) (messageParts, error) {
+	if v, ok := parsedMessages.Load(message); ok {
+		return v.(messageParts), nil
+	}
+
+	p := &messageParser{src: []rune(message)}
+
+	parts, err := p.parse(false)
+	if err == nil && p.pos < len(p.src) {
+		err = p.errorf("unexpected %q", p.src[p.pos])
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	parsedMessages.Store(message, parts)
+
+	return parts, nil
+}
+
+// format writes parts to sb, with # replaced by number if it isn't nil.
+func (parts messageParts) format(sb *strings.Builder, mc messageContext, args Args, number *int) {
+	for _, part := range parts {
+		if part.pound && number != nil {
+			sb.WriteString(mc.formats.Number(*number))
+
+			continue
+		}
+
+		if part.arg == "" {
+			sb.WriteString(part.text)
+
+			continue
+		}
+
+		value, ok := args[part.arg]
+		if !ok {
+			sb.WriteString("{" + part.arg + "}")
+
+			continue
+		}
+
+		switch part.kind {
+		case "":
+			fmt.Fprint(sb, value)
+		case "number":
+			if n, ok := toInt(value); ok {
+				sb.WriteString(mc.formats.Number(n))
+			} else {
+				fmt.Fprint(sb, value)
+			}
+		case "plural", "selectordinal":
+			n, _ := toInt(value)
+			rules := plural.Cardinal
+
+			if part.kind == "selectordinal" {
+				rules = plural.Ordinal
+			}
+
+			sub, ok := part.cases["="+strconv.Itoa(n)]
+			if !ok {
+				sub = part.cases[pluralKeyword(rules, mc.tag, n-part.offset)]
+			}
+
+			if sub == nil {
+				sub = part.cases["other"]
+			}
+
+			shown := n - part.offset
+			sub.format(sb, mc, args, &shown)
+		case "select":
+			sub, ok := part.cases[fmt.Sprint(value)]
+			if !ok {
+				sub = part.cases["other"]
+			}
+
+			sub.format(sb, mc, args, number)
+		}
+	}
+}
+
+// args appends the names of the arguments of parts to names.
+func (parts messageParts) args(names *[]string) {
+	for _, part := range parts {
+		if part.arg == "" {
+			continue
+		}
+
+		*names = append(*names, part.arg)
+
+		for _, sub := range part.cases {
+			sub.args(names)
+		}
+	}
+}
+
+// pluralKeyword returns the keyword of the plural category of n in tag.
+func pluralKeyword(rules *plural.Rules, tag language.Tag, n int) string {
+	if n < 0 {
+		n = -n
+	}
+
+	switch rules.MatchPlural(tag, n, 0, 0, 0, 0) {
+	case plural.Zero:
+		return "zero"
+	case plural.One:
+		return "one"
+	case plural.Two:
+		return "two"
+	case plural.Few:
+		return "few"
+	case plural.Many:
+		return "many"
+	default:
+		return "other"
+	}
+}
+
+// toInt converts integer and floating-point values to an int.
+func toInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case uint:
+		return int(v), true
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case float32:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+// messageParser parses a message from src.
+type messageParser struct {
+	src []rune
+	pos int
+}
+
+func (p *messageParser) errorf(format string, a ...any) error {
+	return fmt.Errorf("%w at %d: %s", ErrInvalidMessage, p.pos, fmt.Sprintf(format, a...))
+}
+
+// parse parses a message up to the end of src, or the } closing it if nested.
+//
+// # is kept as a part of its own in plural messages, including those of select
+// arguments nested in them, for format to replace.
+func (p *messageParser) parse(inPlural bool) (messageParts, error) {
+	var (
+		parts messageParts
+		text  strings.Builder
+	)
+
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, messagePart{text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+
+		switch {
+		case r == '\'':
+			p.pos++
+			p.quoted(&text, inPlural)
+		case r == '{':
+			flush()
+
+			part, err := p.argument(inPlural)
+			if err != nil {
+				return nil, err
+			}
+
+			parts = append(parts, part)
+		case r == '}':
+			flush()
+
+			return parts, nil
+		case r == '#' && inPlural:
+			flush()
+
+			parts = append(parts, messagePart{text: "#", pound: true})
+			p.pos++
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+
+	flush()
+
+	return parts, nil
+}
+
+// quoted writes the text quoted by the apostrophe before p.pos to text.
+func (p *messageParser) quoted(text *strings.Builder, inPlural bool) {
+	if p.pos >= len(p.src) {
+		text.WriteRune('\'')
+
+		return
+	}
+
+	switch r := p.src[p.pos]; {
+	case r == '\'':
+		text.WriteRune('\'')
+		p.pos++
+	case r == '{' || r == '}' || (r == '#' && inPlural):
+		for p.pos < len(p.src) {
+			if p.src[p.pos] == '\'' {
+				if p.pos+1 < len(p.src) && p.src[p.pos+1] == '\'' {
+					text.WriteRune('\'')
+					p.pos += 2
+
+					continue
+				}
+
+				p.pos++
+
+				return
+			}
+
+			text.WriteRune(p.src[p.pos])
+			p.pos++
+		}
+	default:
+		text.WriteRune('\'')
+	}
+}
+
+// argument parses an argument starting at the { at p.pos, in a plural message
+// if inPlural is true.
+func (p *messageParser) argument(inPlural bool) (messagePart, error) {
+	p.pos++
+
+	part := messagePart{arg: p.word()}
+	if part.arg == "" {
+		return part, p.errorf("missing argument name")
+	}
+
+	if p.consume('}') {
+		return part, nil
+	}
+
+	if !p.consume(',') {
+		return part, p.errorf("expected , or } after argument %s", part.arg)
+	}
+
+	part.kind = p.word()
+
+	switch part.kind {
+	case "number":
+		// Styles aren't supported, so numbers are always formatted the same
+		if p.consume(',') {
+			p.word()
+		}
+
+		if !p.consume('}') {
+			return part, p.errorf("expected } after number argument %s", part.arg)
+		}
+
+		return part, nil
+	case "plural", "selectordinal", "select":
+		if !p.consume(',') {
+			return part, p.errorf("expected , after %s argument %s", part.kind, part.arg)
+		}
+	default:
+		return part, p.errorf("unknown argument type %q", part.kind)
+	}
+
+	part.cases = map[string]messageParts{}
+
+	for {
+		p.skipSpace()
+
+		if p.consume('}') {
+			break
+		}
+
+		selector := p.word()
+		if selector == "" {
+			return part, p.errorf("missing selector in argument %s", part.arg)
+		}
+
+		if part.kind != "select" {
+			if offset, found := strings.CutPrefix(selector, "offset:"); found {
+				n, err := strconv.Atoi(offset)
+				if err != nil {
+					return part, p.errorf("invalid offset %q", offset)
+				}
+
+				part.offset = n
+
+				continue
+			}
+		}
+
+		if !p.consume('{') {
+			return part, p.errorf("expected { after selector %s", selector)
+		}
+
+		sub, err := p.parse(inPlural || part.kind != "select")
+		if err != nil {
+			return part, err
+		}
+
+		if !p.consume('}') {
+			return part, p.errorf("unterminated message for selector %s", selector)
+		}
+
+		part.cases[selector] = sub
+	}
+
+	if _, ok := part.cases["other"]; !ok {
+		return part, p.errorf("missing other selector in argument %s", part.arg)
+	}
+
+	return part, nil
+}
+
+// word skips whitespace and returns the following run of characters that
+// aren't whitespace or syntax.
+func (p *messageParser) word() string {
+	p.skipSpace()
+
+	start := p.pos
+	for p.pos < len(p.src) && !unicode.IsSpace(p.src[p.pos]) && !strings.ContainsRune("{},", p.src[p.pos]) {
+		p.pos++
+	}
+
+	return string(p.src[start:p.pos])
+}
+
+// consume skips whitespace and reports whether r follows, skipping it if so.
+func (p *messageParser) consume(r rune) bool {
+	p.skipSpace()
+
+	if p.pos < len(p.src) && p.src[p.pos] == r {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *messageParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
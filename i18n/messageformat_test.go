@@ -0,0 +1,90 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package i18n
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFormatMessage(t *testing.T) {
+	const works = "{count, plural, =0 {No works} one {# work} other {# works}}"
+
+	tests := []struct {
+		locale  string
+		message string
+		args    Args
+		want    string
+	}{
+		{"en", works, Args{"count": 0}, "No works"},
+		{"en", works, Args{"count": 1}, "1 work"},
+		{"en", works, Args{"count": 1234}, "1,234 works"},
+		{"vi-VN", "{count, plural, other {# tác phẩm}}", Args{"count": 1234}, "1.234 tác phẩm"},
+		{"zh-CN", "{count, plural, other {#件作品}}", Args{"count": 1}, "1件作品"},
+		{"en", "{count, plural, offset:1 one {You} other {You and # others}}", Args{"count": 3}, "You and 2 others"},
+		{"en", "{rank, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}", Args{"rank": 22}, "22nd"},
+		{"en", "{type, select, novel {Novel} other {Artwork}}", Args{"type": "novel"}, "Novel"},
+		{"en", "{type, select, novel {Novel} other {Artwork}}", Args{"type": "manga"}, "Artwork"},
+		{"en", "{type, select, novel {# novels} other {{n, plural, other {# artworks}}}}", Args{"type": "x", "n": 2}, "2 artworks"},
+		{"en", "Hello {name}, you have {count, number} points", Args{"name": "Alice", "count": 10000}, "Hello Alice, you have 10,000 points"},
+		{"en", "It''s '{literal}' and {missing}", nil, "It's {literal} and {missing}"},
+		{"en", "{count, plural, other {'#' is #}}", Args{"count": 5}, "# is 5"},
+		{"en", "{count, plural, one {unterminated}", Args{"count": 1}, "{count, plural, one {unterminated}"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatMessage(tt.locale, tt.message, tt.args); got != tt.want {
+			t.Errorf("FormatMessage(%q, %q, %v) = %q, want %q", tt.locale, tt.message, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestMessageArgs(t *testing.T) {
+	args, err := MessageArgs("{a} {b, plural, one {{c}} other {{a}}} {d, select, x {} other {}}")
+	if err != nil {
+		t.Fatalf("MessageArgs() error = %v", err)
+	}
+
+	if want := []string{"a", "b", "c", "d"}; !slices.Equal(args, want) {
+		t.Errorf("MessageArgs() = %v, want %v", args, want)
+	}
+
+	for _, message := range []string{
+		"{}",
+		"{count, plural, one {#}}",
+		"{count, plural, one {#} other {#}",
+		"{count, unknown}",
+		"unmatched }",
+	} {
+		if _, err := MessageArgs(message); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("MessageArgs(%q) error = %v, want ErrInvalidMessage", message, err)
+		}
+	}
+}
+
+func TestMessageContext(t *testing.T) {
+	const message = "{count, plural, one {# work} other {# works}}"
+
+	locales["test-A"] = map[string]string{SuccintID("i18n/messageformat_test.go", message): "{count, plural, other {# A}}"}
+	locales["test-B"] = map[string]string{SuccintID("i18n/messageformat_test.go", message): "{count, plural, other {# B}"}
+
+	t.Cleanup(func() {
+		delete(locales, "test-A")
+		delete(locales, "test-B")
+	})
+
+	tests := map[string]string{
+		"test-A":   "2 A",
+		"test-B":   "2 works", // Invalid translations fall back to the source message
+		BaseLocale: "2 works",
+	}
+
+	for locale, want := range tests {
+		if got := MessageContext(WithLocale(context.Background(), locale), message, Args{"count": 2}); got != want {
+			t.Errorf("MessageContext() in %s = %q, want %q", locale, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+This file reports strings without a translation in the locale they're looked
+up in, so that translation work can be prioritised by what users see.
+*/
+package i18n
+
+import "sync"
+
+// MissHandler is called with the locale, ID and source text of a string
+// without a translation in the locale.
+type MissHandler func(locale, id, text string)
+
+var (
+	missHandler    MissHandler
+	reportedMisses sync.Map // set of missKey already passed to missHandler
+)
+
+type missKey struct {
+	locale string
+	id     string
+}
+
+// SetMissHandler sets the function that misses are reported to, once per
+// locale and ID. A nil handler disables reporting, which is the default.
+//
+// It must be called before translations are looked up, usually right after
+// Setup.
+func SetMissHandler(handler MissHandler) {
+	missHandler = handler
+	reportedMisses.Clear()
+}
+
+// reportMiss reports that id has no translation in locale, unless it was
+// reported before.
+//
+// Misses of the base locale and of locales that weren't loaded are ignored,
+// as there's nothing to translate in them.
+func reportMiss(locale, id, text string) {
+	if missHandler == nil || locale == BaseLocale || !HasLocale(locale) {
+		return
+	}
+
+	if _, reported := reportedMisses.LoadOrStore(missKey{locale, id}, struct{}{}); reported {
+		return
+	}
+
+	missHandler(locale, id, text)
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package i18n
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMissHandler(t *testing.T) {
+	var misses []string
+
+	SetMissHandler(func(locale, id, text string) {
+		misses = append(misses, locale+" "+id+" "+text)
+	})
+
+	locales["test-A"] = map[string]string{
+		SuccintID("i18n/miss_test.go", "Translated"):  "Translated A",
+		SuccintID("miss_test.jet.html", "Translated"): "Translated A",
+	}
+
+	_, hasBase := locales[BaseLocale]
+	if !hasBase {
+		locales[BaseLocale] = map[string]string{}
+	}
+
+	locales[BaseLocale][SuccintID("miss_test.jet.html", "Translated")] = "Translated"
+	locales[BaseLocale][SuccintID("miss_test.jet.html", "Untranslated")] = "Untranslated"
+
+	t.Cleanup(func() {
+		SetMissHandler(nil)
+		delete(locales, "test-A")
+		delete(locales[BaseLocale], SuccintID("miss_test.jet.html", "Translated"))
+		delete(locales[BaseLocale], SuccintID("miss_test.jet.html", "Untranslated"))
+
+		if !hasBase {
+			delete(locales, BaseLocale)
+		}
+
+		tmMu.Lock()
+		delete(tm, cacheKey{locale: "test-A", file: "miss_test.jet.html"})
+		tmMu.Unlock()
+	})
+
+	for _, locale := range []string{"test-A", BaseLocale, "unknown"} {
+		ctx := WithLocale(context.Background(), locale)
+
+		// Misses are only reported once
+		for range 2 {
+			TrContext(ctx, "Translated")
+			TrContext(ctx, "Untranslated")
+		}
+	}
+
+	Replacer("test-A", "miss_test.jet.html")
+
+	want := []string{
+		"test-A " + SuccintID("i18n/miss_test.go", "Untranslated") + " Untranslated",
+		"test-A " + SuccintID("miss_test.jet.html", "Untranslated") + " Untranslated",
+	}
+
+	if !slices.Equal(misses, want) {
+		t.Errorf("reported misses = %q, want %q", misses, want)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+This file negotiates the locale of a request from its Accept-Language header.
+*/
+package i18n
+
+import (
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+var (
+	// matcher matches language tags against the loaded locales.
+	matcher language.Matcher
+	// matcherLocales are the loaded locales, in the order given to matcher.
+	matcherLocales []string
+)
+
+// setupMatcher creates matcher from the loaded locales, with BaseLocale first
+// as the fallback.
+func setupMatcher() {
+	matcherLocales = matcherLocales[:0]
+
+	for locale := range locales {
+		if locale != BaseLocale {
+			matcherLocales = append(matcherLocales, locale)
+		}
+	}
+
+	slices.Sort(matcherLocales)
+	matcherLocales = slices.Insert(matcherLocales, 0, BaseLocale)
+
+	tags := make([]language.Tag, 0, len(matcherLocales))
+
+	for _, locale := range matcherLocales {
+		tags = append(tags, language.Make(locale))
+	}
+
+	matcher = language.NewMatcher(tags)
+}
+
+// Negotiate returns the loaded locale that best matches acceptLanguage, the
+// value of an Accept-Language header.
+//
+// BaseLocale is returned if no locale matches.
+func Negotiate(acceptLanguage string) string {
+	if matcher == nil || acceptLanguage == "" {
+		return BaseLocale
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return BaseLocale
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return BaseLocale
+	}
+
+	return matcherLocales[index]
+}
@@ -9,9 +9,13 @@ package i18n
 import (
 	"slices"
 	"strings"
+	"sync"
 )
 
-var tm = map[cacheKey]*strings.Replacer{}
+var (
+	tm   = map[cacheKey]*strings.Replacer{}
+	tmMu sync.Mutex // guards tm, as templates of different locales are loaded concurrently
+)
 
 // format: old0, new0, old1, new1, ...
 type TrPairs = []string
@@ -43,6 +47,9 @@ func Replacer(locale string, file string) *strings.Replacer {
 
 	k := cacheKey{locale: locale, file: file}
 
+	tmMu.Lock()
+	defer tmMu.Unlock()
+
 	v, exist := tm[k]
 	if exist {
 		return v
@@ -76,6 +83,13 @@ func translationPairsInner(locale string, file string) TrPairs {
 		}
 	}
 
+	// Replacers are cached, so each miss is only found once
+	for k, v := range fromMap {
+		if _, exist := toMap[k]; !exist && strings.HasPrefix(k, file+":") {
+			reportMiss(locale, k, v)
+		}
+	}
+
 	// sort by length. longest first.
 	// this is to prevent weird stuff when rewriting multiple strings and
 	// a short one is substring of a long one.
@@ -7,6 +7,8 @@ This file implements a crawler for extracting translatable strings from HTML tem
 It scans HTML files in the assets/views directory,
 extracts text content while preserving some HTML structure,
 and outputs the results as JSON.
+
+The messages of message("...") calls are extracted as ICU MessageFormat.
 */
 package main
 
@@ -22,6 +24,7 @@ type Match struct {
 	File   string `json:"file"`
 	Msg    string `json:"msg"`
 	Offset int    `json:"offset"`
+	ICU    bool   `json:"icu,omitempty"` // Whether Msg is in ICU MessageFormat
 }
 
 func main() {
@@ -74,6 +77,10 @@ func processFile(filename string, result *[]Match) {
 		}
 		// }
 	})
+
+	findMessageCalls(filename, content2, func(m Match) {
+		*result = append(*result, m)
+	})
 }
 
 // shouldIgnore is a manual filter.
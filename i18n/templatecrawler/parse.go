@@ -19,6 +19,8 @@ var (
 	htmlCommentRegex = regexp.MustCompile(`<!--[\s\S]*?-->`)
 	// Regex to match template control structures that should be removed
 	templateControlRegex = regexp.MustCompile(`\{\{\s*(?:range|end|if|else|block|extends|include|yield)\b[\s\S]*?\}\}`)
+	// Regex to match the quoted message of message() calls in templates
+	messageCallRegex = regexp.MustCompile(`\bmessage\(\s*"([^"\\]*)"`)
 )
 
 // removeTemplateControlStructures removes template control flow but preserves variables in text
@@ -66,6 +68,21 @@ func findAllSimple(filename string, content string, onMatch func(match Match)) {
 	}
 }
 
+// findMessageCalls extracts the messages of message() calls in content.
+//
+// Messages are extracted as they're written in the template, without unquoting,
+// for them to be replaced as-is when templates are localized.
+func findMessageCalls(filename string, content string, onMatch func(match Match)) {
+	for _, loc := range messageCallRegex.FindAllStringSubmatchIndex(content, -1) {
+		onMatch(Match{
+			File:   filename,
+			Msg:    content[loc[2]:loc[3]],
+			Offset: loc[2],
+			ICU:    true,
+		})
+	}
+}
+
 // looksLikeTemplateOrComment checks if the text looks like template syntax or comments
 func looksLikeTemplateOrComment(text string) bool {
 	trimmed := strings.TrimSpace(text)
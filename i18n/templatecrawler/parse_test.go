@@ -164,3 +164,33 @@ func TestLooksLikeTemplateOrComment(t *testing.T) {
 		})
 	}
 }
+
+func TestFindMessageCalls(t *testing.T) {
+	content := `<span>{{ message("{count, plural, one {work} other {works}}", map("count", .Total)) }}</span>
+<p>{{ message( "Hello {name}", map("name", .Name)) }} {{ notmessage("x") }} {{ message("Said \"{name}\"") }}</p>`
+
+	var matches []Match
+	findMessageCalls("test.html", content, func(m Match) {
+		matches = append(matches, m)
+	})
+
+	expected := []string{
+		"{count, plural, one {work} other {works}}",
+		"Hello {name}",
+	}
+
+	if len(matches) != len(expected) {
+		t.Fatalf("findMessageCalls() found %d matches, want %d", len(matches), len(expected))
+	}
+
+	for i, msg := range expected {
+		if matches[i].Msg != msg || !matches[i].ICU {
+			t.Errorf("findMessageCalls() match[%d] = {Msg: %q, ICU: %v}, want {Msg: %q, ICU: true}",
+				i, matches[i].Msg, matches[i].ICU, msg)
+		}
+
+		if got := content[matches[i].Offset : matches[i].Offset+len(msg)]; got != msg {
+			t.Errorf("findMessageCalls() match[%d] offset points to %q", i, got)
+		}
+	}
+}
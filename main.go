@@ -23,6 +23,7 @@ import (
 	"codeberg.org/pixivfe/pixivfe/server/assets"
 	"codeberg.org/pixivfe/pixivfe/server/middleware"
 	"codeberg.org/pixivfe/pixivfe/server/middleware/limiter"
+	"codeberg.org/pixivfe/pixivfe/server/proxychecker"
 	"codeberg.org/pixivfe/pixivfe/server/router"
 	"codeberg.org/pixivfe/pixivfe/server/template"
 )
@@ -57,12 +58,30 @@ func main() {
 	audit.Setup(&config.GlobalConfig)
 	audit.GlobalAuditor.Logger.Info("Auditor initialized")
 
+	shutdownTracing, err := audit.SetupTracing(&config.GlobalConfig)
+	if err != nil {
+		audit.GlobalAuditor.Logger.Errorf("Failed to initialize tracing: %v", err)
+	} else if config.GlobalConfig.Tracing.Exporter != config.NoneTracingExporter {
+		audit.GlobalAuditor.Logger.Infow("Tracing initialized",
+			"exporter", config.GlobalConfig.Tracing.Exporter,
+			"sampleRatio", config.GlobalConfig.Tracing.SampleRatio)
+	}
+
 	if err := i18n.Setup(); err != nil {
 		audit.GlobalAuditor.Logger.Errorf("Failed to initialize i18n engine: %v", err)
 	}
 
 	audit.GlobalAuditor.Logger.Info("i18n engine initialized")
 
+	if config.GlobalConfig.Log.TranslationMisses {
+		i18n.SetMissHandler(func(locale, id, text string) {
+			audit.GlobalAuditor.Logger.Infow("Missing translation",
+				"locale", locale,
+				"id", id,
+				"text", text)
+		})
+	}
+
 	template.Setup(config.GlobalConfig.Development.InDevelopment)
 
 	if err := template.LoadIcons("assets/icons"); err != nil {
@@ -73,20 +92,19 @@ func main() {
 	requests.Setup()
 	audit.GlobalAuditor.Logger.Info("API response cache initialized.")
 
-	audit.GlobalAuditor.Logger.Info("Starting server...")
+	if err := requests.SetupFixtures(); err != nil {
+		audit.GlobalAuditor.Logger.Panicf("Failed to set up upstream fixtures: %v", err)
+	} else if config.GlobalConfig.Development.UpstreamMode != config.NetworkUpstreamMode {
+		audit.GlobalAuditor.Logger.Infow("Upstream fixtures enabled",
+			"mode", config.GlobalConfig.Development.UpstreamMode,
+			"path", config.GlobalConfig.Development.FixturePath)
+	}
 
-	router := router.DefineRoutes()
-	// the first middleware is the most outer / first executed one
-	router.Use(middleware.WithRequestContext)  // needed for everything else
-	router.Use(middleware.SetLocaleFromCookie) // needed for i18n.*()
-	router.Use(middleware.SetResponseHeaders)  // all pages need this
-	router.Use(middleware.HandleError)         // if the inner handler fails, this shows the error page instead
+	proxychecker.Setup()
 
-	// Limiter setup
-	if config.GlobalConfig.Limiter.Enabled {
-		limiter.Setup()
-		router.Use(limiter.Evaluate)
-	}
+	audit.GlobalAuditor.Logger.Info("Starting server...")
+
+	handler := newHandler()
 
 	// watch and compile tailwind css when in development mode
 	if config.GlobalConfig.Development.InDevelopment {
@@ -95,7 +113,7 @@ func main() {
 
 	// Create http.Server instance
 	server := &http.Server{
-		Handler:           router,
+		Handler:           handler,
 		ReadHeaderTimeout: readHeaderTimeout,
 		ReadTimeout:       readTimeout,
 		WriteTimeout:      writeTimeout,
@@ -112,6 +130,9 @@ func main() {
 		}
 	}()
 
+	// Reload the configuration on SIGHUP
+	go reloadOnSignal()
+
 	// Set up graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -126,9 +147,63 @@ func main() {
 		audit.GlobalAuditor.Logger.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	if err := config.GlobalConfig.TokenManager.TokenManager.SaveState(); err != nil {
+		audit.GlobalAuditor.Logger.Errorf("Failed to save token state: %v", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		audit.GlobalAuditor.Logger.Errorf("Failed to flush pending traces: %v", err)
+	}
+
 	audit.GlobalAuditor.Logger.Info("Server exiting")
 }
 
+// newHandler returns the router serving every route, wrapped in the middleware
+// shared by all of them.
+func newHandler() http.Handler {
+	router := router.DefineRoutes()
+	// the first middleware is the most outer / first executed one
+	router.Use(middleware.WithRequestContext) // needed for everything else
+	router.Use(middleware.SetLocale)          // needed for localization
+	router.Use(middleware.SetResponseHeaders) // all pages need this
+	router.Use(middleware.HandleError)        // if the inner handler fails, this shows the error page instead
+
+	// Limiter setup
+	if config.GlobalConfig.Limiter.Enabled {
+		limiter.Setup()
+		router.Use(limiter.Evaluate)
+	}
+
+	return router
+}
+
+// reloadOnSignal reloads the configuration whenever the process receives SIGHUP.
+func reloadOnSignal() {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	for range reload {
+		audit.GlobalAuditor.Logger.Info("Reloading configuration...")
+
+		result, err := config.GlobalConfig.Reload()
+		if err != nil {
+			audit.GlobalAuditor.Logger.Errorf("Failed to reload configuration: %v", err)
+
+			continue
+		}
+
+		audit.GlobalAuditor.Logger.Infow("Reloaded token pool",
+			"added", result.TokensAdded,
+			"removed", result.TokensRemoved)
+
+		for _, section := range result.RestartRequired {
+			audit.GlobalAuditor.Logger.Warnf("Changes to the %s configuration require a restart to take effect", section)
+		}
+
+		audit.GlobalAuditor.Logger.Info("Configuration reloaded")
+	}
+}
+
 func chooseListener() net.Listener {
 	var listener net.Listener
 
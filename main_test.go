@@ -0,0 +1,138 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/template"
+)
+
+// TestRoutesReplay serves routes from the upstream responses in testdata/fixtures,
+// so that they can be tested without a pixiv token or network access.
+//
+// The fixtures are synthetic: they're written by hand in the format produced by
+// PIXIVFE_UPSTREAM_MODE=record, with minimal bodies and made-up IDs such as 901 and 902,
+// rather than recorded from pixiv. Requests without a fixture, such as for artwork 999, fail.
+func TestRoutesReplay(t *testing.T) {
+	t.Setenv("PIXIVFE_CONFIGFILE", t.TempDir()+"/config.yml")
+	t.Setenv("PIXIVFE_TOKEN", "123456_replay")
+	t.Setenv("PIXIVFE_UPSTREAM_MODE", config.ReplayUpstreamMode)
+	t.Setenv("PIXIVFE_FIXTURE_PATH", "testdata/fixtures")
+	t.Setenv("PIXIVFE_LOG_OUTPUTS", "stderr")
+	t.Setenv("PIXIVFE_LOG_LEVEL", "warn")
+
+	if err := config.GlobalConfig.LoadConfig(); err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+
+	audit.Setup(&config.GlobalConfig)
+
+	if err := i18n.Setup(); err != nil {
+		t.Fatalf("failed to initialize i18n engine: %v", err)
+	}
+
+	template.Setup(false)
+
+	if err := template.LoadIcons("assets/icons"); err != nil {
+		t.Fatalf("failed to load icons: %v", err)
+	}
+
+	requests.Setup()
+
+	if err := requests.SetupFixtures(); err != nil {
+		t.Fatalf("failed to set up upstream fixtures: %v", err)
+	}
+
+	server := httptest.NewServer(newHandler())
+	defer server.Close()
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{"/newest", http.StatusOK, "text/html", "/artworks/123"},
+		{"/feed/newest.atom.xml", http.StatusOK, "application/atom+xml", "/artworks/123"},
+		{"/artworks/901", http.StatusOK, "text/html", "Second"},
+		{"/artworks/902", http.StatusOK, "text/html", "/ugoira/902"},
+		{"/novel/123", http.StatusOK, "text/html", "吾輩は猫である"},
+		{"/proxy/i.pximg.net/img/55.png", http.StatusOK, "image/png", ""},
+		{"/artworks/999", http.StatusInternalServerError, "text/html", ""}, // No fixture recorded
+		{"/api/v2/artworks/abc", http.StatusBadRequest, "application/json", `"status":400`},
+		{"/feed/ranking.atom.xml?page=abc", http.StatusBadRequest, "", "Invalid page number"},
+		{"/feed/self/followingWorks.atom.xml", http.StatusUnauthorized, "", "A feed key is required"},
+		{"/feed/self/followingWorks.atom.xml?key=invalid", http.StatusForbidden, "", "The feed key is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %s", contentType, tt.contentType)
+			}
+
+			if !strings.Contains(string(body), tt.contains) {
+				t.Errorf("body doesn't contain %q", tt.contains)
+			}
+		})
+	}
+
+	// Feeds served before they moved under /feed
+	redirects := []struct {
+		path     string
+		location string
+	}{
+		{"/users/123.atom.xml", "/feed/users/123.atom.xml"},
+		{"/users/123/illustrations.atom.xml?page=2", "/feed/users/123/illustrations.atom.xml?page=2"},
+		{"/tags/%E7%8C%AB.atom.xml?mode=safe", "/feed/tags/%E7%8C%AB.atom.xml?mode=safe"},
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, tt := range redirects {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := client.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusMovedPermanently {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusMovedPermanently)
+			}
+
+			if location := resp.Header.Get("Location"); location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"codeberg.org/pixivfe/pixivfe/server/session"
+)
+
+// RequireAdminToken is a middleware that restricts a handler to requests
+// bearing the admin token configured in Admin.Token.
+//
+// The token is expected in an "Authorization: Bearer <token>" header.
+// GET and HEAD requests are also accepted with the admin session cookie set
+// by the /admin/login page, which is never accepted for requests that change
+// state. Requests without a matching token are rejected with a 401 status,
+// as are all requests if no admin token is configured.
+func RequireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r) {
+			ctx := requestcontext.FromRequest(r)
+			ctx.RequestError = i18n.ErrorContext(r.Context(), "Invalid or missing admin token")
+			ctx.StatusCode = http.StatusUnauthorized
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// RequireAdminLogin is like RequireAdminToken, but for pages viewed in a
+// browser: requests without a matching token are redirected to /admin/login.
+func RequireAdminLogin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r) {
+			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// isAdmin reports whether r bears the admin token, or the admin session cookie
+// for GET and HEAD requests.
+func isAdmin(r *http.Request) bool {
+	adminToken := config.GlobalConfig.Admin.Token
+	if adminToken == "" {
+		return false
+	}
+
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	return session.VerifyAdminSession(session.GetCookie(r, session.Cookie_AdminSession), adminToken)
+}
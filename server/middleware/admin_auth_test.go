@@ -0,0 +1,52 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/server/session"
+)
+
+func TestIsAdmin(t *testing.T) {
+	config.GlobalConfig.Admin.Token = "secret"
+	t.Cleanup(func() { config.GlobalConfig.Admin.Token = "" })
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		cookie string
+		want   bool
+	}{
+		{"Bearer token", http.MethodPost, "Bearer secret", "", true},
+		{"Wrong Bearer token", http.MethodGet, "Bearer wrong", session.NewAdminSession("secret"), false},
+		{"Session cookie", http.MethodGet, "", session.NewAdminSession("secret"), true},
+		{"Session cookie on HEAD", http.MethodHead, "", session.NewAdminSession("secret"), true},
+		{"Session cookie on POST", http.MethodPost, "", session.NewAdminSession("secret"), false},
+		{"Raw token as cookie", http.MethodGet, "", "secret", false},
+		{"Wrong session cookie", http.MethodGet, "", session.NewAdminSession("wrong"), false},
+		{"Nothing", http.MethodGet, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/admin/tokens", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: string(session.Cookie_AdminSession), Value: tt.cookie})
+			}
+
+			if got := isAdmin(r); got != tt.want {
+				t.Errorf("isAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
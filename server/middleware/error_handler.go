@@ -4,32 +4,37 @@
 package middleware
 
 import (
-	"maps"
+	"errors"
 	"net/http"
-	"net/http/httptest"
 	"time"
 
 	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
 	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
 	"codeberg.org/pixivfe/pixivfe/server/routes"
 	"codeberg.org/pixivfe/pixivfe/server/utils"
+	"github.com/gorilla/mux"
 )
 
 // CatchError is a middleware that wraps HTTP handlers that return an error.
 //
-// It buffers the response using a httptest.ResponseRecorder. If the handler returns
+// It buffers the response using a responseBuffer. If the handler returns
 // an error, the buffered response is discarded and the error is stored into the request
 // context. Otherwise, the buffered response is copied to the real ResponseWriter.
 //
 // This pattern ensures that nothing is written to the client until we know the handler
 // succeeded. It also avoids the complexity of manually backing up and restoring headers.
+//
+// Handlers that stream their response with utils.StreamResponse, such as downloads
+// too large to buffer, bypass this. An error returned after streaming started is still
+// stored, for HandleError to log it and abort the response.
 func CatchError(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Create a recorder to capture the handler's output.
-		recorder := httptest.NewRecorder()
+		// Create a buffer to capture the handler's output.
+		buffer := newResponseBuffer(w)
 
 		// Execute the handler, capturing any error.
-		if err := handler(recorder, r); err != nil {
+		if err := handler(buffer, r); err != nil {
 			// On error, store it in the request context.
 			requestcontext.FromRequest(r).RequestError = err
 			// (Do not flush the buffered response to the client.)
@@ -37,10 +42,7 @@ func CatchError(handler func(w http.ResponseWriter, r *http.Request) error) http
 		}
 
 		// If no error occurred, copy the buffered response headers, code, and body.
-		maps.Copy(w.Header(), recorder.Header())
-		w.WriteHeader(recorder.Code)
-
-		if _, err := recorder.Body.WriteTo(w); err != nil {
+		if err := buffer.flush(); err != nil {
 			audit.GlobalAuditor.Logger.Errorln("Failed to write response body:", err)
 		}
 	}
@@ -48,7 +50,7 @@ func CatchError(handler func(w http.ResponseWriter, r *http.Request) error) http
 
 // HandleError is a middleware that wraps an http.Handler.
 //
-// It handles both error processing and request logging. It uses httptest.NewRecorder
+// It handles both error processing and request logging. It uses a responseBuffer
 // to capture the response, handles any errors by rendering an error page, and logs
 // all application responses via package audit.
 //
@@ -58,42 +60,43 @@ func CatchError(handler func(w http.ResponseWriter, r *http.Request) error) http
 //
 // The HTTP status code for the error page is taken from
 // requestcontext.FromRequest(r).StatusCode if it's already an error code (>=400);
-// otherwise, it defaults to http.StatusInternalServerError.
+// otherwise, it's derived from the error via errorStatusCode.
 //
-// If no RequestError is set, the buffered response from the recorder is copied
-// to the actual ResponseWriter.
+// If no RequestError is set, the buffered response is copied to the actual
+// ResponseWriter. Responses streamed with utils.StreamResponse are only logged,
+// and if they failed, the connection is aborted as the response is incomplete.
 func HandleError(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Start timing and create recorder to capture response
+		// Start timing and create buffer to capture response
 		start := time.Now()
-		recorder := httptest.NewRecorder()
+		buffer := newResponseBuffer(w)
 
 		// Execute the wrapped handler (e.g., router dispatch, CatchError, NotFoundHandler)
-		// with the recorder.
-		next.ServeHTTP(recorder, r)
+		// with the buffer.
+		next.ServeHTTP(buffer, r)
 
 		ctx := requestcontext.FromRequest(r)
 
-		// Check if an error was signaled in the RequestContext.
-		if ctx.RequestError != nil {
+		switch {
+		case buffer.streamed:
+			// The response was already sent, so an error can only be logged.
+			ctx.StatusCode = buffer.Code
+		case ctx.RequestError != nil:
 			// An error occurred. Determine the correct status code.
 			// If the StatusCode in context is not already an error code (e.g., it's still 200 OK
-			// despite RequestError being set), then it's an unhandled internal error.
+			// despite RequestError being set), derive it from the error.
 			if ctx.StatusCode < http.StatusBadRequest {
-				ctx.StatusCode = http.StatusInternalServerError
+				ctx.StatusCode = errorStatusCode(ctx.RequestError)
 			}
-			// Write the determined status code and render the generic error page.
-			w.WriteHeader(ctx.StatusCode)
+			// Render the generic error page, which writes the determined status code.
 			routes.ErrorPage(w, r) // ErrorPage uses ctx.RequestError and ctx.StatusCode
-		} else {
+		default:
 			// No error was signaled in RequestContext.
-			// Assume the handler executed successfully and wrote its response to the recorder.
+			// Assume the handler executed successfully and wrote its response to the buffer.
 			// Copy the buffered response (headers, status code, body) to the real ResponseWriter.
-			ctx.StatusCode = recorder.Code // Ensure ctx.StatusCode reflects the actual written code for logging.
-			maps.Copy(w.Header(), recorder.Header())
-			w.WriteHeader(recorder.Code)
+			ctx.StatusCode = buffer.Code // Ensure ctx.StatusCode reflects the actual written code for logging.
 
-			if _, err := recorder.Body.WriteTo(w); err != nil {
+			if err := buffer.flush(); err != nil {
 				audit.GlobalAuditor.Logger.Errorln("Failed to write response body:", err)
 			}
 		}
@@ -104,6 +107,7 @@ func HandleError(next http.Handler) http.Handler {
 		if !audit.ShouldSkipServerLogging(r.URL.Path) {
 			span := audit.Span{
 				Component:  audit.ComponentServer,
+				Route:      routeTemplate(r),
 				Duration:   time.Since(start),
 				RequestID:  ctx.RequestID,
 				Method:     r.Method,
@@ -114,5 +118,44 @@ func HandleError(next http.Handler) http.Handler {
 
 			audit.GlobalAuditor.LogAndRecord(span)
 		}
+
+		// Abort a streamed response that failed partway, so that the client doesn't
+		// mistake what was sent for the complete response.
+		if buffer.streamed && ctx.RequestError != nil {
+			panic(http.ErrAbortHandler)
+		}
 	})
 }
+
+// errorStatusCode returns the HTTP status code to respond with for err.
+//
+// Not found and forbidden responses from the pixiv API are passed through,
+// while any other error is treated as an internal server error.
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, requests.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, requests.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// routeTemplate returns the path template of the route matched for r, e.g. "/artworks/{id}".
+//
+// Returns an empty string if the matched route has no path template,
+// such as the handler for non-existent routes.
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+
+	return template
+}
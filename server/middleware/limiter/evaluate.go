@@ -34,6 +34,7 @@ var excludedPaths = []string{
 	"/img/",
 	"/js/",
 	"/manifest.json",
+	"/metrics",
 	"/robots.txt",
 }
 
@@ -88,6 +89,8 @@ func Evaluate(next http.Handler) http.Handler {
 				"ip", client.ip.String(),
 				"network", client.network.String())
 
+			audit.RecordLimiterBlock("blocklist")
+
 			// requestcontext.FromRequest(r).StatusCode = http.StatusUnauthorized
 			w.WriteHeader(http.StatusUnauthorized)
 
@@ -112,6 +115,8 @@ func Evaluate(next http.Handler) http.Handler {
 					"network", client.network.String(),
 					"reason", blockReason)
 
+				audit.RecordLimiterBlock("headers")
+
 				// requestcontext.FromRequest(r).StatusCode = http.StatusUnauthorized
 				w.WriteHeader(http.StatusUnauthorized)
 
@@ -162,6 +167,8 @@ func Evaluate(next http.Handler) http.Handler {
 				"reason", blockReason)
 			addRateLimitHeaders(w, client)
 
+			audit.RecordLimiterBlock("rate_limit")
+
 			// requestcontext.FromRequest(r).StatusCode = http.StatusTooManyRequests
 			w.WriteHeader(http.StatusTooManyRequests)
 
@@ -0,0 +1,67 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package middleware
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+)
+
+// responseBuffer buffers a response so that it can be discarded if the handler
+// fails, unless the handler streams it with utils.StreamResponse.
+type responseBuffer struct {
+	*httptest.ResponseRecorder
+
+	w        http.ResponseWriter // Writer that the response is eventually written to
+	streamed bool                // Whether the response was written to w directly
+}
+
+var _ utils.Streamer = (*responseBuffer)(nil)
+
+func newResponseBuffer(w http.ResponseWriter) *responseBuffer {
+	return &responseBuffer{
+		ResponseRecorder: httptest.NewRecorder(),
+		w:                w,
+	}
+}
+
+// Stream implements utils.Streamer.
+//
+// If w is itself buffered, such as when CatchError runs within HandleError, the
+// response is streamed by every buffer in between.
+func (b *responseBuffer) Stream() http.ResponseWriter {
+	if !b.streamed {
+		b.streamed = true
+		b.w = utils.StreamResponse(b.w)
+
+		maps.Copy(b.w.Header(), b.Header())
+	}
+
+	return b.w
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController to extend
+// its deadlines.
+func (b *responseBuffer) Unwrap() http.ResponseWriter {
+	return b.w
+}
+
+// flush copies the buffered headers, status code and body to the underlying writer.
+//
+// It does nothing if the response was streamed, as it was already written.
+func (b *responseBuffer) flush() error {
+	if b.streamed {
+		return nil
+	}
+
+	maps.Copy(b.w.Header(), b.Header())
+	b.w.WriteHeader(b.Code)
+
+	_, err := b.Body.WriteTo(b.w)
+
+	return err
+}
@@ -10,13 +10,19 @@ import (
 	"codeberg.org/pixivfe/pixivfe/server/session"
 )
 
-// SetLocaleFromCookie is a middleware that extracts the user's locale preference
-// from a cookie and sets it in the i18n package.
-func SetLocaleFromCookie(next http.Handler) http.Handler {
+// SetLocale is a middleware that determines the user's locale and stores it in
+// the request context, where translations and template renders for the request
+// resolve it from.
+//
+// The locale preference from the cookie takes precedence, otherwise the locale
+// is negotiated from the Accept-Language header.
+func SetLocale(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		locale := session.GetCookie(r, session.Cookie_Locale)
-		i18n.SetLocale(locale)
+		if locale == "" {
+			locale = i18n.Negotiate(r.Header.Get("Accept-Language"))
+		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
 	})
 }
@@ -6,17 +6,61 @@ package middleware
 import (
 	"net/http"
 
+	"codeberg.org/pixivfe/pixivfe/audit"
 	"codeberg.org/pixivfe/pixivfe/server/middleware/limiter"
 	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WithRequestContext is a middleware that attaches a RequestContext to each HTTP request.
+//
+// It also starts the root tracing span for the request, continuing any trace
+// propagated in the request headers. The span is carried by r.Context(),
+// so that spans for upstream requests made while handling the request become its children.
 func WithRequestContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+
+		spanName := r.Method
+		if route != "" {
+			spanName += " " + route
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := audit.Tracer().Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.HTTPRoute(route),
+			),
+		)
+		defer span.End()
+
 		// Create a new context with RequestContext attached
-		ctxWithRequest := requestcontext.WithRequestContext(r.Context(), r, limiter.GetOrCreateLinkToken)
+		ctxWithRequest := requestcontext.WithRequestContext(ctx, r, limiter.GetOrCreateLinkToken)
+
+		reqCtx := requestcontext.FromContext(ctxWithRequest)
+		span.SetAttributes(attribute.String("pixivfe.request_id", reqCtx.RequestID))
 
 		// Create new request with the enhanced context and pass to the next handler
 		next.ServeHTTP(w, r.WithContext(ctxWithRequest))
+
+		// The final status code is known once HandleError has run.
+		span.SetAttributes(semconv.HTTPResponseStatusCode(reqCtx.StatusCode))
+
+		if reqCtx.RequestError != nil {
+			span.RecordError(reqCtx.RequestError)
+		}
+
+		if reqCtx.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(reqCtx.StatusCode))
+		}
 	})
 }
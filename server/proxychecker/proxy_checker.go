@@ -0,0 +1,196 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Package proxychecker periodically checks the health of the image proxies in
+config.BuiltInImageProxyList, for the automatic image proxy to resolve to the
+fastest healthy one.
+
+Each proxy is probed by fetching a small image through it, as given by
+ProxyCheck.ProbePath in GlobalConfig.
+*/
+package proxychecker
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+)
+
+// Status is the outcome of the latest check of a proxy.
+type Status struct {
+	URL       string        // URL of the proxy, as listed in config.BuiltInImageProxyList
+	Healthy   bool          // Whether the probe image was served
+	Latency   time.Duration // Time taken to fetch the probe image
+	CheckedAt time.Time     // Time of the check
+	Error     string        // Reason the proxy is unhealthy
+}
+
+var (
+	mu       sync.RWMutex
+	statuses = map[string]Status{}
+)
+
+// Setup starts a goroutine that checks every proxy right away, then again every
+// ProxyCheck.Interval.
+//
+// It does nothing if ProxyCheck.Enabled is false in GlobalConfig.
+func Setup() {
+	if !config.GlobalConfig.ProxyCheck.Enabled {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(config.GlobalConfig.ProxyCheck.Interval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			CheckAll(context.Background())
+		}
+	}()
+
+	audit.GlobalAuditor.Logger.Infow("Image proxy health checker initialized",
+		"interval", config.GlobalConfig.ProxyCheck.Interval.String(),
+		"proxies", len(config.BuiltInImageProxyList))
+}
+
+// CheckAll checks every proxy concurrently and records their status.
+func CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	results := make([]Status, len(config.BuiltInImageProxyList))
+
+	for i, proxy := range config.BuiltInImageProxyList {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = check(ctx, proxy)
+		}()
+	}
+
+	wg.Wait()
+
+	healthy := 0
+
+	mu.Lock()
+
+	for _, status := range results {
+		statuses[status.URL] = status
+
+		if status.Healthy {
+			healthy++
+		}
+	}
+
+	mu.Unlock()
+
+	audit.GlobalAuditor.Logger.Debugw("Image proxies checked",
+		"healthy", healthy,
+		"total", len(results))
+}
+
+// check probes a single proxy.
+func check(ctx context.Context, proxy string) Status {
+	ctx, cancel := context.WithTimeout(ctx, config.GlobalConfig.ProxyCheck.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	status := Status{URL: proxy, CheckedAt: start}
+
+	err := probe(ctx, strings.TrimSuffix(proxy, "/")+config.GlobalConfig.ProxyCheck.ProbePath)
+	if err != nil {
+		status.Error = err.Error()
+
+		return status
+	}
+
+	status.Healthy = true
+	status.Latency = time.Since(start)
+
+	return status
+}
+
+// probe fetches the image at probeURL in full.
+func probe(ctx context.Context, probeURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := utils.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "image/") {
+		return fmt.Errorf("unexpected content type %q", contentType)
+	}
+
+	_, err = io.Copy(io.Discard, resp.Body)
+
+	return err
+}
+
+// Statuses returns the status of every proxy that was checked, in the order of
+// config.BuiltInImageProxyList.
+func Statuses() []Status {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Status, 0, len(statuses))
+
+	for _, proxy := range config.BuiltInImageProxyList {
+		if status, ok := statuses[proxy]; ok {
+			result = append(result, status)
+		}
+	}
+
+	return result
+}
+
+// StatusMap returns the status of every proxy that was checked, by URL.
+func StatusMap() map[string]Status {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return maps.Clone(statuses)
+}
+
+// Fastest returns the healthy proxy with the lowest latency, or the built-in
+// proxy if none is healthy.
+func Fastest() url.URL {
+	healthy := slices.DeleteFunc(Statuses(), func(status Status) bool {
+		return !status.Healthy
+	})
+
+	if len(healthy) > 0 {
+		fastest := slices.MinFunc(healthy, func(a, b Status) int {
+			return cmp.Compare(a.Latency, b.Latency)
+		})
+
+		if proxyURL, err := url.Parse(fastest.URL); err == nil {
+			return *proxyURL
+		}
+	}
+
+	return url.URL{Path: config.BuiltInImageProxyPath}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package proxychecker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+)
+
+// setupProxies replaces the proxy list with servers responding after the given
+// delays, where a negative delay makes the server respond with 404.
+func setupProxies(t *testing.T, delays ...time.Duration) []string {
+	t.Helper()
+
+	originalConfig := config.GlobalConfig
+	originalList := config.BuiltInImageProxyList
+
+	t.Cleanup(func() {
+		config.GlobalConfig = originalConfig
+		config.BuiltInImageProxyList = originalList
+
+		mu.Lock()
+		statuses = map[string]Status{}
+		mu.Unlock()
+	})
+
+	config.GlobalConfig.ProxyCheck.Timeout = time.Second
+	config.GlobalConfig.ProxyCheck.ProbePath = "/probe.png"
+
+	var proxies []string
+
+	for _, delay := range delays {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if delay < 0 || r.URL.Path != "/probe.png" {
+				http.NotFound(w, r)
+
+				return
+			}
+
+			time.Sleep(delay)
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		}))
+		t.Cleanup(server.Close)
+
+		proxies = append(proxies, server.URL)
+	}
+
+	config.BuiltInImageProxyList = proxies
+
+	return proxies
+}
+
+func TestFastest(t *testing.T) {
+	proxies := setupProxies(t, 100*time.Millisecond, -1, 0)
+
+	CheckAll(context.Background())
+
+	got := Statuses()
+	if len(got) != len(proxies) {
+		t.Fatalf("got %d statuses, want %d", len(got), len(proxies))
+	}
+
+	for i, wantHealthy := range []bool{true, false, true} {
+		if got[i].URL != proxies[i] || got[i].Healthy != wantHealthy {
+			t.Errorf("status %d = %+v, want %s with healthy %v", i, got[i], proxies[i], wantHealthy)
+		}
+	}
+
+	if fastest := Fastest(); fastest.String() != proxies[2] {
+		t.Errorf("Fastest() = %s, want %s", fastest.String(), proxies[2])
+	}
+}
+
+func TestFastestFallback(t *testing.T) {
+	setupProxies(t, -1, -1)
+
+	CheckAll(context.Background())
+
+	if fastest := Fastest(); fastest.String() != config.BuiltInImageProxyPath {
+		t.Errorf("Fastest() = %s, want the built-in proxy", fastest.String())
+	}
+}
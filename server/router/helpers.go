@@ -28,6 +28,28 @@ func handleStripPrefix(router *mux.Router, pathPrefix string, handler http.Handl
 	return router.PathPrefix(pathPrefix).Handler(http.StripPrefix(pathPrefix, handler))
 }
 
+// handleFeed registers handler for the feed of the page at path, under
+// routes.FeedPathPrefix, with one route for each supported feed format,
+// as selected by the suffix appended to path.
+func handleFeed(router *mux.Router, path string, handler http.HandlerFunc) {
+	for _, suffix := range routes.FeedSuffixes() {
+		router.HandleFunc(routes.FeedPathPrefix+path+suffix, handler).Methods("HEAD", "GET")
+	}
+}
+
+// legacyFeedRedirect redirects requests for a feed at the path it was served at
+// before feeds moved under routes.FeedPathPrefix, preserving the query string.
+func legacyFeedRedirect(w http.ResponseWriter, r *http.Request) {
+	target := *r.URL
+
+	target.Path = routes.FeedPathPrefix + r.URL.Path
+	if r.URL.RawPath != "" {
+		target.RawPath = routes.FeedPathPrefix + r.URL.RawPath
+	}
+
+	http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+}
+
 // hasTrailingSlash is a helper function to check for trailing slashes.
 func hasTrailingSlash(r *http.Request, _ *mux.RouteMatch) bool {
 	return r.URL.Path != "/" && strings.HasSuffix(r.URL.Path, "/")
@@ -39,6 +39,6 @@ func handleFollowPost(w http.ResponseWriter, r *http.Request) error {
 		return routes.FollowRoute(w, r)
 
 	default:
-		return i18n.Error("Invalid or missing action parameter")
+		return i18n.ErrorContext(r.Context(), "Invalid or missing action parameter")
 	}
 }
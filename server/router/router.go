@@ -80,10 +80,14 @@ func DefineRoutes() *mux.Router {
 	// can only reverse proxy the t-hk.ugoira.com domain directly (e.g. caddy)
 	handleStripPrefix(router, "/proxy/ugoira.com/ugoira/", middleware.CatchError(routes.UgoiraProxy)).Methods("HEAD", "GET")
 
+	// Built-in ugoira renderer, which users can select even when it isn't the default
+	router.HandleFunc(config.BuiltInUgoiraRendererPath+"/{id}.{format}", middleware.CatchError(routes.UgoiraRender)).Methods("HEAD", "GET")
+
 	// Main application routes
 	router.HandleFunc("/", middleware.CatchError(routes.IndexPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/about", middleware.CatchError(routes.AboutPage)).Methods("HEAD", "GET")
-	router.HandleFunc("/newest", middleware.CatchError(routes.NewestPage)).Methods("HEAD", "GET")
+	handleFeed(router, "/newest", middleware.CatchError(routes.NewestFeed))
+	router.HandleFunc("/newest", middleware.CatchError(routes.NegotiateFeed(routes.NewestPage, routes.NewestFeed))).Methods("HEAD", "GET")
 	router.HandleFunc("/discovery", middleware.CatchError(routes.DiscoveryPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/discovery/novel", middleware.CatchError(routes.NovelDiscoveryPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/discovery/users", middleware.CatchError(routes.UserDiscoveryPage)).Methods("HEAD", "GET")
@@ -94,28 +98,36 @@ func DefineRoutes() *mux.Router {
 	router.HandleFunc("/discovery/users", middleware.CatchError(routes.UserDiscoveryPageRefresh)).Methods("POST")
 
 	// Ranking routes
-	router.HandleFunc("/ranking", middleware.CatchError(routes.RankingPage)).Methods("HEAD", "GET")
+	handleFeed(router, "/ranking", middleware.CatchError(routes.RankingFeed))
+	router.HandleFunc("/ranking", middleware.CatchError(routes.NegotiateFeed(routes.Negotiate(routes.RankingPage, routes.RankingJSON), routes.RankingFeed))).Methods("HEAD", "GET")
 	router.HandleFunc("/rankingCalendar", middleware.CatchError(routes.RankingCalendarPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/rankingCalendar", middleware.CatchError(routes.RankingCalendarPicker)).Methods("POST")
 
 	// User routes
-	router.HandleFunc("/users/{id}.atom.xml", middleware.CatchError(routes.UserAtomFeed)).Methods("HEAD", "GET")
-	router.HandleFunc("/users/{id}/{category}.atom.xml", middleware.CatchError(routes.UserAtomFeed)).Methods("HEAD", "GET")
-	router.HandleFunc("/users/{id}", middleware.CatchError(routes.UserPage)).Methods("HEAD", "GET")
-	router.HandleFunc("/users/{id}/{category}", middleware.CatchError(routes.UserPage)).Methods("HEAD", "GET")
+	handleFeed(router, "/users/{id}", middleware.CatchError(routes.UserFeed))
+	router.HandleFunc("/users/{id}.atom.xml", legacyFeedRedirect).Methods("HEAD", "GET")
+	router.HandleFunc("/users/{id}/{category}.atom.xml", legacyFeedRedirect).Methods("HEAD", "GET")
+	handleFeed(router, "/users/{id}/{category}", middleware.CatchError(routes.UserFeed))
+	router.HandleFunc("/users/{id}", middleware.CatchError(routes.NegotiateFeed(routes.Negotiate(routes.UserPage, routes.UserJSON), routes.UserFeed))).Methods("HEAD", "GET")
+	router.HandleFunc("/users/{id}/{category}", middleware.CatchError(routes.NegotiateFeed(routes.Negotiate(routes.UserPage, routes.UserJSON), routes.UserFeed))).Methods("HEAD", "GET")
 	router.HandleFunc("/member.php", legacyRedirect("/users/", "id"))
 
 	// Artwork routes
-	router.HandleFunc("/artworks/{id}", middleware.CatchError(routes.ArtworkPage)).Methods("HEAD", "GET")
+	router.HandleFunc("/artworks/{id}", middleware.CatchError(routes.Negotiate(routes.ArtworkPage, routes.ArtworkJSON))).Methods("HEAD", "GET")
+	router.HandleFunc("/artworks/{id}/download.zip", middleware.CatchError(routes.ArtworkDownload)).Methods("HEAD", "GET")
+	router.HandleFunc("/artworks/{id}/download.cbz", middleware.CatchError(routes.ArtworkDownload)).Methods("HEAD", "GET")
 	router.HandleFunc("/artworks-multi/{ids}", middleware.CatchError(routes.ArtworkMultiPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/member_illust.php", legacyRedirect("/artworks/", "illust_id"))
 
 	// Manga routes
 	router.HandleFunc("/users/{user_id}/series/{series_id}", middleware.CatchError(routes.MangaSeriesPage)).Methods("HEAD", "GET")
+	router.HandleFunc("/users/{user_id}/series/{series_id}/download.cbz", middleware.CatchError(routes.MangaSeriesDownload)).Methods("HEAD", "GET")
 
 	// Novel routes
 	router.HandleFunc("/novel/show.php", legacyRedirect("/novel/", "id"))
-	router.HandleFunc("/novel/{id}", middleware.CatchError(routes.NovelPage)).Methods("HEAD", "GET")
+	router.HandleFunc("/novel/{id}.epub", middleware.CatchError(routes.NovelEPUB)).Methods("HEAD", "GET")
+	router.HandleFunc("/novel/{id}", middleware.CatchError(routes.Negotiate(routes.NovelPage, routes.NovelJSON))).Methods("HEAD", "GET")
+	router.HandleFunc("/novel/series/{id}.epub", middleware.CatchError(routes.NovelSeriesEPUB)).Methods("HEAD", "GET")
 	router.HandleFunc("/novel/series/{id}", middleware.CatchError(routes.NovelSeriesPage)).Methods("HEAD", "GET")
 
 	// Pixivision routes
@@ -131,7 +143,8 @@ func DefineRoutes() *mux.Router {
 	// User action routes
 	router.HandleFunc("/self", middleware.CatchError(routes.SelfUserPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/self/followingUsers", middleware.CatchError(routes.SelfFollowingUsersPage)).Methods("HEAD", "GET")
-	router.HandleFunc("/self/followingWorks", middleware.CatchError(routes.SelfFollowingWorksPage)).Methods("HEAD", "GET")
+	handleFeed(router, "/self/followingWorks", middleware.CatchError(routes.SelfFollowingWorksFeed))
+	router.HandleFunc("/self/followingWorks", middleware.CatchError(routes.NegotiateFeed(routes.SelfFollowingWorksPage, routes.SelfFollowingWorksFeed))).Methods("HEAD", "GET")
 	router.HandleFunc("/self/bookmarks", middleware.CatchError(routes.SelfBookmarksPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/self/addBookmark/{artwork_id}", middleware.CatchError(routes.AddBookmarkRoute)).Methods("POST")
 	router.HandleFunc("/self/deleteBookmark/{bookmark_id}", middleware.CatchError(routes.DeleteBookmarkRoute)).Methods("POST")
@@ -142,11 +155,24 @@ func DefineRoutes() *mux.Router {
 	router.HandleFunc("/oembed", middleware.CatchError(routes.Oembed)).Methods("HEAD", "GET")
 
 	// Tag routes
-	router.HandleFunc("/tags/{name}", middleware.CatchError(routes.TagPage)).Methods("HEAD", "GET")
+	handleFeed(router, "/tags/{name}", middleware.CatchError(routes.TagFeed))
+	// The tag page of a tag ending in .atom.xml is still served at /tags?name=...
+	router.HandleFunc("/tags/{name}.atom.xml", legacyFeedRedirect).Methods("HEAD", "GET")
+	router.HandleFunc("/tags/{name}", middleware.CatchError(routes.NegotiateFeed(routes.Negotiate(routes.TagPage, routes.TagJSON), routes.TagFeed))).Methods("HEAD", "GET")
 	router.HandleFunc("/tags/{name}/", middleware.CatchError(routes.TagPage)).Methods("POST")
 	router.HandleFunc("/tags", middleware.CatchError(routes.TagPage)).Methods("HEAD", "GET")
 	router.HandleFunc("/tags", middleware.CatchError(routes.AdvancedTagPost)).Methods("POST")
 
+	// JSON API routes
+	//
+	// The HTML routes above also serve these when requested with Accept: application/json
+	router.HandleFunc("/api/v2/artworks/{id}", middleware.CatchError(routes.ArtworkJSON)).Methods("HEAD", "GET")
+	router.HandleFunc("/api/v2/users/{id}", middleware.CatchError(routes.UserJSON)).Methods("HEAD", "GET")
+	router.HandleFunc("/api/v2/users/{id}/{category}", middleware.CatchError(routes.UserJSON)).Methods("HEAD", "GET")
+	router.HandleFunc("/api/v2/novel/{id}", middleware.CatchError(routes.NovelJSON)).Methods("HEAD", "GET")
+	router.HandleFunc("/api/v2/ranking", middleware.CatchError(routes.RankingJSON)).Methods("HEAD", "GET")
+	router.HandleFunc("/api/v2/tags/{name}", middleware.CatchError(routes.TagJSON)).Methods("HEAD", "GET")
+
 	// REST API routes (for htmx)
 	// safe methods
 	router.HandleFunc("/api/v1/artwork", middleware.CatchError(routes.ArtworkPartial)).Methods("HEAD", "GET")
@@ -167,6 +193,22 @@ func DefineRoutes() *mux.Router {
 		router.HandleFunc("/diagnostics/reset", routes.ResetDiagnosticsData)
 	}
 
+	// Metrics route
+	if config.GlobalConfig.Metrics.Enabled {
+		router.HandleFunc("/metrics", middleware.CatchError(routes.Metrics)).Methods("HEAD", "GET")
+	}
+
+	// Admin routes
+	if config.GlobalConfig.Admin.Token != "" {
+		router.HandleFunc("/admin/cache", middleware.RequireAdminToken(middleware.CatchError(routes.AdminCacheData))).Methods("HEAD", "GET")
+		router.HandleFunc("/admin/cache/purge", middleware.RequireAdminToken(middleware.CatchError(routes.AdminCachePurge))).Methods("POST")
+		router.HandleFunc("/admin/login", middleware.CatchError(routes.AdminLoginPage)).Methods("HEAD", "GET")
+		router.HandleFunc("/admin/login", middleware.CatchError(routes.AdminLogin)).Methods("POST")
+		router.HandleFunc("/admin/logout", middleware.CatchError(routes.AdminLogout)).Methods("POST")
+		router.HandleFunc("/admin/tokens", middleware.RequireAdminLogin(middleware.CatchError(routes.AdminTokens))).Methods("HEAD", "GET")
+		router.HandleFunc("/admin/tokens.json", middleware.RequireAdminToken(middleware.CatchError(routes.AdminTokensData))).Methods("HEAD", "GET")
+	}
+
 	// Link token route
 	if config.GlobalConfig.Limiter.DetectionMethod == config.LinkTokenDetectionMethod {
 		router.HandleFunc("/limiter/{token}.css", middleware.CatchError(limiter.LinkTokenHandler)).Methods("GET")
@@ -185,7 +227,7 @@ func DefineRoutes() *mux.Router {
 		// We don't write to ResponseWriter or call ErrorPage here
 		// as the HandleError middleware will render the appropriate error page with a 404 status.
 		ctx := requestcontext.FromRequest(r)
-		ctx.RequestError = i18n.Error("Route not found")
+		ctx.RequestError = i18n.ErrorContext(r.Context(), "Route not found")
 		ctx.StatusCode = http.StatusNotFound
 	})
 
@@ -63,14 +63,14 @@ func AddBookmarkRoute(w http.ResponseWriter, r *http.Request) error {
 		if err != nil {
 			bookmarkCount = 0
 			// FIXME: what is this?
-			// return i18n.Error("Invalid bookmark count.")
+			// return i18n.ErrorContext(r.Context(), "Invalid bookmark count.")
 		}
 	}
 
 	// Get the illustration ID from the URL.
 	illustID := GetPathVar(r, artworkIDFormat)
 	if illustID == "" {
-		return i18n.Error("No illustration ID provided.")
+		return i18n.ErrorContext(r.Context(), "No illustration ID provided.")
 	}
 
 	// Build and send the pixiv API request to add the bookmark.
@@ -171,14 +171,14 @@ func DeleteBookmarkRoute(w http.ResponseWriter, r *http.Request) error {
 		var err error
 		bookmarkCount, err = strconv.Atoi(r.FormValue(bookmarkCountFormat))
 		if err != nil {
-			return i18n.Error("Invalid bookmark count.")
+			return i18n.ErrorContext(r.Context(), "Invalid bookmark count.")
 		}
 	}
 
 	// Get the bookmark ID from the URL.
 	bookmarkID := GetPathVar(r, bookmarkIDFormat)
 	if bookmarkID == "" {
-		return i18n.Error("No bookmark ID provided.")
+		return i18n.ErrorContext(r.Context(), "No bookmark ID provided.")
 	}
 
 	// Build and send the pixiv API request to remove the bookmark.
@@ -251,12 +251,12 @@ func LikeRoute(w http.ResponseWriter, r *http.Request) error {
 
 	likeCount, err := strconv.Atoi(strLikeCount)
 	if err != nil {
-		return i18n.Error("Invalid bookmark count.")
+		return i18n.ErrorContext(r.Context(), "Invalid bookmark count.")
 	}
 
 	artworkID := GetPathVar(r, artworkIDFormat)
 	if artworkID == "" {
-		return i18n.Error("No ID provided.")
+		return i18n.ErrorContext(r.Context(), "No ID provided.")
 	}
 
 	if token == "" || csrf == "" {
@@ -323,7 +323,7 @@ func FollowRoute(w http.ResponseWriter, r *http.Request) error {
 
 	followUserID := r.FormValue(userIDFormat)
 	if followUserID == "" {
-		return i18n.Error("No user ID provided.")
+		return i18n.ErrorContext(r.Context(), "No user ID provided.")
 	}
 
 	privateVal := r.FormValue(privateFormat)
@@ -413,7 +413,7 @@ func UnfollowRoute(w http.ResponseWriter, r *http.Request) error {
 		followUserID = r.FormValue(userIDFormat)
 	}
 	if followUserID == "" {
-		return i18n.Error("No user ID provided.")
+		return i18n.ErrorContext(r.Context(), "No user ID provided.")
 	}
 
 	if token == "" || csrf == "" {
@@ -0,0 +1,152 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"codeberg.org/pixivfe/pixivfe/server/session"
+	"codeberg.org/pixivfe/pixivfe/server/template"
+	"codeberg.org/pixivfe/pixivfe/server/tokenmanager"
+)
+
+// adminCacheData is the response body for AdminCacheData.
+type adminCacheData struct {
+	Enabled  bool
+	Backend  string
+	Size     int   // Maximum number of entries, or 0 if not bounded by count
+	MaxBytes int64 // Maximum total size of the entries in bytes, or 0 if not bounded by size
+	Bytes    int64 // Current total size of the entries in bytes
+	Len      int
+	Families []requests.CacheFamilyStats
+	Entries  []requests.CacheEntry
+}
+
+// adminCachePurgeData is the response body for AdminCachePurge.
+type adminCachePurgeData struct {
+	Purged int
+	URLs   []string
+}
+
+// AdminCacheData returns the API response cache statistics and the cached URLs as JSON.
+func AdminCacheData(w http.ResponseWriter, _ *http.Request) error {
+	entries := requests.CacheEntries()
+	size, maxBytes, bytes := requests.CacheUsage()
+
+	data := adminCacheData{
+		Enabled:  config.GlobalConfig.Cache.Enabled,
+		Backend:  config.GlobalConfig.Cache.Backend,
+		Size:     size,
+		MaxBytes: maxBytes,
+		Bytes:    bytes,
+		Len:      len(entries),
+		Families: requests.CacheStats(),
+		Entries:  entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(data)
+}
+
+// AdminCachePurge removes all cached responses whose upstream URL starts with
+// the prefix given in the "prefix" query parameter, and returns the purged URLs as JSON.
+func AdminCachePurge(w http.ResponseWriter, r *http.Request) error {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorContext(r.Context(), "Missing prefix parameter")
+	}
+
+	purged, urls := requests.InvalidateURLs([]string{prefix})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(adminCachePurgeData{
+		Purged: purged,
+		URLs:   urls,
+	})
+}
+
+// adminTokensData is the response body for AdminTokensData.
+type adminTokensData struct {
+	Persisted bool
+	Tokens    []tokenmanager.TokenSnapshot
+}
+
+// AdminLoginPage renders a form for logging in to the admin pages with the admin token.
+func AdminLoginPage(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-store")
+
+	return template.RenderHTML(w, r, Data_adminLogin{
+		Title: "Admin login",
+	})
+}
+
+// AdminLogin checks the admin token submitted from AdminLoginPage, and sets the
+// admin session cookie if it matches.
+//
+// Like the admin endpoints, it rejects every submission if no admin token is configured.
+func AdminLogin(w http.ResponseWriter, r *http.Request) error {
+	adminToken := config.GlobalConfig.Admin.Token
+
+	if adminToken == "" || subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(adminToken)) != 1 {
+		requestcontext.FromRequest(r).StatusCode = http.StatusUnauthorized
+
+		return i18n.ErrorContext(r.Context(), "Invalid admin token")
+	}
+
+	session.SetAdminSessionCookie(w, r, session.NewAdminSession(adminToken))
+
+	http.Redirect(w, r, "/admin/tokens", http.StatusSeeOther)
+
+	return nil
+}
+
+// AdminLogout clears the admin session cookie.
+func AdminLogout(w http.ResponseWriter, r *http.Request) error {
+	session.ClearCookie(w, r, session.Cookie_AdminSession)
+
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+
+	return nil
+}
+
+// AdminTokens renders a page listing the configured pixiv tokens, masked, along with their health.
+func AdminTokens(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-store")
+
+	return template.RenderHTML(w, r, Data_adminTokens{
+		Title:  "Tokens",
+		Tokens: config.GlobalConfig.TokenManager.TokenManager.Snapshot(),
+		Now:    time.Now(),
+	})
+}
+
+// AdminTokensData returns the configured pixiv tokens, masked, along with their health as JSON.
+func AdminTokensData(w http.ResponseWriter, _ *http.Request) error {
+	data := adminTokensData{
+		Persisted: config.GlobalConfig.TokenManager.StatePath != "",
+		Tokens:    config.GlobalConfig.TokenManager.TokenManager.Snapshot(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	return json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,325 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"github.com/goccy/go-json"
+)
+
+// APIv2Prefix is the path prefix of the JSON API.
+const APIv2Prefix = "/api/v2/"
+
+// Negotiate returns a handler that serves the JSON API handler jsonHandler if the
+// request prefers JSON over HTML, as determined by PrefersJSON, and htmlHandler otherwise.
+func Negotiate(htmlHandler, jsonHandler func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		// The response differs based on the Accept header, so shared caches must key on it.
+		addVary(w, "Accept")
+
+		if PrefersJSON(r) {
+			return jsonHandler(w, r)
+		}
+
+		return htmlHandler(w, r)
+	}
+}
+
+// PrefersJSON reports whether a JSON response should be served for r.
+//
+// This is the case for requests to the JSON API, and for requests whose Accept header
+// gives application/json a higher quality value than text/html.
+// Wildcards such as */* count towards text/html only, so browsers are always served HTML.
+func PrefersJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, APIv2Prefix) {
+		return true
+	}
+
+	qualities := acceptedQualities(r)
+
+	return qualities["application/json"] > htmlQuality(qualities)
+}
+
+// acceptedQualities returns the quality value of each media range in the Accept header of r.
+func acceptedQualities(r *http.Request) map[string]float64 {
+	qualities := make(map[string]float64)
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		qualities[mediaType] = max(qualities[mediaType], quality)
+	}
+
+	return qualities
+}
+
+// htmlQuality returns the quality value given to text/html by qualities,
+// as returned by acceptedQualities, including through wildcards.
+func htmlQuality(qualities map[string]float64) float64 {
+	return max(qualities["text/html"], qualities["text/*"], qualities["*/*"])
+}
+
+// addVary adds field to the Vary header of w, unless it's already present.
+func addVary(w http.ResponseWriter, field string) {
+	for _, value := range w.Header().Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+
+	w.Header().Add("Vary", field)
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, statusCode int, v any) error {
+	return writeJSONAs(w, "application/json; charset=utf-8", statusCode, v)
+}
+
+// writeJSONAs writes v as the JSON response body, with the given content type.
+func writeJSONAs(w http.ResponseWriter, contentType string, statusCode int, v any) error {
+	w.Header().Set("Content-Type", contentType)
+	// As with HTML responses, proxied URLs depend on the user's settings,
+	// and messages such as errors on the negotiated locale.
+	addVary(w, "Cookie")
+	addVary(w, "Accept-Language")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode JSON response: %w", err)
+	}
+
+	return nil
+}
+
+// writeJSONError writes err as an APIError response body.
+func writeJSONError(w http.ResponseWriter, statusCode int, err error) error {
+	var body APIError
+
+	body.Error.Status = statusCode
+	body.Error.Message = err.Error()
+
+	return writeJSON(w, statusCode, body)
+}
+
+// ArtworkJSON serves an artwork as an APIArtwork.
+func ArtworkJSON(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	illust, err := core.GetArtwork(w, r, id)
+	if err != nil {
+		return err
+	}
+
+	setCacheControl(r, w)
+
+	return writeJSON(w, http.StatusOK, newAPIArtwork(r, illust))
+}
+
+// UserJSON serves a user and a page of their works in a category as an APIUser.
+func UserJSON(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	page := GetQueryParam(r, "page", "1")
+	if _, err := strconv.Atoi(page); err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid page number: %s", page)
+	}
+
+	data, err := fetchData(r, false)
+	if err != nil {
+		return err
+	}
+
+	user := data.user
+
+	var (
+		artworks []core.ArtworkBrief
+		novels   []core.NovelBrief
+	)
+
+	if category, ok := user.Categories[data.category.Value]; ok {
+		artworks = category.IllustWorks
+		novels = category.NovelWorks
+	}
+
+	setCacheControl(r, w)
+
+	return writeJSON(w, http.StatusOK, APIUser{
+		ID:         user.ID,
+		Name:       user.Name,
+		Avatar:     absoluteURL(r, user.Avatar),
+		Background: absoluteURL(r, user.BackgroundImage),
+		Comment:    user.Comment,
+		Webpage:    user.Webpage,
+		Following:  user.Following,
+		Category:   data.category.Value,
+		Page:       data.page,
+		PageCount:  data.category.PageLimit,
+		Artworks:   newAPIArtworkBriefs(r, artworks),
+		Novels:     newAPINovelBriefs(r, novels),
+		URL:        absoluteURL(r, "/users/"+user.ID),
+	})
+}
+
+// NovelJSON serves a novel as an APINovel.
+func NovelJSON(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	novel, err := core.GetNovelByID(r, id)
+	if err != nil {
+		return err
+	}
+
+	setCacheControl(r, w)
+
+	return writeJSON(w, http.StatusOK, newAPINovel(r, &novel))
+}
+
+// RankingJSON serves a page of a ranking as an APIRanking.
+func RankingJSON(w http.ResponseWriter, r *http.Request) error {
+	date := GetQueryParam(r, "date", "")
+	page := GetQueryParam(r, "page", "1")
+
+	if _, err := strconv.Atoi(page); err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid page number: %s", page)
+	}
+
+	ranking, err := core.GetRanking(r,
+		GetQueryParam(r, "mode", "daily"),
+		GetQueryParam(r, "content", "all"),
+		date,
+		page)
+	if err != nil {
+		return err
+	}
+
+	setCacheControl(r, w)
+
+	return writeJSON(w, http.StatusOK, newAPIRanking(r, &ranking, date))
+}
+
+// TagJSON serves a page of search results for a tag as an APITagSearch.
+//
+// Only the artworks, illustrations, manga and novels categories are supported.
+func TagJSON(w http.ResponseWriter, r *http.Request) error {
+	queries, pageInt, err := tagSearchSettings(r)
+	if err != nil {
+		return err
+	}
+
+	result, err := core.GetSearch(r, queries)
+	if err != nil {
+		return err
+	}
+
+	var (
+		artworks []core.ArtworkBrief
+		novels   []core.NovelBrief
+	)
+
+	switch queries.Category {
+	case "artworks":
+		artworks = result.IllustManga.Data
+	case "illustrations":
+		artworks = result.Illustrations.Data
+	case "manga":
+		artworks = result.Manga.Data
+	case "novels":
+		novels = result.Novels.Data
+	}
+
+	setCacheControl(r, w)
+
+	return writeJSON(w, http.StatusOK, APITagSearch{
+		Tag:       queries.Name,
+		Category:  queries.Category,
+		Order:     queries.Order,
+		Mode:      queries.Mode,
+		Page:      pageInt,
+		PageCount: result.LastPage,
+		Total:     result.Total,
+		Artworks:  newAPIArtworkBriefs(r, artworks),
+		Novels:    newAPINovelBriefs(r, novels),
+		Related:   newAPITags(r, result.RelatedTags),
+	})
+}
+
+// tagSearchSettings returns the search settings for the tag and query parameters
+// of r, along with the requested page number.
+//
+// Only the artworks, illustrations, manga and novels categories are supported.
+func tagSearchSettings(r *http.Request) (core.ArtworkSearchSettings, int, error) {
+	name, err := url.PathUnescape(GetPathVar(r, "name"))
+	if err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return core.ArtworkSearchSettings{}, 0, err
+	}
+
+	queries := core.ArtworkSearchSettings{
+		Name:     name,
+		Category: GetQueryParam(r, "category", "artworks"),
+		Order:    GetQueryParam(r, "order", "date_d"),
+		Mode:     GetQueryParam(r, "mode", "safe"),
+		Ratio:    GetQueryParam(r, "ratio", ""),
+		Page:     GetQueryParam(r, "page", "1"),
+	}
+
+	pageInt, err := strconv.Atoi(queries.Page)
+	if err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return core.ArtworkSearchSettings{}, 0, i18n.ErrorfContext(r.Context(), "Invalid page number: %s", queries.Page)
+	}
+
+	switch queries.Category {
+	case "artworks", "illustrations", "manga", "novels":
+		// supported
+	default:
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return core.ArtworkSearchSettings{}, 0, i18n.ErrorfContext(r.Context(), "Invalid category: %s", queries.Category)
+	}
+
+	return queries, pageInt, nil
+}
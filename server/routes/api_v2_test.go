@@ -0,0 +1,118 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+)
+
+func TestPrefersJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		want   bool
+	}{
+		{"API path", "/api/v2/artworks/1", "text/html", true},
+		{"no Accept header", "/artworks/1", "", false},
+		{"browser", "/artworks/1", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"JSON only", "/artworks/1", "application/json", true},
+		{"JSON preferred", "/artworks/1", "application/json, text/html;q=0.5", true},
+		{"HTML preferred", "/artworks/1", "application/json;q=0.5, text/html", false},
+		{"equal preference", "/artworks/1", "application/json, */*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			if got := PrefersJSON(r); got != tt.want {
+				t.Errorf("PrefersJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewAPIArtwork verifies that URLs of the built-in proxies are made absolute,
+// and that enumerations are serialized as stable strings.
+func TestNewAPIArtwork(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v2/artworks/1", nil)
+	r.Host = "pixivfe.example.com"
+
+	illust := &core.Illust{
+		ID:         "1",
+		IllustType: core.Ugoira,
+		XRestrict:  core.R18,
+		AiType:     core.AI,
+		Images: []core.Thumbnails{{
+			Medium:          "/proxy/i.pximg.net/c/600x600/img.jpg",
+			MasterWebp_1200: "https://pximg.example.com/img.webp",
+		}},
+	}
+
+	artwork := newAPIArtwork(r, illust)
+
+	if got, want := artwork.Images[0].Thumbnail, "http://pixivfe.example.com/proxy/i.pximg.net/c/600x600/img.jpg"; got != want {
+		t.Errorf("Thumbnail = %q, want %q", got, want)
+	}
+
+	if got, want := artwork.Images[0].Regular, "https://pximg.example.com/img.webp"; got != want {
+		t.Errorf("Regular = %q, want %q", got, want)
+	}
+
+	if artwork.Type != "ugoira" || artwork.XRestrict != "r18" || artwork.AIType != "ai" {
+		t.Errorf("unexpected enumerations: type=%q xRestrict=%q aiType=%q",
+			artwork.Type, artwork.XRestrict, artwork.AIType)
+	}
+
+	if got, want := artwork.URL, "http://pixivfe.example.com/artworks/1"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	if artwork.Tags == nil {
+		t.Error("Tags must be an empty array rather than null")
+	}
+}
+
+// TestWriteJSONVary verifies that JSON responses vary on the headers that
+// their content depends on, without repeating them.
+func TestWriteJSONVary(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Cookie")
+
+	if err := writeJSON(w, http.StatusOK, struct{}{}); err != nil {
+		t.Fatalf("writeJSON() error = %v", err)
+	}
+
+	if got, want := w.Header().Values("Vary"), []string{"Accept", "Cookie", "Accept-Language"}; !slices.Equal(got, want) {
+		t.Errorf("Vary = %q, want %q", got, want)
+	}
+}
+
+// TestNewAPITags verifies that tag translations are chosen by the locale of the request.
+func TestNewAPITags(t *testing.T) {
+	tags := []core.Tag{{
+		Name:            "猫",
+		TagTranslations: core.TagTranslations{En: "cat", Zh: "猫咪"},
+	}}
+
+	for locale, want := range map[string]string{"en": "cat", "zh-CN": "猫咪"} {
+		r := httptest.NewRequest("GET", "/api/v2/artworks/1", nil)
+		r = r.WithContext(i18n.WithLocale(r.Context(), locale))
+
+		if got := newAPITags(r, tags)[0].Translation; got != want {
+			t.Errorf("Translation in %s = %q, want %q", locale, got, want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+)
+
+// The types in this file define the schema of the JSON API served under /api/v2.
+//
+// The schema is stable: fields are only ever added within a version, never renamed,
+// removed or changed in type. It's deliberately decoupled from the core types,
+// which mirror the pixiv API and change along with it.
+//
+// Refer to docs/json-api.md for the documentation of each field.
+
+// APIImage represents a single page of an artwork.
+type APIImage struct {
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Thumbnail string `json:"thumbnail"`           // 600x600 thumbnail
+	Regular   string `json:"regular"`             // Original aspect ratio, width limited to 1200px
+	Original  string `json:"original,omitempty"`  // Full-size original image
+	Video     string `json:"video,omitempty"`     // Video rendition, for ugoira
+	Animation string `json:"animation,omitempty"` // Animated image rendition, for ugoira rendered by PixivFE
+}
+
+// APITag represents a tag on a work.
+type APITag struct {
+	Name        string `json:"name"`
+	Translation string `json:"translation,omitempty"` // Translation in the locale of the request
+	Romaji      string `json:"romaji,omitempty"`
+}
+
+// APIArtworkBrief represents an artwork in a listing.
+type APIArtworkBrief struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Type      string `json:"type"`
+	UserID    string `json:"userId"`
+	UserName  string `json:"userName"`
+	PageCount int    `json:"pageCount"`
+	XRestrict string `json:"xRestrict"`
+	AIType    string `json:"aiType"`
+	Thumbnail string `json:"thumbnail"`
+	URL       string `json:"url"`
+}
+
+// APIArtwork represents an artwork, as served by /api/v2/artworks/{id}.
+type APIArtwork struct {
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"` // HTML
+	Type          string     `json:"type"`
+	UserID        string     `json:"userId"`
+	UserName      string     `json:"userName"`
+	UploadDate    time.Time  `json:"uploadDate"`
+	PageCount     int        `json:"pageCount"`
+	BookmarkCount int        `json:"bookmarkCount"`
+	LikeCount     int        `json:"likeCount"`
+	CommentCount  int        `json:"commentCount"`
+	ViewCount     int        `json:"viewCount"`
+	XRestrict     string     `json:"xRestrict"`
+	AIType        string     `json:"aiType"`
+	Tags          []APITag   `json:"tags"`
+	Images        []APIImage `json:"images"`
+	URL           string     `json:"url"`
+}
+
+// APINovelBrief represents a novel in a listing.
+type APINovelBrief struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	UserID    string   `json:"userId"`
+	UserName  string   `json:"userName"`
+	Cover     string   `json:"cover"`
+	Tags      []string `json:"tags"`
+	WordCount int      `json:"wordCount"`
+	XRestrict string   `json:"xRestrict"`
+	AIType    string   `json:"aiType"`
+	URL       string   `json:"url"`
+}
+
+// APINovelSeriesRef identifies the series a novel belongs to.
+type APINovelSeriesRef struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Order int    `json:"order"`
+}
+
+// APINovel represents a novel, as served by /api/v2/novel/{id}.
+type APINovel struct {
+	ID            string             `json:"id"`
+	Title         string             `json:"title"`
+	Description   string             `json:"description"` // HTML
+	UserID        string             `json:"userId"`
+	UserName      string             `json:"userName"`
+	CreateDate    time.Time          `json:"createDate"`
+	UploadDate    time.Time          `json:"uploadDate"`
+	Language      string             `json:"language"`
+	Cover         string             `json:"cover"`
+	Content       string             `json:"content"` // Raw text, including pixiv markup such as [newpage]
+	WordCount     int                `json:"wordCount"`
+	BookmarkCount int                `json:"bookmarkCount"`
+	LikeCount     int                `json:"likeCount"`
+	CommentCount  int                `json:"commentCount"`
+	ViewCount     int                `json:"viewCount"`
+	XRestrict     string             `json:"xRestrict"`
+	AIType        string             `json:"aiType"`
+	Tags          []string           `json:"tags"`
+	Series        *APINovelSeriesRef `json:"series"` // null if the novel isn't part of a series
+	URL           string             `json:"url"`
+}
+
+// APIUser represents a user and a page of their works, as served by /api/v2/users/{id}.
+type APIUser struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Avatar     string            `json:"avatar"`
+	Background string            `json:"background,omitempty"`
+	Comment    string            `json:"comment"`
+	Webpage    string            `json:"webpage,omitempty"`
+	Following  int               `json:"following"`
+	Category   string            `json:"category"`
+	Page       int               `json:"page"`
+	PageCount  int               `json:"pageCount"`
+	Artworks   []APIArtworkBrief `json:"artworks"`
+	Novels     []APINovelBrief   `json:"novels"`
+	URL        string            `json:"url"`
+}
+
+// APIRankedArtwork represents an artwork in a ranking.
+type APIRankedArtwork struct {
+	Rank int `json:"rank"`
+	APIArtworkBrief
+}
+
+// APIRanking represents a page of a ranking, as served by /api/v2/ranking.
+type APIRanking struct {
+	Mode     string             `json:"mode"`
+	Content  string             `json:"content"`
+	Date     string             `json:"date"`
+	PrevDate string             `json:"prevDate,omitempty"`
+	NextDate string             `json:"nextDate,omitempty"`
+	Page     int                `json:"page"`
+	Artworks []APIRankedArtwork `json:"artworks"`
+}
+
+// APITagSearch represents a page of search results for a tag, as served by /api/v2/tags/{name}.
+type APITagSearch struct {
+	Tag       string            `json:"tag"`
+	Category  string            `json:"category"`
+	Order     string            `json:"order"`
+	Mode      string            `json:"mode"`
+	Page      int               `json:"page"`
+	PageCount int               `json:"pageCount"`
+	Total     int               `json:"total"`
+	Artworks  []APIArtworkBrief `json:"artworks"`
+	Novels    []APINovelBrief   `json:"novels"`
+	Related   []APITag          `json:"relatedTags"`
+}
+
+// APIError is the body of error responses served by the JSON API.
+type APIError struct {
+	Error struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// absoluteURL resolves a URL relative to the origin of r, such as
+// those of the built-in proxies, into an absolute URL.
+func absoluteURL(r *http.Request, u string) string {
+	if strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") {
+		return utils.Origin(r) + u
+	}
+
+	return u
+}
+
+func apiIllustType(t core.IllustType) string {
+	switch t {
+	case core.Manga:
+		return "manga"
+	case core.Ugoira:
+		return "ugoira"
+	default:
+		return "illustration"
+	}
+}
+
+func apiXRestrict(x core.XRestrict) string {
+	switch x {
+	case core.R18:
+		return "r18"
+	case core.R18G:
+		return "r18g"
+	default:
+		return "safe"
+	}
+}
+
+func apiAIType(a core.AiType) string {
+	switch a {
+	case core.NotAI:
+		return "not_ai"
+	case core.AI:
+		return "ai"
+	default:
+		return "unrated"
+	}
+}
+
+func newAPIImage(r *http.Request, t core.Thumbnails) APIImage {
+	return APIImage{
+		Width:     t.Width,
+		Height:    t.Height,
+		Thumbnail: absoluteURL(r, t.Medium),
+		Regular:   absoluteURL(r, t.MasterWebp_1200),
+		Original:  absoluteURL(r, t.Original),
+		Video:     absoluteURL(r, t.Video),
+		Animation: absoluteURL(r, t.Animation),
+	}
+}
+
+func newAPITags(r *http.Request, tags []core.Tag) []APITag {
+	locale := i18n.LocaleFrom(r.Context())
+	result := make([]APITag, 0, len(tags))
+
+	for _, tag := range tags {
+		result = append(result, APITag{
+			Name:        tag.Name,
+			Translation: tag.Translation(locale),
+			Romaji:      tag.Romaji,
+		})
+	}
+
+	return result
+}
+
+func newAPIArtwork(r *http.Request, illust *core.Illust) APIArtwork {
+	images := make([]APIImage, 0, len(illust.Images))
+	for _, image := range illust.Images {
+		images = append(images, newAPIImage(r, image))
+	}
+
+	return APIArtwork{
+		ID:            illust.ID,
+		Title:         illust.Title,
+		Description:   string(illust.Description),
+		Type:          apiIllustType(illust.IllustType),
+		UserID:        illust.UserID,
+		UserName:      illust.UserName,
+		UploadDate:    illust.Date,
+		PageCount:     illust.Pages,
+		BookmarkCount: illust.Bookmarks,
+		LikeCount:     illust.Likes,
+		CommentCount:  illust.Comments,
+		ViewCount:     illust.Views,
+		XRestrict:     apiXRestrict(illust.XRestrict),
+		AIType:        apiAIType(illust.AiType),
+		Tags:          newAPITags(r, illust.Tags.Tags),
+		Images:        images,
+		URL:           utils.Origin(r) + "/artworks/" + illust.ID,
+	}
+}
+
+func newAPIArtworkBriefs(r *http.Request, works []core.ArtworkBrief) []APIArtworkBrief {
+	result := make([]APIArtworkBrief, 0, len(works))
+
+	for _, work := range works {
+		result = append(result, APIArtworkBrief{
+			ID:        work.ID,
+			Title:     work.Title,
+			Type:      apiIllustType(core.IllustType(work.IllustType)),
+			UserID:    work.UserID,
+			UserName:  work.UserName,
+			PageCount: work.Pages,
+			XRestrict: apiXRestrict(work.XRestrict),
+			AIType:    apiAIType(work.AiType),
+			Thumbnail: absoluteURL(r, work.Thumbnails.Medium),
+			URL:       utils.Origin(r) + "/artworks/" + work.ID,
+		})
+	}
+
+	return result
+}
+
+func newAPINovelBriefs(r *http.Request, novels []core.NovelBrief) []APINovelBrief {
+	result := make([]APINovelBrief, 0, len(novels))
+
+	for _, novel := range novels {
+		tags := novel.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+
+		result = append(result, APINovelBrief{
+			ID:        novel.ID,
+			Title:     novel.Title,
+			UserID:    novel.UserID,
+			UserName:  novel.UserName,
+			Cover:     absoluteURL(r, novel.CoverURL),
+			Tags:      tags,
+			WordCount: novel.WordCount,
+			XRestrict: apiXRestrict(novel.XRestrict),
+			AIType:    apiAIType(novel.AiType),
+			URL:       utils.Origin(r) + "/novel/" + novel.ID,
+		})
+	}
+
+	return result
+}
+
+func newAPINovel(r *http.Request, novel *core.Novel) APINovel {
+	tags := make([]string, 0, len(novel.Tags.Tags))
+	for _, tag := range novel.Tags.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	var series *APINovelSeriesRef
+	if novel.SeriesNavData.SeriesID != 0 {
+		series = &APINovelSeriesRef{
+			ID:    novel.SeriesNavData.SeriesID,
+			Title: novel.SeriesNavData.Title,
+			Order: novel.SeriesNavData.Order,
+		}
+	}
+
+	return APINovel{
+		ID:            novel.ID,
+		Title:         novel.Title,
+		Description:   novel.Description,
+		UserID:        novel.UserID,
+		UserName:      novel.UserName,
+		CreateDate:    novel.CreateDate,
+		UploadDate:    novel.UploadDate,
+		Language:      strings.ToLower(novel.Language),
+		Cover:         absoluteURL(r, novel.CoverURL),
+		Content:       novel.Content,
+		WordCount:     novel.WordCount,
+		BookmarkCount: novel.Bookmarks,
+		LikeCount:     novel.Likes,
+		CommentCount:  novel.CommentCount,
+		ViewCount:     novel.Views,
+		XRestrict:     apiXRestrict(novel.XRestrict),
+		AIType:        apiAIType(novel.AiType),
+		Tags:          tags,
+		Series:        series,
+		URL:           utils.Origin(r) + "/novel/" + novel.ID,
+	}
+}
+
+func newAPIRanking(r *http.Request, ranking *core.Ranking, date string) APIRanking {
+	artworks := make([]APIRankedArtwork, 0, len(ranking.Contents))
+
+	for _, work := range ranking.Contents {
+		artworks = append(artworks, APIRankedArtwork{
+			Rank: work.Rank,
+			APIArtworkBrief: APIArtworkBrief{
+				ID:        work.ID,
+				Title:     work.Title,
+				Type:      apiIllustType(core.IllustType(work.Type)),
+				UserID:    work.AuthorDetails.UserID,
+				UserName:  work.AuthorDetails.UserName,
+				PageCount: work.PageCount,
+				XRestrict: apiXRestrict(work.XRestrict),
+				AIType:    apiAIType(work.AiType),
+				Thumbnail: absoluteURL(r, work.Thumbnails.Medium),
+				URL:       utils.Origin(r) + "/artworks/" + work.ID,
+			},
+		})
+	}
+
+	if ranking.CurrentDate != "" {
+		date = ranking.CurrentDate
+	}
+
+	return APIRanking{
+		Mode:     ranking.Mode,
+		Content:  ranking.Content,
+		Date:     date,
+		PrevDate: ranking.PrevDate,
+		NextDate: ranking.NextDate,
+		Page:     ranking.Page,
+		Artworks: artworks,
+	}
+}
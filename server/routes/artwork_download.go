@@ -0,0 +1,305 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+)
+
+const (
+	// archiveConcurrency limits how many images of an archive are fetched at once,
+	// which also bounds how many are held in memory.
+	archiveConcurrency = 4
+
+	// archiveWriteTimeout is how long writing each image of an archive may take.
+	//
+	// It replaces the write timeout of the server, which applies to whole responses
+	// and is too short for archives of many images.
+	archiveWriteTimeout = time.Minute
+
+	// archiveMaxImageBytes is the largest image that an archive may contain,
+	// which is the size limit of original images on pixiv.
+	archiveMaxImageBytes int64 = 32 << 20 // 32 MiB
+)
+
+// Media types of archives.
+const (
+	zipMediaType = "application/zip"
+	cbzMediaType = "application/vnd.comicbook+zip"
+)
+
+// archiveFile is an image in an archive.
+type archiveFile struct {
+	Name string // Path of the file in the archive
+	URL  string // Proxied URL of the image
+}
+
+// ArtworkDownload serves every page of an artwork in its original size as a
+// ZIP archive, or as a CBZ archive with ComicInfo.xml for paths ending in .cbz.
+func ArtworkDownload(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	illust, err := core.GetArtworkWithImages(r, id)
+	if err != nil {
+		return err
+	}
+
+	files := make([]archiveFile, 0, len(illust.Images))
+	width := len(strconv.Itoa(len(illust.Images) - 1))
+
+	for i, image := range illust.Images {
+		// Named like the originals on pixiv, but padded so that they sort in order
+		files = append(files, archiveFile{
+			Name: fmt.Sprintf("%s_p%0*d%s", illust.ID, width, i, path.Ext(image.Original)),
+			URL:  image.Original,
+		})
+	}
+
+	if strings.HasSuffix(r.URL.Path, ".cbz") {
+		comicInfo := core.NewArtworkComicInfo(illust)
+
+		return streamArchive(w, r, illust.Title+".cbz", cbzMediaType, files, &comicInfo)
+	}
+
+	return streamArchive(w, r, illust.Title+".zip", zipMediaType, files, nil)
+}
+
+// MangaSeriesDownload serves every page of every artwork in a manga series, in
+// series order, as a CBZ archive with ComicInfo.xml.
+//
+// Like MangaSeriesPage, requests for a series under another user are redirected
+// to the URL of the user who owns it.
+func MangaSeriesDownload(w http.ResponseWriter, r *http.Request) error {
+	userID := GetPathVar(r, "user_id")
+	if _, err := strconv.Atoi(userID); err != nil {
+		return i18n.ErrorfContext(r.Context(), "Invalid user ID: %s", userID)
+	}
+
+	seriesID := GetPathVar(r, "series_id")
+	if _, err := strconv.Atoi(seriesID); err != nil {
+		return i18n.ErrorfContext(r.Context(), "Invalid series ID: %s", seriesID)
+	}
+
+	firstPage, err := core.GetMangaSeriesByID(r, seriesID, 1)
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(firstPage.IllustSeries, func(s core.IllustSeries) bool {
+		return s.ID == seriesID
+	})
+	if idx == -1 {
+		return i18n.ErrorfContext(r.Context(), "Invalid series ID: %s", seriesID)
+	}
+
+	if ownerID := firstPage.IllustSeries[idx].UserID; ownerID != userID {
+		http.Redirect(w, r, fmt.Sprintf("/users/%s/series/%s/download.cbz", ownerID, seriesID), http.StatusPermanentRedirect)
+
+		return nil
+	}
+
+	series, works, err := core.GetMangaSeriesWorks(r, seriesID)
+	if err != nil {
+		return err
+	}
+
+	var files []archiveFile
+
+	for _, work := range works {
+		for _, image := range work.Images {
+			files = append(files, archiveFile{URL: image.Original})
+		}
+	}
+
+	width := len(strconv.Itoa(len(files)))
+	for i := range files {
+		files[i].Name = fmt.Sprintf("%0*d%s", width, i+1, path.Ext(files[i].URL))
+	}
+
+	comicInfo := core.NewMangaSeriesComicInfo(series, works)
+
+	return streamArchive(w, r, series.Title+".cbz", cbzMediaType, files, &comicInfo)
+}
+
+// archiveSource is where the image of an archiveFile is fetched from.
+type archiveSource struct {
+	url     string
+	headers map[string]string
+}
+
+// archiveImage is the result of fetching an archiveFile.
+type archiveImage struct {
+	data []byte
+	err  error
+}
+
+// streamArchive writes files to w as a ZIP archive named filename, along with
+// ComicInfo.xml if comicInfo isn't nil.
+//
+// Up to archiveConcurrency images are fetched ahead of the one being written.
+// The response is streamed once the first image has been fetched, so that most
+// errors can still be shown as a page. An error after that leaves the archive
+// incomplete.
+func streamArchive(
+	w http.ResponseWriter,
+	r *http.Request,
+	filename, mediaType string,
+	files []archiveFile,
+	comicInfo *core.ComicInfo,
+) error {
+	if len(files) == 0 {
+		return i18n.ErrorContext(r.Context(), "There are no images to download.")
+	}
+
+	// Images are fetched from pixiv rather than from the proxy chosen by the user,
+	// which would let a proxy cookie make this instance fetch from any address
+	sources := make([]archiveSource, len(files))
+
+	for i, file := range files {
+		upstreamURL, headers, ok := core.UpstreamContentURL(r, file.URL)
+		if !ok {
+			return i18n.ErrorfContext(r.Context(), "Unsupported image URL: %s", file.URL)
+		}
+
+		sources[i] = archiveSource{url: upstreamURL, headers: headers}
+	}
+
+	setArchiveHeaders(w, r, filename, mediaType)
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	// Stop fetching images once the archive is no longer being written
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	images := make([]chan archiveImage, len(files))
+	for i := range images {
+		images[i] = make(chan archiveImage, 1)
+	}
+
+	// Each slot is freed once its image has been written
+	slots := make(chan struct{}, archiveConcurrency)
+
+	go func() {
+		for i, source := range sources {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			go func() {
+				data, err := requests.FetchContentLimited(ctx, source.url, source.headers, archiveMaxImageBytes)
+				images[i] <- archiveImage{data: data, err: err}
+			}()
+		}
+	}()
+
+	var (
+		zw         *zip.Writer
+		controller *http.ResponseController
+		modified   = time.Now()
+	)
+
+	for i, file := range files {
+		image := <-images[i]
+		if image.err != nil {
+			return image.err
+		}
+
+		if zw == nil {
+			w = utils.StreamResponse(w)
+			zw = zip.NewWriter(w)
+			controller = http.NewResponseController(w)
+
+			if comicInfo != nil {
+				if err := writeComicInfo(zw, comicInfo, modified); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := controller.SetWriteDeadline(time.Now().Add(archiveWriteTimeout)); err != nil &&
+			!errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+
+		// Images are already compressed, so they're stored as is
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Store,
+			Modified: modified,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := fw.Write(image.data); err != nil {
+			return err
+		}
+
+		// Send the image now rather than once the writer's buffer fills up
+		if err := zw.Flush(); err != nil {
+			return err
+		}
+
+		<-slots
+	}
+
+	return zw.Close()
+}
+
+// setArchiveHeaders sets the headers of a response serving an archive as a
+// download named filename.
+func setArchiveHeaders(w http.ResponseWriter, r *http.Request, filename, mediaType string) {
+	setCacheControl(r, w)
+
+	// FormatMediaType encodes file names that aren't ASCII as described in RFC 2231
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Disposition", disposition)
+}
+
+// writeComicInfo writes comicInfo to zw as ComicInfo.xml.
+func writeComicInfo(zw *zip.Writer, comicInfo *core.ComicInfo, modified time.Time) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "ComicInfo.xml",
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := fw.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(fw)
+	encoder.Indent("", "  ")
+
+	return encoder.Encode(comicInfo)
+}
@@ -0,0 +1,32 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStreamArchive_UnsupportedURL verifies that archives of images outside of
+// pixiv's image servers are refused before anything is fetched or written.
+func TestStreamArchive_UnsupportedURL(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/artworks/1/download.zip", nil)
+	r.AddCookie(&http.Cookie{Name: "pixivfe-ImageProxy", Value: "https://proxy.example.com"})
+
+	w := httptest.NewRecorder()
+
+	files := []archiveFile{
+		{Name: "1_p0.png", URL: "https://proxy.example.com/img-original/1_p0.png"},
+		{Name: "1_p1.png", URL: "http://169.254.169.254/latest/meta-data/"},
+	}
+
+	if err := streamArchive(w, r, "1.zip", zipMediaType, files, nil); err == nil {
+		t.Fatal("expected an error for an unsupported image URL")
+	}
+
+	if disposition := w.Header().Get("Content-Disposition"); disposition != "" {
+		t.Errorf("expected no archive to be started, got Content-Disposition %q", disposition)
+	}
+}
@@ -33,7 +33,7 @@ func ArtworkMultiPage(w http.ResponseWriter, r *http.Request) error {
 	var err_global error = nil
 	for i, id := range ids {
 		if _, err := strconv.Atoi(id); err != nil {
-			err_global = i18n.Errorf("Invalid ID: %s", id)
+			err_global = i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
 			break
 		}
 
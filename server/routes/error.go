@@ -1,22 +1,38 @@
 package routes
 
 import (
+	"errors"
 	"net/http"
 
 	"codeberg.org/pixivfe/pixivfe/assets/components/layout"
 	"codeberg.org/pixivfe/pixivfe/assets/components/pages"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
 	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
 	"github.com/a-h/templ"
 )
 
-// ErrorPage writes an error page.
+// ErrorPage writes an error page with the status code from the request context.
+//
+// Requests that prefer JSON, as determined by PrefersJSON, are served an APIError instead.
 func ErrorPage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-store")
 
+	err := requestcontext.FromRequest(r).RequestError
+	statusCode := requestcontext.FromRequest(r).StatusCode
+
+	if PrefersJSON(r) {
+		_ = writeJSONError(w, statusCode, err)
+
+		return
+	}
+
+	w.WriteHeader(statusCode)
+
 	pageData := pages.ErrorData{
 		Title:      "Error",
-		Error:      requestcontext.FromRequest(r).RequestError,
-		StatusCode: requestcontext.FromRequest(r).StatusCode,
+		Error:      err,
+		StatusCode: statusCode,
+		Upstream:   errors.Is(err, requests.ErrNotFound) || errors.Is(err, requests.ErrForbidden),
 	}
 
 	pageContent := pages.Error(pageData)
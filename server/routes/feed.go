@@ -0,0 +1,464 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"codeberg.org/pixivfe/pixivfe/server/session"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+)
+
+// feedKeyParam is the query parameter used to pass a feed key to personal feeds.
+const feedKeyParam = "key"
+
+// Feed is a feed of works, independent of the format it's served in.
+type Feed struct {
+	ID      string // Absolute URL of the page the feed corresponds to
+	Self    string // Absolute URL of the feed itself
+	Title   string
+	Updated time.Time // Date of the most recently updated entry
+	Links   FeedLinks
+	Entries []FeedEntry
+
+	format feedFormat
+}
+
+// FeedLinks holds the absolute URLs of other pages of a paged feed.
+//
+// URLs are empty if the page doesn't exist or isn't known.
+type FeedLinks struct {
+	First    string
+	Last     string
+	Previous string
+	Next     string
+}
+
+// FeedEntry is an artwork or novel in a Feed.
+type FeedEntry struct {
+	ID         string
+	Title      string
+	URL        string // Absolute URL of the work on PixivFE
+	AuthorName string
+	AuthorURL  string // Absolute URL of the author on PixivFE
+	Image      string // Absolute URL of the proxied thumbnail or cover
+	Enclosure  string // Absolute URL of the proxied full-size image or cover
+	Type       string // Same values as the type field of the JSON API, or "novel"
+	Pages      int    // Number of pages, for artworks
+	WordCount  int    // Number of words, for novels
+	XRestrict  string
+	AIType     string
+	Tags       []string
+	Published  time.Time
+	Updated    time.Time
+	Locale     string // Locale ContentHTML is rendered in
+}
+
+// EnclosureType returns the media type of e.Enclosure, based on its file extension.
+func (e FeedEntry) EnclosureType() string {
+	if mediaType := mime.TypeByExtension(path.Ext(e.Enclosure)); mediaType != "" {
+		return mediaType
+	}
+
+	return "image/jpeg"
+}
+
+// ContentHTML returns the HTML shown by feed readers for e.
+func (e FeedEntry) ContentHTML() string {
+	var b strings.Builder
+
+	ctx := i18n.WithLocale(context.Background(), e.Locale)
+
+	if e.Image != "" {
+		fmt.Fprintf(&b, `<p><a href="%s"><img src="%s" alt="%s"></a></p>`,
+			html.EscapeString(e.URL), html.EscapeString(e.Image), html.EscapeString(e.Title))
+	}
+
+	fmt.Fprintf(&b, `<p><a href="%s">%s</a></p><ul>`,
+		html.EscapeString(e.AuthorURL), html.EscapeString(e.AuthorName))
+
+	items := []string{i18n.SprintfContext(ctx, "Type: %s", e.Type)}
+
+	if e.Type == "novel" {
+		items = append(items, i18n.SprintfContext(ctx, "Words: %d", e.WordCount))
+	} else {
+		items = append(items, i18n.SprintfContext(ctx, "Pages: %d", e.Pages))
+	}
+
+	items = append(items,
+		i18n.SprintfContext(ctx, "Age restriction: %s", e.XRestrict),
+		i18n.SprintfContext(ctx, "AI-generated: %s", e.AIType))
+
+	for _, item := range items {
+		b.WriteString("<li>" + html.EscapeString(item) + "</li>")
+	}
+
+	b.WriteString("</ul>")
+
+	return b.String()
+}
+
+// newFeed creates a Feed for r with the given title and entries, to be served
+// in the format requested by r.
+//
+// page is the current page, and pageLimit the number of pages or 0 if unknown,
+// in which case a link to the next page is only included if hasNext is true.
+func newFeed(r *http.Request, title string, entries []FeedEntry, page, pageLimit int, hasNext bool) Feed {
+	format := requestedFeedFormat(r)
+
+	links := FeedLinks{
+		First: feedPageURL(r, format, 1),
+	}
+
+	if pageLimit > 0 {
+		links.Last = feedPageURL(r, format, pageLimit)
+		hasNext = page < pageLimit
+	}
+
+	if page > 1 {
+		links.Previous = feedPageURL(r, format, page-1)
+	}
+
+	if hasNext {
+		links.Next = feedPageURL(r, format, page+1)
+	}
+
+	return Feed{
+		ID:      feedAlternateURL(r),
+		Self:    feedURL(r, format, r.URL.Query()),
+		Title:   title,
+		Updated: lastUpdated(entries),
+		Links:   links,
+		Entries: entries,
+		format:  format,
+	}
+}
+
+// lastUpdated returns the most recent publication or update date of entries.
+//
+// The current time is returned if no entry has a date, so that empty feeds stay valid.
+func lastUpdated(entries []FeedEntry) time.Time {
+	var updated time.Time
+
+	for _, entry := range entries {
+		for _, date := range []time.Time{entry.Published, entry.Updated} {
+			if date.After(updated) {
+				updated = date
+			}
+		}
+	}
+
+	if updated.IsZero() {
+		return time.Now()
+	}
+
+	return updated
+}
+
+// feedAlternateURL returns the URL of the page that the feed requested by r corresponds to.
+//
+// The feed key, if any, is left out.
+func feedAlternateURL(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del(feedKeyParam)
+
+	alternate := utils.Origin(r) + feedBasePath(r.URL.Path)
+	if len(query) > 0 {
+		alternate += "?" + query.Encode()
+	}
+
+	return alternate
+}
+
+// feedURL returns the URL of the feed requested by r in the given format, with query.
+func feedURL(r *http.Request, format feedFormat, query url.Values) string {
+	u := utils.Origin(r) + FeedPathPrefix + feedBasePath(r.URL.Path) + format.Suffix
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	return u
+}
+
+// feedPageURL returns the URL of the given page of the feed requested by r.
+func feedPageURL(r *http.Request, format feedFormat, page int) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+
+	return feedURL(r, format, query)
+}
+
+// feedImageURL returns u as an absolute URL, proxied according to the settings of r.
+func feedImageURL(r *http.Request, u string) string {
+	return absoluteURL(r, string(core.RewriteContentURLsNoEscape(r, []byte(u))))
+}
+
+// newArtworkFeedEntries converts artworks into feed entries.
+func newArtworkFeedEntries(r *http.Request, artworks []core.ArtworkBrief) []FeedEntry {
+	origin := utils.Origin(r)
+	locale := i18n.LocaleFrom(r.Context())
+	entries := make([]FeedEntry, 0, len(artworks))
+
+	for _, artwork := range artworks {
+		entries = append(entries, FeedEntry{
+			ID:         artwork.ID,
+			Title:      artwork.Title,
+			URL:        origin + "/artworks/" + artwork.ID,
+			AuthorName: artwork.UserName,
+			AuthorURL:  origin + "/users/" + artwork.UserID,
+			Image:      feedImageURL(r, artwork.Thumbnails.Medium),
+			Enclosure:  feedImageURL(r, artwork.Thumbnails.MasterLarge),
+			Type:       apiIllustType(core.IllustType(artwork.IllustType)),
+			Pages:      artwork.Pages,
+			XRestrict:  apiXRestrict(artwork.XRestrict),
+			AIType:     apiAIType(artwork.AiType),
+			Tags:       artwork.Tags,
+			Published:  artwork.CreateDate,
+			Updated:    artwork.UpdateDate,
+			Locale:     locale,
+		})
+	}
+
+	return entries
+}
+
+// newNovelFeedEntries converts novels into feed entries.
+func newNovelFeedEntries(r *http.Request, novels []core.NovelBrief) []FeedEntry {
+	origin := utils.Origin(r)
+	locale := i18n.LocaleFrom(r.Context())
+	entries := make([]FeedEntry, 0, len(novels))
+
+	for _, novel := range novels {
+		cover := feedImageURL(r, novel.CoverURL)
+
+		entries = append(entries, FeedEntry{
+			ID:         novel.ID,
+			Title:      novel.Title,
+			URL:        origin + "/novel/" + novel.ID,
+			AuthorName: novel.UserName,
+			AuthorURL:  origin + "/users/" + novel.UserID,
+			Image:      cover,
+			Enclosure:  cover,
+			Type:       "novel",
+			WordCount:  novel.WordCount,
+			XRestrict:  apiXRestrict(novel.XRestrict),
+			AIType:     apiAIType(novel.AiType),
+			Tags:       novel.Tags,
+			Published:  novel.CreateDate,
+			Updated:    novel.UpdateDate,
+			Locale:     locale,
+		})
+	}
+
+	return entries
+}
+
+// newRankingFeedEntries converts the artworks of a ranking into feed entries.
+//
+// Ranked artworks don't have an update date, so the upload date is used instead.
+func newRankingFeedEntries(r *http.Request, ranking *core.Ranking) []FeedEntry {
+	origin := utils.Origin(r)
+	locale := i18n.LocaleFrom(r.Context())
+	entries := make([]FeedEntry, 0, len(ranking.Contents))
+
+	for _, artwork := range ranking.Contents {
+		uploaded := time.Unix(artwork.UploadTimestamp, 0)
+
+		entries = append(entries, FeedEntry{
+			ID:         artwork.ID,
+			Title:      fmt.Sprintf("#%d %s", artwork.Rank, artwork.Title),
+			URL:        origin + "/artworks/" + artwork.ID,
+			AuthorName: artwork.AuthorDetails.UserName,
+			AuthorURL:  origin + "/users/" + artwork.AuthorDetails.UserID,
+			Image:      feedImageURL(r, artwork.Thumbnails.Medium),
+			Enclosure:  feedImageURL(r, artwork.Thumbnails.MasterLarge),
+			Type:       apiIllustType(core.IllustType(artwork.Type)),
+			Pages:      artwork.PageCount,
+			XRestrict:  apiXRestrict(artwork.XRestrict),
+			AIType:     apiAIType(artwork.AiType),
+			Tags:       artwork.Tags,
+			Published:  uploaded,
+			Updated:    uploaded,
+			Locale:     locale,
+		})
+	}
+
+	return entries
+}
+
+// UserFeed serves a page of a user's works in a category.
+func UserFeed(w http.ResponseWriter, r *http.Request) error {
+	data, err := fetchData(r, false)
+	if err != nil {
+		return err
+	}
+
+	var entries []FeedEntry
+
+	if category, ok := data.user.Categories[data.category.Value]; ok {
+		entries = append(newArtworkFeedEntries(r, category.IllustWorks),
+			newNovelFeedEntries(r, category.NovelWorks)...)
+	}
+
+	setCacheControl(r, w)
+
+	return renderFeed(w, r, newFeed(r, data.user.Name, entries, data.page, data.category.PageLimit, false))
+}
+
+// TagFeed serves a page of search results for a tag.
+//
+// Query parameters are the same as for TagJSON.
+func TagFeed(w http.ResponseWriter, r *http.Request) error {
+	queries, pageInt, err := tagSearchSettings(r)
+	if err != nil {
+		return err
+	}
+
+	result, err := core.GetSearch(r, queries)
+	if err != nil {
+		return err
+	}
+
+	var entries []FeedEntry
+
+	switch queries.Category {
+	case "artworks":
+		entries = newArtworkFeedEntries(r, result.IllustManga.Data)
+	case "illustrations":
+		entries = newArtworkFeedEntries(r, result.Illustrations.Data)
+	case "manga":
+		entries = newArtworkFeedEntries(r, result.Manga.Data)
+	case "novels":
+		entries = newNovelFeedEntries(r, result.Novels.Data)
+	}
+
+	setCacheControl(r, w)
+
+	return renderFeed(w, r, newFeed(r, "#"+queries.Name, entries, pageInt, result.LastPage, false))
+}
+
+// RankingFeed serves a page of a ranking.
+func RankingFeed(w http.ResponseWriter, r *http.Request) error {
+	mode := GetQueryParam(r, "mode", "daily")
+	content := GetQueryParam(r, "content", "all")
+	date := GetQueryParam(r, "date", "")
+	page := GetQueryParam(r, "page", "1")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid page number: %s", page)
+	}
+
+	ranking, err := core.GetRanking(r, mode, content, date, page)
+	if err != nil {
+		return err
+	}
+
+	setCacheControl(r, w)
+
+	// The number of pages in a ranking isn't known, so only previous pages are linked.
+	return renderFeed(w, r, newFeed(r, ranking.Title, newRankingFeedEntries(r, &ranking), pageInt, 0, false))
+}
+
+// NewestFeed serves the newest works on pixiv.
+//
+// Query parameters are the same as for NewestPage.
+func NewestFeed(w http.ResponseWriter, r *http.Request) error {
+	worktype := GetQueryParam(r, "type", "illust")
+	r18 := GetQueryParam(r, "r18", "false")
+
+	works, err := core.GetNewestArtworks(r, worktype, r18)
+	if err != nil {
+		return err
+	}
+
+	setCacheControl(r, w)
+
+	feed := newFeed(r, i18n.TrContext(r.Context(), "Newest works"), newArtworkFeedEntries(r, works), 1, 1, false)
+
+	// The feed isn't paged.
+	feed.Links = FeedLinks{}
+
+	return renderFeed(w, r, feed)
+}
+
+// SelfFollowingWorksFeed serves the latest works by users followed by the logged in user.
+//
+// Feed readers can't log in, so the user's token is taken from the feed key in the
+// key query parameter if present. Feed URLs including a key are linked from
+// SelfFollowingWorksPage.
+func SelfFollowingWorksFeed(w http.ResponseWriter, r *http.Request) error {
+	if key := GetQueryParam(r, feedKeyParam); key != "" {
+		keyed, err := session.WithFeedKey(r, key)
+		if err != nil {
+			requestcontext.FromRequest(r).StatusCode = http.StatusForbidden
+
+			return i18n.ErrorContext(r.Context(), "The feed key is invalid. Copy the feed URL from the latest works by followed users page again.")
+		}
+
+		r = keyed
+	}
+
+	if session.GetUserToken(r) == "" {
+		requestcontext.FromRequest(r).StatusCode = http.StatusUnauthorized
+
+		return i18n.ErrorContext(r.Context(), "A feed key is required for this feed.")
+	}
+
+	mode := GetQueryParam(r, "mode", "safe")
+	page := GetQueryParam(r, "page", "1")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid page number: %s", page)
+	}
+
+	data, err := core.GetNewestFromFollowing(r, "illust", mode, page)
+	if err != nil {
+		return err
+	}
+
+	setCacheControl(r, w)
+
+	entries := newArtworkFeedEntries(r, data.Body.Thumbnails.Illust)
+
+	return renderFeed(w, r, newFeed(r, i18n.TrContext(r.Context(), "Latest by followed users"), entries, pageInt, 0, !data.Body.Page.IsLastPage))
+}
+
+// selfFollowingWorksFeedURL returns the URL of the feed for SelfFollowingWorksPage,
+// including a feed key for the logged in user.
+//
+// It returns an empty string if feed keys are disabled.
+func selfFollowingWorksFeedURL(r *http.Request, mode string) (string, error) {
+	if !session.FeedKeysEnabled() {
+		return "", nil
+	}
+
+	key, err := session.NewFeedKey(session.GetUserToken(r))
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("mode", mode)
+	query.Set(feedKeyParam, key)
+
+	return utils.Origin(r) + FeedPathPrefix + "/self/followingWorks" + atomFeed.Suffix + "?" + query.Encode(), nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/server/template"
+)
+
+// FeedPathPrefix is prepended to the path of a page to get the path of its feed,
+// so that feed routes never shadow pages whose path ends like a feed suffix,
+// such as the tag page of "news.rss".
+const FeedPathPrefix = "/feed"
+
+// feedFormat is a format that a Feed can be served in.
+type feedFormat struct {
+	Suffix    string // Path suffix of feed routes serving this format
+	MediaType string // Media type, used for Accept negotiation and as the Content-Type
+	render    func(w http.ResponseWriter, r *http.Request, feed Feed) error
+}
+
+// Supported feed formats.
+var (
+	atomFeed = feedFormat{
+		Suffix:    ".atom.xml",
+		MediaType: "application/atom+xml",
+		render: func(w http.ResponseWriter, r *http.Request, feed Feed) error {
+			return template.RenderWithContentType(w, r, "application/atom+xml", Data_feedAtom{Feed: feed})
+		},
+	}
+	rssFeed = feedFormat{
+		Suffix:    ".rss",
+		MediaType: "application/rss+xml",
+		render: func(w http.ResponseWriter, r *http.Request, feed Feed) error {
+			return template.RenderWithContentType(w, r, "application/rss+xml", Data_feedRSS{Feed: feed})
+		},
+	}
+	jsonFeed = feedFormat{
+		Suffix:    ".json",
+		MediaType: "application/feed+json",
+		render: func(w http.ResponseWriter, r *http.Request, feed Feed) error {
+			return writeJSONAs(w, "application/feed+json; charset=utf-8", http.StatusOK, newJSONFeed(feed))
+		},
+	}
+)
+
+// feedFormats lists the supported feed formats, in order of preference when the
+// Accept header of a request doesn't prefer any.
+var feedFormats = []feedFormat{atomFeed, rssFeed, jsonFeed}
+
+// FeedSuffixes returns the path suffixes of feed routes, one for each supported format.
+func FeedSuffixes() []string {
+	suffixes := make([]string, 0, len(feedFormats))
+
+	for _, format := range feedFormats {
+		suffixes = append(suffixes, format.Suffix)
+	}
+
+	return suffixes
+}
+
+// NegotiateFeed returns a handler that serves feedHandler if the request prefers
+// one of the supported feed formats over HTML, and handler otherwise.
+//
+// This allows feeds to be requested from the URL of their page, with the
+// Accept header alone.
+func NegotiateFeed(handler, feedHandler func(w http.ResponseWriter, r *http.Request) error) func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		addVary(w, "Accept")
+
+		if _, ok := preferredFeedFormat(r); ok {
+			return feedHandler(w, r)
+		}
+
+		return handler(w, r)
+	}
+}
+
+// renderFeed writes feed in the format requested for it.
+func renderFeed(w http.ResponseWriter, r *http.Request, feed Feed) error {
+	return feed.format.render(w, r, feed)
+}
+
+// requestedFeedFormat returns the format requested by r, based on the path
+// suffix or, failing that, the Accept header.
+//
+// Atom is used if neither selects a supported format.
+func requestedFeedFormat(r *http.Request) feedFormat {
+	if strings.HasPrefix(r.URL.Path, FeedPathPrefix+"/") {
+		for _, format := range feedFormats {
+			if strings.HasSuffix(r.URL.Path, format.Suffix) {
+				return format
+			}
+		}
+	}
+
+	if format, ok := preferredFeedFormat(r); ok {
+		return format
+	}
+
+	return atomFeed
+}
+
+// preferredFeedFormat returns the feed format given the highest quality value by the
+// Accept header of r, if any is preferred over HTML.
+func preferredFeedFormat(r *http.Request) (feedFormat, bool) {
+	qualities := acceptedQualities(r)
+
+	var (
+		preferred feedFormat
+		found     bool
+		best      = htmlQuality(qualities)
+	)
+
+	for _, format := range feedFormats {
+		if quality := qualities[format.MediaType]; quality > best {
+			preferred, found, best = format, true, quality
+		}
+	}
+
+	return preferred, found
+}
+
+// feedBasePath returns the path of the page that the feed at p corresponds to,
+// which is p without FeedPathPrefix and the suffix of its format.
+//
+// Paths of pages, as used for feeds negotiated with the Accept header, are returned as is.
+func feedBasePath(p string) string {
+	base, found := strings.CutPrefix(p, FeedPathPrefix+"/")
+	if !found {
+		return p
+	}
+
+	for _, format := range feedFormats {
+		if trimmed, found := strings.CutSuffix(base, format.Suffix); found {
+			return "/" + trimmed
+		}
+	}
+
+	return p
+}
+
+// jsonFeedVersion is the version URL of the JSON Feed specification implemented by newJSONFeed.
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// JSONFeed is a feed in the JSON Feed format.
+//
+// ref: https://www.jsonfeed.org/version/1.1/
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	NextURL     string         `json:"next_url,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedItem is an item of a JSONFeed.
+type JSONFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	Image         string               `json:"image,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	DateModified  string               `json:"date_modified,omitempty"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Tags          []string             `json:"tags,omitempty"`
+	Attachments   []JSONFeedAttachment `json:"attachments,omitempty"`
+}
+
+// JSONFeedAuthor is the author of a JSONFeedItem.
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// JSONFeedAttachment is a file attached to a JSONFeedItem.
+type JSONFeedAttachment struct {
+	URL      string `json:"url"`
+	MIMEType string `json:"mime_type"`
+}
+
+// newJSONFeed converts feed to the JSON Feed format.
+func newJSONFeed(feed Feed) JSONFeed {
+	items := make([]JSONFeedItem, 0, len(feed.Entries))
+
+	for _, entry := range feed.Entries {
+		item := JSONFeedItem{
+			ID:            entry.URL,
+			URL:           entry.URL,
+			Title:         entry.Title,
+			ContentHTML:   entry.ContentHTML(),
+			Image:         entry.Image,
+			DatePublished: formatJSONFeedDate(entry.Published),
+			DateModified:  formatJSONFeedDate(entry.Updated),
+			Authors:       []JSONFeedAuthor{{Name: entry.AuthorName, URL: entry.AuthorURL}},
+			Tags:          entry.Tags,
+		}
+
+		if entry.Enclosure != "" {
+			item.Attachments = []JSONFeedAttachment{{URL: entry.Enclosure, MIMEType: entry.EnclosureType()}}
+		}
+
+		items = append(items, item)
+	}
+
+	return JSONFeed{
+		Version:     jsonFeedVersion,
+		Title:       feed.Title,
+		HomePageURL: feed.ID,
+		FeedURL:     feed.Self,
+		NextURL:     feed.Links.Next,
+		Items:       items,
+	}
+}
+
+// formatJSONFeedDate formats t as RFC 3339, or returns an empty string if t is zero.
+func formatJSONFeedDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
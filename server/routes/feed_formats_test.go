@@ -0,0 +1,87 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestedFeedFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		want   feedFormat
+	}{
+		{"Atom suffix", "/feed/tags/cat.atom.xml", "", atomFeed},
+		{"RSS suffix", "/feed/tags/cat.rss", "", rssFeed},
+		{"JSON Feed suffix", "/feed/tags/cat.json", "", jsonFeed},
+		{"suffix wins over Accept", "/feed/tags/cat.rss", "application/feed+json", rssFeed},
+		{"page path ending like a suffix", "/tags/news.rss", "application/feed+json", jsonFeed},
+		{"Accept RSS", "/tags/cat", "application/rss+xml, application/atom+xml;q=0.9", rssFeed},
+		{"Accept JSON Feed", "/tags/cat", "application/feed+json", jsonFeed},
+		{"browser", "/tags/cat", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", atomFeed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			if got := requestedFeedFormat(r); got.Suffix != tt.want.Suffix {
+				t.Errorf("requestedFeedFormat() = %q, want %q", got.Suffix, tt.want.Suffix)
+			}
+		})
+	}
+}
+
+func TestFeedBasePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/feed/tags/cat.atom.xml", "/tags/cat"},
+		{"/feed/tags/news.rss.json", "/tags/news.rss"},
+		{"/feed/ranking.rss", "/ranking"},
+		{"/tags/news.rss", "/tags/news.rss"}, // A page, negotiated with the Accept header
+	}
+
+	for _, tt := range tests {
+		if got := feedBasePath(tt.path); got != tt.want {
+			t.Errorf("feedBasePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNewFeed(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	r := httptest.NewRequest("GET", "http://pixivfe.example/feed/self/followingWorks.rss?key=secret&page=2", nil)
+
+	feed := newFeed(r, "Test", []FeedEntry{
+		{Published: older, Updated: older},
+		{Published: older, Updated: newer},
+	}, 2, 0, true)
+
+	if !feed.Updated.Equal(newer) {
+		t.Errorf("Updated = %v, want the newest entry date %v", feed.Updated, newer)
+	}
+
+	if want := "http://pixivfe.example/self/followingWorks?page=2"; feed.ID != want {
+		t.Errorf("ID = %q, want %q", feed.ID, want)
+	}
+
+	if want := "http://pixivfe.example/feed/self/followingWorks.rss?key=secret&page=3"; feed.Links.Next != want {
+		t.Errorf("Links.Next = %q, want %q", feed.Links.Next, want)
+	}
+
+	if feed.Links.Last != "" {
+		t.Errorf("Links.Last = %q, want none as the number of pages is unknown", feed.Links.Last)
+	}
+}
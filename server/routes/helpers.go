@@ -10,7 +10,6 @@ import (
 	"strings"
 
 	"codeberg.org/pixivfe/pixivfe/core"
-	"codeberg.org/pixivfe/pixivfe/server/session"
 	"github.com/gorilla/mux"
 )
 
@@ -114,11 +113,12 @@ func preloadArtworkAssets(w http.ResponseWriter, r *http.Request, illust core.Il
 		linkValues = append(linkValues, makePreloadImageLink(originalURLBeforeSwap))
 		linkValues = append(linkValues, makePreloadImageLink(originalURL))
 
-	case core.Ugoira: // For ugoira, preload the video URL.
-		proxy := session.GetProxyPrefix(session.GetUgoiraProxy(r))
-
-		videoURL := proxy + "/ugoira/" + illust.ID
-		linkValues = append(linkValues, makePreloadVideoLink(videoURL))
+	case core.Ugoira: // For ugoira, preload the video URL, or the animated image URL when rendered by PixivFE.
+		if renditionURL, animated := core.UgoiraRenditionURL(r, illust.ID); animated {
+			linkValues = append(linkValues, makePreloadImageLink(renditionURL))
+		} else {
+			linkValues = append(linkValues, makePreloadVideoLink(renditionURL))
+		}
 
 	default: // Invalid IllustType, don't preload/prefetch anything.
 	}
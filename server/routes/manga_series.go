@@ -21,19 +21,19 @@ import (
 func MangaSeriesPage(w http.ResponseWriter, r *http.Request) error {
 	user_id := GetPathVar(r, "user_id")
 	if _, err := strconv.Atoi(user_id); err != nil {
-		return i18n.Errorf("Invalid user ID: %s", user_id)
+		return i18n.ErrorfContext(r.Context(), "Invalid user ID: %s", user_id)
 	}
 
 	series_id := GetPathVar(r, "series_id")
 	if _, err := strconv.Atoi(series_id); err != nil {
-		return i18n.Errorf("Invalid series ID: %s", series_id)
+		return i18n.ErrorfContext(r.Context(), "Invalid series ID: %s", series_id)
 	}
 
 	// jackyzy823: No way to know total before the GetMangaSeriesByID request.
 	pageStr := GetQueryParam(r, "page", "1")
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
-		return i18n.Errorf("Invalid Page")
+		return i18n.ErrorfContext(r.Context(), "Invalid Page")
 	}
 
 	var mangaSeries core.MangaSeries
@@ -78,7 +78,7 @@ func MangaSeriesPage(w http.ResponseWriter, r *http.Request) error {
 
 	// jackyzy823: Pixiv display empty (not error page) if page id exceeds the total/12 +1
 	if page > pageLimit {
-		return i18n.Errorf("Invalid Page")
+		return i18n.ErrorfContext(r.Context(), "Invalid Page")
 	}
 
 	// Replace user data
@@ -0,0 +1,80 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"bytes"
+	"net/http"
+
+	"codeberg.org/pixivfe/pixivfe/audit"
+	"codeberg.org/pixivfe/pixivfe/core/requests"
+)
+
+// metricsContentType is the content type of the Prometheus text format.
+const metricsContentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metrics is the handler for the /metrics endpoint.
+//
+// It exposes the metrics recorded by package audit, along with
+// the API response cache statistics, in the Prometheus text format.
+func Metrics(w http.ResponseWriter, _ *http.Request) error {
+	var buf bytes.Buffer
+
+	if err := audit.WriteMetrics(&buf); err != nil {
+		return err
+	}
+
+	writeCacheMetrics(&buf)
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// writeCacheMetrics writes the API response cache statistics to buf.
+func writeCacheMetrics(buf *bytes.Buffer) {
+	stats := requests.CacheStats()
+	if stats == nil {
+		return
+	}
+
+	var (
+		hits, misses, evictions, entries, storedBytes []audit.MetricSample
+		totalHits, totalMisses                        uint64
+	)
+
+	for _, family := range stats {
+		labels := []string{"family", family.Family}
+
+		hits = append(hits, audit.MetricSample{Labels: labels, Value: float64(family.Hits)})
+		misses = append(misses, audit.MetricSample{Labels: labels, Value: float64(family.Misses)})
+		evictions = append(evictions, audit.MetricSample{Labels: labels, Value: float64(family.Evictions)})
+		entries = append(entries, audit.MetricSample{Labels: labels, Value: float64(family.Entries)})
+		storedBytes = append(storedBytes, audit.MetricSample{Labels: labels, Value: float64(family.StoredBytes)})
+
+		totalHits += family.Hits
+		totalMisses += family.Misses
+	}
+
+	audit.WriteMetricFamily(buf, "pixivfe_cache_hits_total", audit.MetricTypeCounter,
+		"API responses served from the cache, by endpoint family.", hits...)
+	audit.WriteMetricFamily(buf, "pixivfe_cache_misses_total", audit.MetricTypeCounter,
+		"Cacheable API requests sent upstream, by endpoint family.", misses...)
+	audit.WriteMetricFamily(buf, "pixivfe_cache_evictions_total", audit.MetricTypeCounter,
+		"Cache entries evicted to make room for new ones, by endpoint family.", evictions...)
+	audit.WriteMetricFamily(buf, "pixivfe_cache_entries", audit.MetricTypeGauge,
+		"Cache entries currently stored, by endpoint family.", entries...)
+	audit.WriteMetricFamily(buf, "pixivfe_cache_stored_bytes", audit.MetricTypeGauge,
+		"Size of the API responses currently cached, by endpoint family.", storedBytes...)
+
+	if totalHits+totalMisses > 0 {
+		audit.WriteMetricFamily(buf, "pixivfe_cache_hit_ratio", audit.MetricTypeGauge,
+			"Ratio of cacheable API requests served from the cache since startup.",
+			audit.MetricSample{Value: float64(totalHits) / float64(totalHits+totalMisses)})
+	}
+}
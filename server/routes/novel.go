@@ -29,7 +29,7 @@ func NovelPage(w http.ResponseWriter, r *http.Request) error {
 
 	id := GetPathVar(r, "id")
 	if _, err := strconv.Atoi(id); err != nil {
-		return i18n.Errorf("Invalid ID: %s", id)
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
 	}
 
 	// Fetch main novel data
@@ -0,0 +1,72 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/core/epub"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+)
+
+// NovelEPUB serves a novel as an EPUB.
+func NovelEPUB(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	book, err := core.GetNovelEPUB(r, id)
+	if err != nil {
+		return err
+	}
+
+	return writeEPUB(w, r, book)
+}
+
+// NovelSeriesEPUB serves every novel in a novel series as a single EPUB.
+func NovelSeriesEPUB(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	book, err := core.GetNovelSeriesEPUB(r, id)
+	if err != nil {
+		return err
+	}
+
+	return writeEPUB(w, r, book)
+}
+
+// writeEPUB writes book as a download named after its title.
+//
+// The book is written to a buffer first, so that an error can still be
+// shown as a page.
+func writeEPUB(w http.ResponseWriter, r *http.Request, book *epub.Book) error {
+	var buf bytes.Buffer
+
+	if err := book.Write(&buf); err != nil {
+		return i18n.ErrorfContext(r.Context(), "failed to write EPUB: %w", err)
+	}
+
+	setCacheControl(r, w)
+	// Links in the book, such as to other pages, are localized
+	addVary(w, "Accept-Language")
+
+	// FormatMediaType encodes file names that aren't ASCII as described in RFC 2231
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": book.Title + ".epub"})
+
+	w.Header().Set("Content-Type", epub.MediaType)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
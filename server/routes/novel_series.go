@@ -19,7 +19,7 @@ import (
 func NovelSeriesPage(w http.ResponseWriter, r *http.Request) error {
 	id := GetPathVar(r, "id")
 	if _, err := strconv.Atoi(id); err != nil {
-		return i18n.Errorf("Invalid ID: %s", id)
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
 	}
 
 	series, err := core.GetNovelSeriesByID(r, id)
@@ -34,7 +34,7 @@ func NovelSeriesPage(w http.ResponseWriter, r *http.Request) error {
 	page := GetQueryParam(r, "p", "1")
 	pageNum, err := strconv.Atoi(page)
 	if err != nil || pageNum < 1 || pageNum > pageLimit {
-		return i18n.Errorf("Invalid Page Number: %d", pageNum)
+		return i18n.ErrorfContext(r.Context(), "Invalid Page Number: %d", pageNum)
 	}
 
 	// TODO should use token only if R-18/R-18G
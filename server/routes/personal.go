@@ -83,10 +83,16 @@ func SelfFollowingWorksPage(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	feedURL, err := selfFollowingWorksFeedURL(r, mode)
+	if err != nil {
+		return err
+	}
+
 	return template.RenderHTML(w, r, Data_following{
-		Title: "Latest by followed users",
-		Mode:  mode,
-		Data:  data,
-		Page:  pageInt,
+		Title:   "Latest by followed users",
+		Mode:    mode,
+		Data:    data,
+		Page:    pageInt,
+		FeedURL: feedURL,
 	})
 }
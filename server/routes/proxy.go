@@ -4,25 +4,73 @@
 package routes
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"time"
 
 	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/core/imageproxy"
 	"codeberg.org/pixivfe/pixivfe/core/requests"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
 )
 
+// imageTransformWriteTimeout is how long transforming and serving an image may take.
+const imageTransformWriteTimeout = 90 * time.Second
+
 // SPximgProxy handles requests for static assets from s.pximg.net.
 func SPximgProxy(w http.ResponseWriter, r *http.Request) error {
 	return requests.ProxyHandler(w, r, "https://s.pximg.net", nil)
 }
 
 // IPximgProxy handles requests for image assets from i.pximg.net.
+//
+// Images are resized and converted when requested with the w and fmt query
+// parameters, unless image transformations are disabled.
 func IPximgProxy(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	if config.GlobalConfig.ImageTransform.Enabled && (query.Has("w") || query.Has("fmt")) {
+		return transformedImage(w, r)
+	}
+
 	headers := map[string]string{
 		"Referer": "https://www.pixiv.net/",
 	}
 	return requests.ProxyHandler(w, r, "https://i.pximg.net", headers)
 }
 
+// transformedImage serves an image from i.pximg.net transformed as described by
+// the query parameters of the request.
+func transformedImage(w http.ResponseWriter, r *http.Request) error {
+	opts, err := imageproxy.ParseOptions(r.URL.Query())
+	if err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid image transformation: %s", r.URL.RawQuery)
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(imageTransformWriteTimeout)); err != nil &&
+		!errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	data, err := core.TransformImage(r, r.URL.Path, opts)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("ETag", `"`+utils.GenerateETag(data)+`"`)
+
+	// ServeContent handles conditional and range requests
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+
+	return nil
+}
+
 // UgoiraProxy handles requests for video assets from ugoira.com.
 func UgoiraProxy(w http.ResponseWriter, r *http.Request) error {
 	return requests.ProxyHandler(w, r, "https://ugoira.com/api/mp4", nil)
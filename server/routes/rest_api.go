@@ -334,7 +334,7 @@ func parseWorkIDs(s string) ([]int, error) {
 // func UgoiraPreview(w http.ResponseWriter, r *http.Request) error {
 // 	id := GetPathVar(r, "id")
 // 	if _, err := strconv.Atoi(id); err != nil {
-// 		return i18n.Errorf("Invalid ID: %s", id)
+// 		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
 // 	}
 
 // 	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d",
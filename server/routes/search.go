@@ -75,14 +75,11 @@ func TagPage(w http.ResponseWriter, r *http.Request) error {
 		Page:     GetQueryParam(r, "page", "1"),
 	}
 
-	switch category {
-	case "users":
+	if category == "users" {
 		return resultPageUser(w, r, queries)
-	case "novels":
-		return resultPageArtNovel(w, r, queries)
-	default:
-		return resultPageArtNovel(w, r, queries)
 	}
+
+	return resultPageArtNovel(w, r, queries)
 }
 
 func resultPageUser(w http.ResponseWriter, r *http.Request, queries core.ArtworkSearchSettings) error {
@@ -123,8 +120,9 @@ func resultPageArtNovel(w http.ResponseWriter, r *http.Request, queries core.Art
 	}
 
 	var (
-		tag    core.TagSearchResult
-		result *core.ArtworkSearchResponse
+		tag            core.TagSearchResult
+		result         *core.ArtworkSearchResponse
+		canonicalTagTo string
 	)
 
 	g, _ := errgroup.WithContext(r.Context())
@@ -137,6 +135,13 @@ func resultPageArtNovel(w http.ResponseWriter, r *http.Request, queries core.Art
 		}
 		tag = t
 
+		// Translated tag names are redirected to the canonical tag page,
+		// so there is no cover artwork to fetch
+		if target, ok := canonicalTagURL(r, name, tag); ok {
+			canonicalTagTo = target
+			return nil
+		}
+
 		// Fetch cover artwork after tag data is available
 		id := tag.Metadata.ID.String()
 		if id != "" {
@@ -168,7 +173,15 @@ func resultPageArtNovel(w http.ResponseWriter, r *http.Request, queries core.Art
 		return nil
 	})
 
-	if err := g.Wait(); err != nil {
+	err = g.Wait()
+
+	if canonicalTagTo != "" {
+		http.Redirect(w, r, canonicalTagTo, http.StatusSeeOther)
+
+		return nil
+	}
+
+	if err != nil {
 		return err
 	}
 
@@ -195,6 +208,32 @@ func resultPageArtNovel(w http.ResponseWriter, r *http.Request, queries core.Art
 	})
 }
 
+// canonicalTagURL returns the tag page of the canonical name of a translated
+// tag name, keeping the other search options, for GET and HEAD requests, and
+// reports whether name should be redirected there.
+//
+// tag is the tag data for name, from which the canonical name is resolved.
+func canonicalTagURL(r *http.Request, name string, tag core.TagSearchResult) (string, bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", false
+	}
+
+	canonical, ok := core.CanonicalTagName(name, tag)
+	if !ok {
+		return "", false
+	}
+
+	query := r.URL.Query()
+	query.Del("name")
+
+	target := "/tags/" + url.PathEscape(canonical)
+	if encoded := query.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+
+	return target, true
+}
+
 func setCacheControl(r *http.Request, w http.ResponseWriter) {
 	if session.GetUserToken(r) != "" {
 		w.Header().Set("Cache-Control", "private, max-age=60")
@@ -15,6 +15,7 @@ import (
 	"codeberg.org/pixivfe/pixivfe/core"
 	"codeberg.org/pixivfe/pixivfe/core/requests"
 	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/proxychecker"
 	"codeberg.org/pixivfe/pixivfe/server/session"
 	"codeberg.org/pixivfe/pixivfe/server/template"
 	"codeberg.org/pixivfe/pixivfe/server/utils"
@@ -29,7 +30,7 @@ var (
 func setToken(w http.ResponseWriter, r *http.Request) (string, error) {
 	token := r.FormValue("token")
 	if token == "" {
-		return "", i18n.Error("You submitted an empty/invalid form.")
+		return "", i18n.ErrorContext(r.Context(), "You submitted an empty/invalid form.")
 	}
 
 	cookies := map[string]string{
@@ -40,7 +41,7 @@ func setToken(w http.ResponseWriter, r *http.Request) (string, error) {
 	url := core.GetNewestFromFollowingURL("illust", "all", "1")
 	_, err := requests.FetchJSONBodyField(r.Context(), url, cookies, r.Header)
 	if err != nil {
-		return "", i18n.Error("Cannot authorize with supplied token. (API returned not OK)")
+		return "", i18n.ErrorContext(r.Context(), "Cannot authorize with supplied token. (API returned not OK)")
 	}
 
 	// Request artwork page to extract csrf token from body
@@ -51,25 +52,25 @@ func setToken(w http.ResponseWriter, r *http.Request) (string, error) {
 	}
 
 	if rawResp.StatusCode != http.StatusOK {
-		return "", i18n.Error("Cannot authorize with supplied token. (Page returned not OK)")
+		return "", i18n.ErrorContext(r.Context(), "Cannot authorize with supplied token. (Page returned not OK)")
 	}
 
 	// Extract CSRF token
 	csrfMatches := r_csrf.FindStringSubmatch(string(rawResp.Body))
 	if len(csrfMatches) < 2 {
-		return "", i18n.Error("Unable to extract CSRF token from response.")
+		return "", i18n.ErrorContext(r.Context(), "Unable to extract CSRF token from response.")
 	}
 	csrf := csrfMatches[1]
 
 	// Extract personal ID
 	personalIDMatches := r_p_ab.FindStringSubmatch(string(rawResp.Body))
 	if len(personalIDMatches) < 2 {
-		return "", i18n.Error("Unable to extract a personal ID from response.")
+		return "", i18n.ErrorContext(r.Context(), "Unable to extract a personal ID from response.")
 	}
 	personalID := personalIDMatches[1]
 
 	if personalID == "" {
-		return "", i18n.Error("Cannot authorize with supplied token. (Invalid personal ID)")
+		return "", i18n.ErrorContext(r.Context(), "Cannot authorize with supplied token. (Invalid personal ID)")
 	}
 
 	// Get user ID from token
@@ -104,7 +105,7 @@ func setToken(w http.ResponseWriter, r *http.Request) (string, error) {
 	session.SetCookie(w, r, session.Cookie_UserID, userInfo.UserID)
 	session.SetCookie(w, r, session.Cookie_UserAvatar, userInfo.Avatar)
 
-	return i18n.Sprintf("Successfully logged in."), nil
+	return i18n.SprintfContext(r.Context(), "Successfully logged in."), nil
 }
 
 func setImageServer(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -118,7 +119,7 @@ func setImageServer(w http.ResponseWriter, r *http.Request) (string, error) {
 		return handleSelectedProxy(w, r, selectedProxy)
 	default:
 		session.ClearCookie(w, r, session.Cookie_ImageProxy)
-		return i18n.Sprintf("Image proxy server cleared. Using default proxy."), nil
+		return i18n.SprintfContext(r.Context(), "Image proxy server cleared. Using default proxy."), nil
 	}
 }
 
@@ -128,7 +129,7 @@ func handleCustomProxy(w http.ResponseWriter, r *http.Request, customProxy strin
 	if customProxy == config.BuiltInImageProxyPath {
 		proxyURL = config.BuiltInImageProxyPath
 	} else {
-		parsedURL, err := utils.ValidateURL(customProxy, "Custom image proxy")
+		parsedURL, err := utils.ValidateURL(r.Context(), customProxy, "Custom image proxy")
 		if err != nil {
 			return "", err
 		}
@@ -136,14 +137,14 @@ func handleCustomProxy(w http.ResponseWriter, r *http.Request, customProxy strin
 	}
 
 	session.SetCookie(w, r, session.Cookie_ImageProxy, proxyURL)
-	return i18n.Sprintf("Image proxy server set successfully to: %s", proxyURL), nil
+	return i18n.SprintfContext(r.Context(), "Image proxy server set successfully to: %s", proxyURL), nil
 }
 
 func handleSelectedProxy(w http.ResponseWriter, r *http.Request, selectedProxy string) (string, error) {
 	proxyURL := selectedProxy
 
 	session.SetCookie(w, r, session.Cookie_ImageProxy, proxyURL)
-	return i18n.Sprintf("Image proxy server set successfully to: %s", proxyURL), nil
+	return i18n.SprintfContext(r.Context(), "Image proxy server set successfully to: %s", proxyURL), nil
 }
 
 func setVisualEffects(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -169,10 +170,10 @@ func setVisualEffects(w http.ResponseWriter, r *http.Request) (string, error) {
 	}
 
 	if isSuccessful {
-		return i18n.Sprintf("Visual effects preference updated successfully."), nil
+		return i18n.SprintfContext(r.Context(), "Visual effects preference updated successfully."), nil
 	}
 
-	return "", i18n.Error("Invalid visual effects preference.")
+	return "", i18n.ErrorContext(r.Context(), "Invalid visual effects preference.")
 }
 
 func setTimeZone(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -181,57 +182,57 @@ func setTimeZone(w http.ResponseWriter, r *http.Request) (string, error) {
 	// Validate timezone
 	_, err := session.ValidateTimezone(timeZone)
 	if err != nil {
-		return "", i18n.Error("Invalid timezone specified.")
+		return "", i18n.ErrorContext(r.Context(), "Invalid timezone specified.")
 	}
 
 	if timeZone == "" {
 		session.ClearCookie(w, r, session.Cookie_TZ)
-		return i18n.Sprintf("Timezone reset to UTC."), nil
+		return i18n.SprintfContext(r.Context(), "Timezone reset to UTC."), nil
 	}
 
 	session.SetCookie(w, r, session.Cookie_TZ, timeZone)
-	return i18n.Sprintf("Timezone updated successfully to %s.", timeZone), nil
+	return i18n.SprintfContext(r.Context(), "Timezone updated successfully to %s.", timeZone), nil
 }
 
 func setNovelFontType(w http.ResponseWriter, r *http.Request) (string, error) {
 	fontType := r.FormValue("font-type")
 	if fontType != "" {
 		session.SetCookie(w, r, session.Cookie_NovelFontType, fontType)
-		return i18n.Sprintf("Novel font type updated successfully."), nil
+		return i18n.SprintfContext(r.Context(), "Novel font type updated successfully."), nil
 	}
 
-	return "", i18n.Error("Invalid font type.")
+	return "", i18n.ErrorContext(r.Context(), "Invalid font type.")
 }
 
 func setNovelViewMode(w http.ResponseWriter, r *http.Request) (string, error) {
 	viewMode := r.FormValue("view-mode")
 	if viewMode == "1" || viewMode == "2" || viewMode == "" {
 		session.SetCookie(w, r, session.Cookie_NovelViewMode, viewMode)
-		return i18n.Sprintf("Novel view mode updated successfully."), nil
+		return i18n.SprintfContext(r.Context(), "Novel view mode updated successfully."), nil
 	}
 
-	return "", i18n.Error("Invalid view mode.")
+	return "", i18n.ErrorContext(r.Context(), "Invalid view mode.")
 }
 
 func setThumbnailToNewTab(w http.ResponseWriter, r *http.Request) (string, error) {
 	ttnt := r.FormValue("ttnt")
 	if ttnt == "_blank" {
 		session.SetCookie(w, r, session.Cookie_ThumbnailToNewTab, ttnt)
-		return i18n.Sprintf("Thumbnails will now open in a new tab."), nil
+		return i18n.SprintfContext(r.Context(), "Thumbnails will now open in a new tab."), nil
 	}
 
 	session.SetCookie(w, r, session.Cookie_ThumbnailToNewTab, "_self")
-	return i18n.Sprintf("Thumbnails will now open in the same tab."), nil
+	return i18n.SprintfContext(r.Context(), "Thumbnails will now open in the same tab."), nil
 }
 
 func setArtworkPreview(w http.ResponseWriter, r *http.Request) (string, error) {
 	value := r.FormValue("app")
 	if value == "cover" || value == "button" || value == "" {
 		session.SetCookie(w, r, session.Cookie_ArtworkPreview, value)
-		return i18n.Sprintf("Artwork preview setting updated successfully."), nil
+		return i18n.SprintfContext(r.Context(), "Artwork preview setting updated successfully."), nil
 	}
 
-	return "", i18n.Error("Invalid artwork preview setting.")
+	return "", i18n.ErrorContext(r.Context(), "Invalid artwork preview setting.")
 }
 
 func setFilter(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -243,7 +244,7 @@ func setFilter(w http.ResponseWriter, r *http.Request) (string, error) {
 	session.SetCookie(w, r, session.Cookie_VisibilityArtR18G, visibilityArtR18G)
 	session.SetCookie(w, r, session.Cookie_VisibilityArtAI, visibilityArtAI)
 
-	return i18n.Sprintf("Filter settings updated successfully."), nil
+	return i18n.SprintfContext(r.Context(), "Filter settings updated successfully."), nil
 }
 
 func setLogout(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -257,7 +258,7 @@ func setLogout(w http.ResponseWriter, r *http.Request) (string, error) {
 	session.ClearCookie(w, r, session.Cookie_Username)
 	session.ClearCookie(w, r, session.Cookie_UserID)
 	session.ClearCookie(w, r, session.Cookie_UserAvatar)
-	return i18n.Sprintf("Successfully logged out."), nil
+	return i18n.SprintfContext(r.Context(), "Successfully logged out."), nil
 }
 
 func setCookie(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -267,10 +268,10 @@ func setCookie(w http.ResponseWriter, r *http.Request) (string, error) {
 	for _, cookieName := range session.AllCookieNames {
 		if string(cookieName) == key {
 			session.SetCookie(w, r, cookieName, value)
-			return i18n.Sprintf("Cookie %s set successfully.", key), nil
+			return i18n.SprintfContext(r.Context(), "Cookie %s set successfully.", key), nil
 		}
 	}
-	return "", i18n.Errorf("Invalid Cookie Name: %s", key)
+	return "", i18n.ErrorfContext(r.Context(), "Invalid Cookie Name: %s", key)
 }
 
 func clearCookie(w http.ResponseWriter, r *http.Request) (string, error) {
@@ -279,11 +280,11 @@ func clearCookie(w http.ResponseWriter, r *http.Request) (string, error) {
 	for _, cookieName := range session.AllCookieNames {
 		if string(cookieName) == key {
 			session.ClearCookie(w, r, cookieName)
-			return i18n.Sprintf("Cookie %s cleared successfully.", key), nil
+			return i18n.SprintfContext(r.Context(), "Cookie %s cleared successfully.", key), nil
 		}
 	}
 
-	return "", i18n.Errorf("Invalid Cookie Name: %s", key)
+	return "", i18n.ErrorfContext(r.Context(), "Invalid Cookie Name: %s", key)
 }
 
 // setRawCookie processes a multi-line string of key=value pairs
@@ -338,18 +339,17 @@ func setRawCookie(w http.ResponseWriter, r *http.Request) (string, error) {
 	// Applied count is the base message
 	msgApplied := ""
 	if appliedCount > 0 {
-		// Manually handle plurals since i18n.Sprintf doesn't
-		if appliedCount == 1 {
-			msgApplied = i18n.Sprintf("Applied 1 setting successfully")
-		} else {
-			msgApplied = i18n.Sprintf("Applied %d settings successfully", appliedCount)
-		}
+		msgApplied = i18n.MessageContext(r.Context(),
+			"Applied {count, plural, one {# setting} other {# settings}} successfully",
+			i18n.Args{"count": appliedCount})
 	}
 
 	// Skipped count is an optional addition
 	msgSkipped := ""
 	if skippedCount > 0 {
-		msgSkipped = i18n.Sprintf("Skipped %d invalid or unknown entries", skippedCount)
+		msgSkipped = i18n.MessageContext(r.Context(),
+			"Skipped {count, plural, one {# invalid or unknown entry} other {# invalid or unknown entries}}",
+			i18n.Args{"count": skippedCount})
 	}
 
 	// Combine the parts conditionally
@@ -365,7 +365,7 @@ func setRawCookie(w http.ResponseWriter, r *http.Request) (string, error) {
 		return fmt.Sprintf("No valid settings found. %s.", msgSkipped), nil
 	default: // appliedCount == 0 && skippedCount == 0
 		// Neither applied nor skipped
-		return i18n.Sprintf("No valid settings found in the input."), nil
+		return i18n.SprintfContext(r.Context(), "No valid settings found in the input."), nil
 	}
 }
 
@@ -375,7 +375,7 @@ func resetAll(w http.ResponseWriter, r *http.Request) (string, error) {
 
 	// Cookie clearing as fallback
 	session.ClearAllCookies(w, r)
-	return i18n.Sprintf("All preferences have been reset to default values."), nil
+	return i18n.SprintfContext(r.Context(), "All preferences have been reset to default values."), nil
 }
 
 func SettingsPage(w http.ResponseWriter, r *http.Request) error {
@@ -394,11 +394,13 @@ func SettingsPage(w http.ResponseWriter, r *http.Request) error {
 	return template.RenderHTML(w, r, Data_settings{
 		SelfSettings:       profile,
 		ProxyList:          config.BuiltInImageProxyList,
+		ProxyStatus:        proxychecker.StatusMap(),
 		DefaultProxyServer: config.GlobalConfig.ContentProxies.Image.String(),
+		AutoProxyEnabled:   config.GlobalConfig.ProxyCheck.Enabled,
 	})
 }
 
-func handleAJAXResponse(w http.ResponseWriter, message string, err error) {
+func handleAJAXResponse(w http.ResponseWriter, r *http.Request, message string, err error) {
 	w.Header().Set("Content-Type", "text/html")
 
 	var (
@@ -408,11 +410,11 @@ func handleAJAXResponse(w http.ResponseWriter, message string, err error) {
 
 	if err != nil {
 		statusCode = http.StatusBadRequest
-		html = i18n.Sprintf(
+		html = i18n.SprintfContext(r.Context(),
 			`<div class="form-htmx-target" hidden></div><div id="form-htmx-response" class="flex items-center w-fit bg-yellow-500/10 border border-yellow-500 text-yellow-100 fill-yellow-100 text-sm rounded-lg gap-4 py-3 px-4 transition-opacity duration-300"> %s<button type="button" class="group size-fit cursor-pointer hover:bg-yellow-500/20 active:scale-95 transition rounded-full p-1 -me-1" aria-label="Close" hx-on:click="const el = this.closest('#form-htmx-response'); el.style.opacity = '0'; setTimeout(() => el.remove(), 200)"><span class="material-symbols-rounded-20>close</span></button></div>`, err.Error())
 	} else {
 		statusCode = http.StatusOK
-		html = i18n.Sprintf(
+		html = i18n.SprintfContext(r.Context(),
 			`<div class="form-htmx-target" hidden></div><div id="form-htmx-response" class="flex items-center w-fit bg-blue-500/10 border border-blue-500 text-blue-100 fill-blue-100 text-sm rounded-lg gap-4 py-3 px-4 transition-opacity duration-300"> %s<button type="button" class="group size-fit cursor-pointer hover:bg-blue-500/20 active:scale-95 transition rounded-full p-1 -me-1" aria-label="Close" hx-on:click="const el = this.closest('#form-htmx-response'); el.style.opacity = '0'; setTimeout(() => el.remove(), 200)"><span class="material-symbols-rounded-20>close</span></button></div>`, message)
 	}
 
@@ -452,7 +454,7 @@ func SettingsPost(w http.ResponseWriter, r *http.Request) error {
 	if action, ok := actions[actionType]; ok {
 		message, err = action(w, r)
 	} else {
-		err = i18n.Error("No such setting is available.")
+		err = i18n.ErrorContext(r.Context(), "No such setting is available.")
 	}
 
 	isHtmx := r.Header.Get("HX-Request") == "true"
@@ -462,7 +464,7 @@ func SettingsPost(w http.ResponseWriter, r *http.Request) error {
 		if returnPath != "" && err == nil {
 			http.Redirect(w, r, returnPath, http.StatusSeeOther)
 		} else {
-			handleAJAXResponse(w, message, err)
+			handleAJAXResponse(w, r, message, err)
 		}
 		return nil
 	}
@@ -4,9 +4,13 @@
 package routes
 
 import (
+	"time"
+
 	"codeberg.org/pixivfe/pixivfe/core"
 	"codeberg.org/pixivfe/pixivfe/core/pixivision"
+	"codeberg.org/pixivfe/pixivfe/server/proxychecker"
 	"codeberg.org/pixivfe/pixivfe/server/template"
+	"codeberg.org/pixivfe/pixivfe/server/tokenmanager"
 )
 
 // Tutorial: adding new types in this file
@@ -69,11 +73,18 @@ type Data_discovery struct {
 	Queries           template.PartialURL
 	RequiresAsyncLoad bool // Determines if we should show loading skeleton and trigger async load
 }
+type Data_feedAtom struct {
+	Feed Feed
+}
+type Data_feedRSS struct {
+	Feed Feed
+}
 type Data_following struct {
-	Title string
-	Mode  string
-	Data  core.NewestFromFollowingResponse
-	Page  int
+	Title   string
+	Mode    string
+	Data    core.NewestFromFollowingResponse
+	Page    int
+	FeedURL string
 }
 type Data_index struct {
 	Title       string
@@ -161,7 +172,9 @@ type Data_rankingCalendar struct {
 type Data_settings struct {
 	SelfSettings       core.SettingsSelfResponse
 	ProxyList          []string
+	ProxyStatus        map[string]proxychecker.Status
 	DefaultProxyServer string
+	AutoProxyEnabled   bool // Whether the automatic image proxy can be selected
 }
 type Data_tag struct {
 	SearchQuery          string
@@ -188,16 +201,6 @@ type Data_user struct {
 	Page           int
 	MetaImage      string
 }
-type Data_userAtom struct {
-	URL       string
-	Title     string
-	User      core.User
-	Category  core.UserWorkCategory
-	Updated   string
-	PageLimit int
-	Page      int
-	// MetaImage string
-}
 type Data_userDiscovery struct {
 	Users   []core.User
 	Title   string
@@ -214,6 +217,16 @@ type (
 	Data_diagnostics struct{}
 )
 
+type Data_adminLogin struct {
+	Title string
+}
+
+type Data_adminTokens struct {
+	Title  string
+	Tokens []tokenmanager.TokenSnapshot
+	Now    time.Time
+}
+
 type Data_addBookmarkPartial struct {
 	Illust core.Illust
 }
@@ -0,0 +1,60 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package routes
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/core"
+	"codeberg.org/pixivfe/pixivfe/core/ugoira"
+	"codeberg.org/pixivfe/pixivfe/i18n"
+	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
+	"codeberg.org/pixivfe/pixivfe/server/utils"
+)
+
+// ugoiraWriteTimeout is how long rendering and serving an ugoira may take.
+//
+// It replaces the write timeout of the server, which is too short for ugoira
+// that haven't been rendered before.
+const ugoiraWriteTimeout = 3 * time.Minute
+
+// UgoiraRender serves an ugoira artwork rendered as an animated image by the
+// built-in renderer, in the format given by the extension of the path.
+func UgoiraRender(w http.ResponseWriter, r *http.Request) error {
+	id := GetPathVar(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid ID: %s", id)
+	}
+
+	format, err := ugoira.ParseFormat(GetPathVar(r, "format"))
+	if err != nil {
+		requestcontext.FromRequest(r).StatusCode = http.StatusBadRequest
+
+		return i18n.ErrorfContext(r.Context(), "Invalid ugoira format: %s", GetPathVar(r, "format"))
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(ugoiraWriteTimeout)); err != nil &&
+		!errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	data, err := core.RenderUgoira(r, id, format)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", format.MediaType())
+	w.Header().Set("ETag", `"`+utils.GenerateETag(data)+`"`)
+
+	// ServeContent handles conditional and range requests
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+
+	return nil
+}
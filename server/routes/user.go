@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"codeberg.org/pixivfe/pixivfe/audit"
 	"codeberg.org/pixivfe/pixivfe/config"
@@ -60,27 +59,6 @@ func UserPage(w http.ResponseWriter, r *http.Request) error {
 	})
 }
 
-func UserAtomFeed(w http.ResponseWriter, r *http.Request) error {
-	data, err := fetchData(r, false)
-	if err != nil {
-		return err
-	}
-
-	// TODO: need to handle following status
-	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d",
-		int(config.GlobalConfig.HTTPCache.MaxAge.Seconds()),
-		int(config.GlobalConfig.HTTPCache.StaleWhileRevalidate.Seconds())))
-
-	return template.RenderWithContentType(w, r, "application/atom+xml", Data_userAtom{
-		URL:      r.RequestURI,
-		Title:    data.user.Name,
-		User:     data.user,
-		Category: data.category,
-		Updated:  time.Now().Format(time.RFC3339),
-		Page:     data.page,
-	})
-}
-
 func fetchData(r *http.Request, getTags bool) (userPageData, error) {
 	id := GetPathVar(r, "id")
 	if _, err := strconv.Atoi(id); err != nil {
@@ -89,7 +67,7 @@ func fetchData(r *http.Request, getTags bool) (userPageData, error) {
 
 	categoryValue := GetPathVar(r, "category", core.CategoryAny.Value)
 	category := core.NewUserWorkCategory(categoryValue)
-	err := category.Validate()
+	err := category.Validate(r.Context())
 	if err != nil {
 		return userPageData{}, err
 	}
@@ -0,0 +1,91 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Admin sessions (Set by the /admin/login page)
+
+The admin session cookie holds the time it was issued and an HMAC-SHA256 signature
+over that time and the admin token, keyed with a secret generated when the process
+starts. Sessions therefore expire after adminSessionMaxAge, and are invalidated when
+the process restarts or the admin token changes. The cookie can't be used as the
+admin token if it leaks.
+*/
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adminSessionMaxAge is how long an admin session is valid for after logging in.
+const adminSessionMaxAge = 12 * time.Hour
+
+// adminSessionSecret is the HMAC key used for signing admin sessions.
+var adminSessionSecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("failed to generate admin session secret: " + err.Error())
+	}
+
+	return secret
+}()
+
+// NewAdminSession returns the value of a new admin session cookie for adminToken.
+func NewAdminSession(adminToken string) string {
+	return newAdminSession(adminToken, time.Now())
+}
+
+func newAdminSession(adminToken string, issued time.Time) string {
+	timestamp := strconv.FormatInt(issued.Unix(), 10)
+
+	return timestamp + "." + adminSessionSignature(adminToken, timestamp)
+}
+
+// VerifyAdminSession reports whether value is an unexpired admin session cookie
+// issued by this process for adminToken.
+func VerifyAdminSession(value, adminToken string) bool {
+	timestamp, signature, found := strings.Cut(value, ".")
+	if !found {
+		return false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(adminSessionSignature(adminToken, timestamp))) {
+		return false
+	}
+
+	issued, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	age := time.Since(time.Unix(issued, 0))
+
+	return age >= 0 && age < adminSessionMaxAge
+}
+
+// SetAdminSessionCookie sets the admin session cookie to value, expiring along with the session.
+func SetAdminSessionCookie(w http.ResponseWriter, r *http.Request, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     string(Cookie_AdminSession),
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(adminSessionMaxAge),
+		HttpOnly: true,
+		Secure:   ShouldCookieBeSecure(r),
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// adminSessionSignature signs timestamp for adminToken.
+func adminSessionSignature(adminToken, timestamp string) string {
+	mac := hmac.New(sha256.New, adminSessionSecret)
+	mac.Write([]byte(timestamp + "\x00" + adminToken))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
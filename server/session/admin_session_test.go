@@ -0,0 +1,37 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+// TestVerifyAdminSession verifies that admin sessions are only accepted for the
+// token they were issued for, until they expire.
+func TestVerifyAdminSession(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"Valid", newAdminSession("secret", now), true},
+		{"Other token", newAdminSession("other", now), false},
+		{"Expired", newAdminSession("secret", now.Add(-adminSessionMaxAge)), false},
+		{"Issued in the future", newAdminSession("secret", now.Add(time.Hour)), false},
+		{"Tampered timestamp", "1" + newAdminSession("secret", now), false},
+		{"Raw token", "secret", false},
+		{"Empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyAdminSession(tt.value, "secret"); got != tt.want {
+				t.Errorf("VerifyAdminSession() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -42,6 +42,10 @@ const (
 	Cookie_LogoStyle               CookieName = "pixivfe-LogoStyle"
 	Cookie_BlacklistArtist         CookieName = "pixivfe-BlacklistArtist"
 	Cookie_BlacklistTag            CookieName = "pixivfe-BlacklistTag"
+
+	// Cookie_AdminSession is deliberately left out of AllCookieNames, so that
+	// it can't be read or written through the settings page.
+	Cookie_AdminSession CookieName = "pixivfe-AdminSession"
 )
 
 // Go can't make this a const...
@@ -0,0 +1,123 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+/*
+Feed keys (Tokens embedded in feed URLs)
+
+Feed readers can't log in, so feeds of personal timelines are authenticated with a
+key query parameter instead of the pixivfe-Token cookie. The key is the user's token,
+encrypted with AES-GCM using a key derived from Feature.FeedSecret, which both keeps
+the token out of server logs and prevents keys from being forged or tampered with.
+*/
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+)
+
+// feedKeyAdditionalData binds feed keys to their purpose, so that ciphertexts
+// produced with the same secret for anything else aren't accepted.
+var feedKeyAdditionalData = []byte("pixivfe-FeedKey")
+
+var (
+	// ErrFeedKeysDisabled is returned when Feature.FeedSecret is not configured.
+	ErrFeedKeysDisabled = errors.New("feed keys are disabled as no feed secret is configured")
+
+	// ErrInvalidFeedKey is returned when a feed key can't be decrypted.
+	ErrInvalidFeedKey = errors.New("invalid feed key")
+)
+
+// FeedKeysEnabled reports whether feed keys can be created and opened.
+func FeedKeysEnabled() bool {
+	return config.GlobalConfig.Feature.FeedSecret != ""
+}
+
+// NewFeedKey encrypts token into a feed key that is safe to use in a URL.
+func NewFeedKey(token string) (string, error) {
+	aead, err := feedKeyAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(token), feedKeyAdditionalData)
+
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// OpenFeedKey decrypts a feed key created by NewFeedKey, returning the token.
+func OpenFeedKey(key string) (string, error) {
+	aead, err := feedKeyAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidFeedKey
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	token, err := aead.Open(nil, nonce, ciphertext, feedKeyAdditionalData)
+	if err != nil {
+		return "", ErrInvalidFeedKey
+	}
+
+	return string(token), nil
+}
+
+// WithFeedKey returns a shallow copy of r authenticated with the token in the
+// feed key, so that GetUserToken returns it.
+//
+// The pixivfe-Token cookie of r, if any, is replaced.
+func WithFeedKey(r *http.Request, key string) (*http.Request, error) {
+	token, err := OpenFeedKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := r.Clone(r.Context())
+	clone.Header.Del("Cookie")
+
+	for _, cookie := range r.Cookies() {
+		if cookie.Name != string(Cookie_Token) {
+			clone.AddCookie(cookie)
+		}
+	}
+
+	clone.AddCookie(&http.Cookie{Name: string(Cookie_Token), Value: url.QueryEscape(token)})
+
+	return clone, nil
+}
+
+// feedKeyAEAD returns the AES-GCM cipher used for feed keys.
+func feedKeyAEAD() (cipher.AEAD, error) {
+	secret := config.GlobalConfig.Feature.FeedSecret
+	if secret == "" {
+		return nil, ErrFeedKeysDisabled
+	}
+
+	key := sha256.Sum256([]byte(secret))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package session
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"codeberg.org/pixivfe/pixivfe/config"
+)
+
+// TestFeedKey verifies that feed keys round-trip to the original token,
+// and that tampered keys or keys created with another secret are rejected.
+func TestFeedKey(t *testing.T) {
+	const token = "12345678_abcdefghijklmnopqrstuvwxyz"
+
+	config.GlobalConfig.Feature.FeedSecret = "0123456789abcdef0123456789abcdef"
+	t.Cleanup(func() { config.GlobalConfig.Feature.FeedSecret = "" })
+
+	key, err := NewFeedKey(token)
+	if err != nil {
+		t.Fatalf("unexpected error creating feed key: %v", err)
+	}
+
+	got, err := OpenFeedKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error opening feed key: %v", err)
+	}
+
+	if got != token {
+		t.Errorf("got token %q, want %q", got, token)
+	}
+
+	r := httptest.NewRequest("GET", "/self/followingWorks.atom.xml", nil)
+	r.Header.Set("Cookie", "pixivfe-Token=other; pixivfe-Locale=ja")
+
+	keyed, err := WithFeedKey(r, key)
+	if err != nil {
+		t.Fatalf("unexpected error authenticating request: %v", err)
+	}
+
+	if got := GetUserToken(keyed); got != token {
+		t.Errorf("got user token %q, want %q", got, token)
+	}
+
+	if got := GetCookie(keyed, Cookie_Locale); got != "ja" {
+		t.Errorf("got locale %q, want other cookies to be kept", got)
+	}
+
+	tampered := []byte(key)
+	tampered[len(tampered)/2] ^= 1
+
+	if _, err := OpenFeedKey(string(tampered)); !errors.Is(err, ErrInvalidFeedKey) {
+		t.Errorf("tampered key: got error %v, want %v", err, ErrInvalidFeedKey)
+	}
+
+	config.GlobalConfig.Feature.FeedSecret = "fedcba9876543210fedcba9876543210"
+
+	if _, err := OpenFeedKey(key); !errors.Is(err, ErrInvalidFeedKey) {
+		t.Errorf("key from another secret: got error %v, want %v", err, ErrInvalidFeedKey)
+	}
+
+	config.GlobalConfig.Feature.FeedSecret = ""
+
+	if _, err := NewFeedKey(token); !errors.Is(err, ErrFeedKeysDisabled) {
+		t.Errorf("without secret: got error %v, want %v", err, ErrFeedKeysDisabled)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 
 	"codeberg.org/pixivfe/pixivfe/config"
+	"codeberg.org/pixivfe/pixivfe/server/proxychecker"
 )
 
 // GetUserToken retrieves an authentication token for
@@ -20,9 +21,16 @@ func GetUserToken(r *http.Request) string {
 // GetImageProxy returns the content proxy URL for i.pximg.net content.
 //
 // The proxy URL is retrieved from cookies if available, otherwise falls back
-// to the default configuration.
+// to the default configuration. The automatic proxy resolves to the fastest
+// healthy proxy, or the built-in proxy if none is healthy.
 func GetImageProxy(r *http.Request) url.URL {
-	return getProxy(r, Cookie_ImageProxy, config.GlobalConfig.ContentProxies.Image)
+	proxy := getProxy(r, Cookie_ImageProxy, config.GlobalConfig.ContentProxies.Image)
+
+	if proxy.Host == "" && proxy.Path == config.AutoImageProxy {
+		return proxychecker.Fastest()
+	}
+
+	return proxy
 }
 
 // GetStaticProxy returns the content proxy URL for s.pximg.net content.
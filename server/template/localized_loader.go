@@ -18,6 +18,8 @@ import (
 type LocalizedFSLoader struct {
 	// Dir is the slash-separated base directory for resolving template paths within assets.FS.
 	Dir string
+	// Locale is the locale that templates are localized to.
+	Locale string
 }
 
 // Exists checks if a template file exists in assets.FS.
@@ -42,14 +44,12 @@ func (l *LocalizedFSLoader) Exists(templatePath string) bool {
 //
 // Falls back to unlocalized content if no i18n replacer is found.
 func (l *LocalizedFSLoader) Open(templatePath string) (io.ReadCloser, error) {
-	locale := i18n.GetLocale()
-
 	// path.Join ensures the resulting path uses slashes, suitable for fs.FS and i18n keys.
 	resourcePath := path.Join(l.Dir, templatePath)
 
 	// println("load replacer:", resourcePath)
 
-	replacer := i18n.Replacer(locale, resourcePath)
+	replacer := i18n.Replacer(l.Locale, resourcePath)
 	if replacer == nil {
 		// Fallback to unlocalized content if no replacer is found for the locale/path.
 		return assets.FS.Open(resourcePath)
@@ -63,11 +63,13 @@ func (l *LocalizedFSLoader) Open(templatePath string) (io.ReadCloser, error) {
 	return io.NopCloser(strings.NewReader(replacer.Replace(string(content)))), nil
 }
 
-// newLocalizedFSLoader creates a new LocalizedFSLoader with the specified base directory.
+// newLocalizedFSLoader creates a new LocalizedFSLoader with the specified base directory
+// and locale.
 //
 // dir is expected to be a slash-separated path.
-func newLocalizedFSLoader(dir string) *LocalizedFSLoader {
+func newLocalizedFSLoader(dir, locale string) *LocalizedFSLoader {
 	return &LocalizedFSLoader{
-		Dir: dir,
+		Dir:    dir,
+		Locale: locale,
 	}
 }
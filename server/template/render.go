@@ -7,38 +7,81 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"mime"
 	"net/http"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"codeberg.org/pixivfe/pixivfe/i18n"
 	"codeberg.org/pixivfe/pixivfe/server/requestcontext"
 	"codeberg.org/pixivfe/pixivfe/server/session"
 	"codeberg.org/pixivfe/pixivfe/server/utils"
 	"github.com/CloudyKit/jet/v6"
 	"github.com/tdewolff/minify/v2"
 	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/xml"
 )
 
-var views *jet.Set // global variable, yes.
+var (
+	viewsMu      sync.Mutex
+	views        = map[string]*jet.Set{} // Template sets by locale
+	disableCache bool
+)
+
+// xmlMediaType matches the media types of XML-based formats, such as application/atom+xml.
+var xmlMediaType = regexp.MustCompile(`[/+]xml$`)
 
 // Setup initializes the template engine.
-func Setup(disableCache bool) {
-	loader := newLocalizedFSLoader("assets/views")
+//
+// Templates are localized when loaded, so each locale gets its own template set,
+// created the first time a template is rendered in it.
+func Setup(disable bool) {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+
+	disableCache = disable
+	clear(views)
+}
+
+// viewsFor returns the template set for locale, creating it if needed.
+//
+// Locales without translations share the set of i18n.BaseLocale.
+func viewsFor(locale string) *jet.Set {
+	if !i18n.HasLocale(locale) {
+		locale = i18n.BaseLocale
+	}
+
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+
+	if set, ok := views[locale]; ok {
+		return set
+	}
+
+	loader := newLocalizedFSLoader("assets/views", locale)
+
+	var set *jet.Set
 	if disableCache {
-		views = jet.NewSet(
+		set = jet.NewSet(
 			loader,
 			jet.InDevelopmentMode(), // disable cache
 		)
 	} else {
-		views = jet.NewSet(
+		set = jet.NewSet(
 			loader,
 		)
 	}
 
-	for fnName, fn := range getTemplateFunctions() {
-		views.AddGlobal(fnName, fn)
+	for fnName, fn := range getTemplateFunctions(locale) {
+		set.AddGlobal(fnName, fn)
 	}
+
+	views[locale] = set
+
+	return set
 }
 
 func RenderHTML[T any](w http.ResponseWriter, r *http.Request, data T) error {
@@ -47,7 +90,7 @@ func RenderHTML[T any](w http.ResponseWriter, r *http.Request, data T) error {
 
 func RenderWithContentType[T any](w http.ResponseWriter, r *http.Request, contentType string, data T) error {
 	// Render and get ETag
-	content, etag, serverTiming, err := Render(getTemplatingVariables(r), data)
+	content, etag, serverTiming, err := Render(getTemplatingVariables(r), i18n.LocaleFrom(r.Context()), contentType, data)
 	if err != nil {
 		return err
 	}
@@ -71,11 +114,13 @@ func RenderWithContentType[T any](w http.ResponseWriter, r *http.Request, conten
 	// Write Server-Timing header
 	w.Header().Add("Server-Timing", serverTiming)
 
-	// Set Vary: Cookie to prevent user preferences from affecting the shared cache
+	// Set Vary: Cookie to prevent user preferences from affecting the shared cache,
+	// and Vary: Accept-Language as the locale is negotiated from it without a cookie
 	//
 	// This negatively affects HTTP cache hit rate, but we don't have the option of
 	// client-side hydration via JS nor ESI so these will have to do
-	w.Header().Set("Vary", "Cookie")
+	w.Header().Add("Vary", "Cookie")
+	w.Header().Add("Vary", "Accept-Language")
 
 	w.WriteHeader(requestcontext.FromRequest(r).StatusCode)
 
@@ -84,7 +129,14 @@ func RenderWithContentType[T any](w http.ResponseWriter, r *http.Request, conten
 	return err
 }
 
-func Render[T any](variables jet.VarMap, data T) ([]byte, string, string, error) {
+// Render executes the template for data, localized to locale, and minifies the result
+// according to contentType, which must be HTML or an XML-based format.
+func Render[T any](variables jet.VarMap, locale, contentType string, data T) ([]byte, string, string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+
 	templateName, found := strings.CutPrefix(reflect.TypeFor[T]().Name(), "Data_")
 	if !found {
 		log.Panicf("struct name does not start with 'Data_': %s", templateName)
@@ -96,6 +148,8 @@ func Render[T any](variables jet.VarMap, data T) ([]byte, string, string, error)
 		templatePath = "partials/" + templatePath
 	}
 
+	views := viewsFor(locale)
+
 	template, err := views.GetTemplate(templatePath)
 	if err != nil {
 		return nil, "", "", err
@@ -112,7 +166,8 @@ func Render[T any](variables jet.VarMap, data T) ([]byte, string, string, error)
 	// Create minifier
 	m := minify.New()
 	m.AddFunc("text/html", html.Minify)
-	minWriter := m.Writer("text/html", buf)
+	m.AddFuncRegexp(xmlMediaType, xml.Minify)
+	minWriter := m.Writer(mediaType, buf)
 
 	// Execute template directly to minifier writer
 	if err := template.Execute(minWriter, variables, data); err != nil {
@@ -4,6 +4,7 @@
 package template
 
 import (
+	"context"
 	"fmt"
 	"html"
 	"html/template"
@@ -602,96 +603,75 @@ func ParseTimeCustomFormat(date time.Time, format string) string {
 	return date.Format(format)
 }
 
-// NaturalTime formats a time.Time value as a natural language string.
-//
-// TODO: tailor the format per locale.
-func NaturalTime(date time.Time) string {
-	return date.Format("Monday, 2 January 2006, at 3:04 PM")
+// NaturalTime formats a time.Time value as a natural language string in locale.
+func NaturalTime(locale string, date time.Time) string {
+	f := i18n.FormatsFor(locale)
+
+	return f.Date(date, f.DateTime)
 }
 
-// RelativeTime returns a RelativeTimeData struct with a relative description based on the given date.
+// RelativeTime returns a RelativeTimeData struct with a relative description based on the given date,
+// formatted in locale.
 //
 // The "Yesterday" case is special and is triggered when the date matches exactly the previous
 // calendar day (i.e., same year, month, and previous day), regardless of the exact number of
 // hours that have elapsed.
-//
-// TODO: tailor the format per locale.
-func RelativeTime(date time.Time) RelativeTimeData {
+func RelativeTime(locale string, date time.Time) RelativeTimeData {
+	f := i18n.FormatsFor(locale)
 	now := time.Now()
 	duration := now.Sub(date)
 
-	// local helper function to choose the correct singular/plural unit.
-	pluralize := func(value int, singular, plural string) string {
-		if value == 1 {
-			return singular
+	// local helper function to describe value units ago.
+	ago := func(value int, unit i18n.RelativeUnit) RelativeTimeData {
+		return RelativeTimeData{
+			Value:       f.Cardinal(f.Relative[unit], value),
+			Description: f.Ago,
 		}
-
-		return plural
 	}
 
 	// For future dates, simply show the day and full date/time formatting.
 	if duration < 0 {
 		return RelativeTimeData{
-			Value:       date.Format("2"),
-			Description: date.Format("January 2006 3:04 PM"),
+			Value:       f.Number(date.Day()),
+			Description: f.Date(date, f.MonthYear) + " " + f.Date(date, f.Time),
 		}
 	}
 
 	// Less than one minute ago.
 	if duration < time.Minute {
 		return RelativeTimeData{
-			Value: "Just now",
+			Value: f.JustNow,
 		}
 	}
 
 	// Less than one hour: display minutes.
 	if duration < time.Hour {
-		minutes := int(duration.Minutes())
-
-		return RelativeTimeData{
-			Value:       fmt.Sprintf("%d %s", minutes, pluralize(minutes, "minute", "minutes")),
-			Description: "ago",
-		}
+		return ago(int(duration.Minutes()), i18n.Minute)
 	}
 
 	// Less than one day: display hours.
 	if duration < 24*time.Hour {
-		hours := int(duration.Hours())
-
-		return RelativeTimeData{
-			Value:       fmt.Sprintf("%d %s", hours, pluralize(hours, "hour", "hours")),
-			Description: "ago",
-		}
+		return ago(int(duration.Hours()), i18n.Hour)
 	}
 
 	// Check if the date corresponds to 'yesterday'
 	yesterday := now.AddDate(0, 0, -1)
 	if date.Year() == yesterday.Year() && date.Month() == yesterday.Month() && date.Day() == yesterday.Day() {
 		return RelativeTimeData{
-			Value:       "Yesterday",
-			Description: "at",
-			Time:        date.Format("3:04 PM"),
+			Value:       f.Yesterday,
+			Description: f.At,
+			Time:        f.Date(date, f.Time),
 		}
 	}
 
 	// Less than one week: display days.
 	if duration < 7*24*time.Hour {
-		days := int(duration.Hours() / 24)
-
-		return RelativeTimeData{
-			Value:       fmt.Sprintf("%d %s", days, pluralize(days, "day", "days")),
-			Description: "ago",
-		}
+		return ago(int(duration.Hours()/24), i18n.Day)
 	}
 
 	// Less than one month (using a 31-day threshold): display weeks.
 	if duration < 31*24*time.Hour {
-		weeks := int(duration.Hours() / (24 * 7))
-
-		return RelativeTimeData{
-			Value:       fmt.Sprintf("%d %s", weeks, pluralize(weeks, "week", "weeks")),
-			Description: "ago",
-		}
+		return ago(int(duration.Hours()/(24*7)), i18n.Week)
 	}
 
 	// Calculate total month difference (ignoring day differences for simplicity).
@@ -701,77 +681,21 @@ func RelativeTime(date time.Time) RelativeTimeData {
 
 	// Less than one year: display months.
 	if months < 12 {
-		return RelativeTimeData{
-			Value:       fmt.Sprintf("%d %s", months, pluralize(months, "month", "months")),
-			Description: "ago",
-		}
+		return ago(months, i18n.Month)
 	}
 
 	// Otherwise, show years.
-	years := months / 12
-
-	return RelativeTimeData{
-		Value:       fmt.Sprintf("%d %s", years, pluralize(years, "year", "years")),
-		Description: "ago",
-	}
+	return ago(months/12, i18n.Year)
 }
 
-// PrettyNumber pretty prints an integer with commas as thousands separators.
-//
-// Negative numbers are handled by first setting aside the sign.
-func PrettyNumber(n int) string {
-	// Determine sign
-	sign := ""
-	if n < 0 {
-		sign = "-"
-		n = -n // work with an absolute value
-	}
-
-	// Convert the integer to a string using strconv.Itoa.
-	numStr := strconv.Itoa(n)
-
-	// If there are no more than three digits, no comma is needed.
-	if len(numStr) <= 3 {
-		return sign + numStr
-	}
-
-	// Build digit groups from right to left.
-	var groups []string
-	for len(numStr) > 3 {
-		// Extract the last three digits.
-		groups = append([]string{numStr[len(numStr)-3:]}, groups...)
-		// Remove the processed group.
-		numStr = numStr[:len(numStr)-3]
-	}
-	// Prepend any remaining digits.
-	if len(numStr) > 0 {
-		groups = append([]string{numStr}, groups...)
-	}
-
-	// Join the groups with commas, and add a preceding negative sign if needed.
-	return sign + strings.Join(groups, ",")
+// PrettyNumber pretty prints an integer with the thousands separators of locale.
+func PrettyNumber(locale string, n int) string {
+	return i18n.FormatsFor(locale).Number(n)
 }
 
-// OrdinalNumeral returns an integer as its ordinal form in a string.
-func OrdinalNumeral(num int) string {
-	// Handle special cases for 11, 12, 13
-	lastTwo := num % 100
-	if lastTwo == 11 || lastTwo == 12 || lastTwo == 13 {
-		return strconv.Itoa(num) + "th"
-	}
-
-	// Handle cases based on last digit
-	lastDigit := num % 10
-	switch lastDigit {
-	case 1:
-		return strconv.Itoa(num) + "st"
-	case 2:
-		return strconv.Itoa(num) + "nd"
-	case 3:
-		return strconv.Itoa(num) + "rd"
-	default:
-		return strconv.Itoa(num) + "th"
-	}
+// OrdinalNumeral returns an integer as its ordinal form in locale.
+func OrdinalNumeral(locale string, num int) string {
+	return i18n.FormatsFor(locale).OrdinalNumber(num)
 }
 
 // CreatePaginator generates pagination data based on the current page and maximum number of pages.
@@ -904,12 +828,13 @@ var genreMap = map[string]string{
 	"17": "Other",
 }
 
-// GetNovelGenre returns the genre name for a given genre ID.
-func GetNovelGenre(s string) string {
+// GetNovelGenre returns the genre name for a given genre ID, translated to the
+// locale of ctx.
+func GetNovelGenre(ctx context.Context, s string) string {
 	if genre, ok := genreMap[s]; ok {
-		return i18n.Tr(genre)
+		return i18n.TrContext(ctx, genre)
 	}
-	return i18n.Sprintf("(Unknown Genre: %s)", s)
+	return i18n.SprintfContext(ctx, "(Unknown Genre: %s)", s)
 }
 
 // IsFirstPathPart checks if the first part of the current path matches the given path.
@@ -1048,30 +973,22 @@ func FormatWorkIDs(ids []int) string {
 	return strings.Join(strIDs, ",")
 }
 
-var (
-	furiganaPattern = regexp.MustCompile(`\[\[rb:\s*(.+?)\s*>\s*(.+?)\s*\]\]`)
-	chapterPattern  = regexp.MustCompile(`\[chapter:\s*(.+?)\s*\]`)
-	jumpURIPattern  = regexp.MustCompile(`\[\[jumpuri:\s*(.+?)\s*>\s*(.+?)\s*\]\]`)
-	jumpPagePattern = regexp.MustCompile(`\[jump:\s*(\d+?)\s*\]`)
-	newPagePattern  = regexp.MustCompile(`\s*\[newpage\]\s*`)
-)
-
 func ParseNovelContent(s string) HTML {
 	// Replace furigana markup with HTML ruby tags
 	furiganaTemplate := `<ruby>$1<rp>(</rp><rt>$2</rt><rp>)</rp></ruby>`
-	s = furiganaPattern.ReplaceAllString(s, furiganaTemplate)
+	s = core.NovelFuriganaPattern.ReplaceAllString(s, furiganaTemplate)
 
 	// Replace chapter markup with HTML h2 tags
 	chapterTemplate := `<h2>$1</h2>`
-	s = chapterPattern.ReplaceAllString(s, chapterTemplate)
+	s = core.NovelChapterPattern.ReplaceAllString(s, chapterTemplate)
 
 	// Replace jump URI markup with HTML anchor tags
 	jumpURITemplate := `<a href="$2" target="_blank">$1</a>`
-	s = jumpURIPattern.ReplaceAllString(s, jumpURITemplate)
+	s = core.NovelJumpURIPattern.ReplaceAllString(s, jumpURITemplate)
 
 	// Replace jump page markup with HTML anchor tags
 	jumpPageTemplate := `<a href="#$1">To page $1</a>`
-	s = jumpPagePattern.ReplaceAllString(s, jumpPageTemplate)
+	s = core.NovelJumpPagePattern.ReplaceAllString(s, jumpPageTemplate)
 
 	// Handle newpage markup
 	if strings.Contains(s, "[newpage]") {
@@ -1080,7 +997,7 @@ func ParseNovelContent(s string) HTML {
 		pageIdx := 1
 
 		// Create a slice of all matches
-		matches := newPagePattern.FindAllString(s, -1)
+		matches := core.NovelNewPagePattern.FindAllString(s, -1)
 
 		// Replace each match one by one
 		for _, match := range matches {
@@ -1208,9 +1125,18 @@ func ShouldHide(CookieList map[string]string, work_ any) (bool, string) {
 	return false, ""
 }
 
-// getTemplateFunctions returns a map of custom template functions for use in HTML templates.
-func getTemplateFunctions() template.FuncMap {
+// getTemplateFunctions returns a map of custom template functions for use in HTML templates
+// localized to locale.
+func getTemplateFunctions(locale string) template.FuncMap {
+	ctx := i18n.WithLocale(context.Background(), locale)
+
 	return template.FuncMap{
+		"locale": func() string {
+			return locale
+		},
+		"message": func(message string, args map[string]any) string {
+			return i18n.FormatMessage(locale, message, args)
+		},
 		"icon": RenderIcon,
 		"parseEmojis": func(s string) HTML {
 			return ParseEmojis(s)
@@ -1248,13 +1174,13 @@ func getTemplateFunctions() template.FuncMap {
 			return ParseTimeCustomFormat(date, format)
 		},
 		"naturalTime": func(date time.Time) string {
-			return NaturalTime(date)
+			return NaturalTime(locale, date)
 		},
 		"relativeTime": func(date time.Time) RelativeTimeData {
-			return RelativeTime(date)
+			return RelativeTime(locale, date)
 		},
 		"prettyNumber": func(n int) string {
-			return PrettyNumber(n)
+			return PrettyNumber(locale, n)
 		},
 		"createPaginator": func(base, ending string, current_page, max_page, page_margin, dropdown_offset int) PaginationData {
 			paginationData, err := CreatePaginator(base, ending, current_page, max_page, page_margin, dropdown_offset)
@@ -1268,17 +1194,24 @@ func getTemplateFunctions() template.FuncMap {
 		"parseNovelContent": func(s string) HTML {
 			return ParseNovelContent(s)
 		},
-		"getNovelGenre": GetNovelGenre,
+		"getNovelGenre": func(s string) string {
+			return GetNovelGenre(ctx, s)
+		},
 		"floor": func(i float64) int {
 			return int(math.Floor(i))
 		},
 		"pixivisionCategoryID": PixivisionCategoryID,
-		"ordinalNumeral":       OrdinalNumeral,
-		"unfinishedQuery":      unfinishedQuery,
-		"replaceQuery":         replaceQuery,
-		"isFirstPathPart":      IsFirstPathPart,
-		"isLastPathPart":       IsLastPathPart,
-		"IsFullPath":           IsFullPath,
+		"ordinalNumeral": func(num int) string {
+			return OrdinalNumeral(locale, num)
+		},
+		"tagTranslation": func(tag core.Tag) string {
+			return tag.Translation(locale)
+		},
+		"unfinishedQuery": unfinishedQuery,
+		"replaceQuery":    replaceQuery,
+		"isFirstPathPart": IsFirstPathPart,
+		"isLastPathPart":  IsLastPathPart,
+		"IsFullPath":      IsFullPath,
 		"shouldHide": func(x map[string]string, y any) bool {
 			a, reason := ShouldHide(x, y)
 			_ = reason
@@ -1291,5 +1224,6 @@ func getTemplateFunctions() template.FuncMap {
 		},
 
 		"FormatWorkIDs": FormatWorkIDs,
+		"imageSrcset":   core.ImageSrcset,
 	}
 }
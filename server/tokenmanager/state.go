@@ -0,0 +1,148 @@
+// Copyright 2023 - 2025, VnPower and the PixivFE contributors
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package tokenmanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/pixivfe/pixivfe/core/atomicfile"
+	"github.com/goccy/go-json"
+)
+
+const (
+	stateDirPermissions  os.FileMode = 0o700
+	stateFilePermissions os.FileMode = 0o600
+)
+
+// persistedState is the on-disk representation of the token state.
+//
+// Tokens are keyed by tokenID rather than by value, so that token values
+// aren't written to disk.
+type persistedState struct {
+	Tokens map[string]persistedToken `json:"tokens"`
+}
+
+// persistedToken is the persisted metadata of a single token.
+type persistedToken struct {
+	Invalid      bool      `json:"invalid,omitempty"`
+	FailureCount int       `json:"failureCount"`
+	TimeoutUntil time.Time `json:"timeoutUntil"`
+	LastUsed     time.Time `json:"lastUsed"`
+	LastSuccess  time.Time `json:"lastSuccess"`
+}
+
+// tokenID returns a stable identifier for a token value that doesn't reveal the value.
+func tokenID(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// PersistState restores the token state previously saved to path, if any,
+// and saves the state to path whenever a token is timed out or recovers.
+//
+// Tokens that were timed out when the state was saved stay timed out until
+// their timeout expires, and invalid tokens stay invalid. State for tokens that are no longer configured is ignored.
+func (tm *TokenManager) PersistState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read token state file %s: %w", path, err)
+	}
+
+	var state persistedState
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to decode token state file %s: %w", path, err)
+		}
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.statePath = path
+	now := time.Now()
+
+	for _, token := range tm.tokens {
+		saved, ok := state.Tokens[tokenID(token.Value)]
+		if !ok {
+			continue
+		}
+
+		token.FailureCount = saved.FailureCount
+		token.TimeoutUntil = saved.TimeoutUntil
+		token.LastUsed = saved.LastUsed
+		token.LastSuccess = saved.LastSuccess
+
+		switch {
+		case saved.Invalid:
+			token.Status = Invalid
+		case saved.TimeoutUntil.After(now):
+			token.Status = TimedOut
+		}
+	}
+
+	return nil
+}
+
+// SaveState writes the current token state to the path given to PersistState.
+//
+// It does nothing if state persistence isn't enabled.
+func (tm *TokenManager) SaveState() error {
+	tm.saveMu.Lock()
+	defer tm.saveMu.Unlock()
+
+	// The state is captured while holding saveMu, so that concurrent saves
+	// can't overwrite a newer state with an older one.
+	tm.mu.Lock()
+
+	path := tm.statePath
+	if path == "" {
+		tm.mu.Unlock()
+
+		return nil
+	}
+
+	state := persistedState{Tokens: make(map[string]persistedToken, len(tm.tokens))}
+
+	for _, token := range tm.tokens {
+		state.Tokens[tokenID(token.Value)] = persistedToken{
+			Invalid:      token.Status == Invalid,
+			FailureCount: token.FailureCount,
+			TimeoutUntil: token.TimeoutUntil,
+			LastUsed:     token.LastUsed,
+			LastSuccess:  token.LastSuccess,
+		}
+	}
+
+	tm.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode token state: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, stateDirPermissions); err != nil {
+		return fmt.Errorf("failed to create token state directory %s: %w", dir, err)
+	}
+
+	return atomicfile.WriteFile(dir, filepath.Base(path), data, stateFilePermissions)
+}
+
+// saveStateAsync calls SaveState, logging any error.
+func (tm *TokenManager) saveStateAsync() {
+	if err := tm.SaveState(); err != nil {
+		log.Printf("Failed to save token state: %v", err)
+	}
+}
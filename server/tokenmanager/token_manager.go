@@ -13,7 +13,9 @@ import (
 	"math/rand"
 	"net/http"
 	"regexp"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -24,8 +26,28 @@ type TokenStatus int
 const (
 	Good     TokenStatus = iota // Token is in a good state and can be used
 	TimedOut                    // Token is currently timed out and should not be used
+	Invalid                     // Token was rejected as invalid or expired and won't be used again
 )
 
+// String returns the name of the status, as used in snapshots.
+func (s TokenStatus) String() string {
+	switch s {
+	case Good:
+		return "good"
+	case TimedOut:
+		return "timed_out"
+	case Invalid:
+		return "invalid"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so that the status is encoded by name.
+func (s TokenStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // Token represents an individual API token with its associated metadata.
 type Token struct {
 	Value               string        // The actual token value
@@ -33,6 +55,7 @@ type Token struct {
 	TimeoutUntil        time.Time     // Time until which the token is timed out
 	FailureCount        int           // Number of consecutive failures
 	LastUsed            time.Time     // Last time the token was used
+	LastSuccess         time.Time     // Last time a request using the token succeeded
 	BaseTimeoutDuration time.Duration // Base duration for timeout calculations
 	P_AB                string
 }
@@ -46,6 +69,19 @@ type TokenManager struct {
 	maxBackoffTime      time.Duration // Maximum allowed backoff time
 	loadBalancingMethod string        // Method used for load balancing (e.g., "round-robin", "random")
 	currentIndex        int           // Current index for round-robin selection
+	statePath           string        // File that token state is persisted to, if any
+	saveMu              sync.Mutex    // Serializes writes to statePath
+}
+
+// TokenSnapshot is a point-in-time copy of a token's metadata,
+// with the token value masked so that it can be safely displayed.
+type TokenSnapshot struct {
+	MaskedValue  string
+	Status       TokenStatus
+	FailureCount int
+	LastUsed     time.Time
+	LastSuccess  time.Time
+	TimeoutUntil time.Time
 }
 
 var r_p_ab = regexp.MustCompile(`'p_ab_d_id': "(\d+)"`)
@@ -83,19 +119,25 @@ func getP_AB(token string) string {
 	return personalID
 }
 
+// newToken creates a Token in a good state for the given value.
+func newToken(value string, baseTimeout time.Duration) *Token {
+	// p_ab := getP_AB(value)
+	p_ab := "801787224"
+
+	return &Token{
+		Value:               value,
+		Status:              Good,
+		BaseTimeoutDuration: baseTimeout,
+		P_AB:                p_ab,
+	}
+}
+
 // NewTokenManager creates and initializes a new TokenManager with the given parameters.
 func NewTokenManager(tokenValues []string, maxRetries int, baseTimeout, maxBackoffTime time.Duration, loadBalancingMethod string) *TokenManager {
 	tokens := make([]*Token, len(tokenValues))
 
 	for i, value := range tokenValues {
-		// p_ab := getP_AB(value)
-		p_ab := "801787224"
-		tokens[i] = &Token{
-			Value:               value,
-			Status:              Good,
-			BaseTimeoutDuration: baseTimeout,
-			P_AB:                p_ab,
-		}
+		tokens[i] = newToken(value, baseTimeout)
 	}
 
 	return &TokenManager{
@@ -108,6 +150,55 @@ func NewTokenManager(tokenValues []string, maxRetries int, baseTimeout, maxBacko
 	}
 }
 
+// Reconfigure atomically replaces the token pool and the token management settings.
+//
+// Tokens whose value is in both the old and new pools keep their metadata,
+// such as their status and failure count, while new tokens start in a good state.
+// It returns the number of tokens added to and removed from the pool.
+func (tm *TokenManager) Reconfigure(tokenValues []string, maxRetries int, baseTimeout, maxBackoffTime time.Duration, loadBalancingMethod string) (int, int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	existing := make(map[string]*Token, len(tm.tokens))
+	for _, token := range tm.tokens {
+		existing[token.Value] = token
+	}
+
+	tokens := make([]*Token, 0, len(tokenValues))
+	added, kept := 0, 0
+
+	for _, value := range tokenValues {
+		token, ok := existing[value]
+		if ok {
+			// Remove the token so that a duplicate value gets a token of its own.
+			delete(existing, value)
+
+			token.BaseTimeoutDuration = baseTimeout
+			kept++
+		} else {
+			token = newToken(value, baseTimeout)
+			added++
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	removed := len(tm.tokens) - kept
+
+	tm.tokens = tokens
+	tm.maxRetries = maxRetries
+	tm.baseTimeout = baseTimeout
+	tm.maxBackoffTime = maxBackoffTime
+	tm.loadBalancingMethod = loadBalancingMethod
+	tm.currentIndex = 0
+
+	if tm.statePath != "" {
+		go tm.saveStateAsync()
+	}
+
+	return added, removed
+}
+
 // GetToken selects and returns a token based on the configured load balancing method.
 func (tm *TokenManager) GetToken() *Token {
 	tm.mu.Lock()
@@ -199,12 +290,21 @@ func (tm *TokenManager) leastRecentlyUsedSelection(healthyTokens []*Token) *Toke
 }
 
 // MarkTokenStatus updates the status of a token and handles timeout logic.
+//
+// A token marked as Invalid is no longer selected by GetToken,
+// and isn't reset by ResetAllTokens.
 func (tm *TokenManager) MarkTokenStatus(token *Token, status TokenStatus) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	// Only persist changes that affect token selection after a restart,
+	// rather than after every successful request.
+	changed := status != token.Status || token.FailureCount != 0
+
 	token.Status = status
-	if status == TimedOut {
+
+	switch status {
+	case TimedOut:
 		token.FailureCount++
 		// Calculate timeout duration using exponential backoff with a maximum limit
 		timeoutDuration := time.Duration(math.Min(
@@ -212,19 +312,134 @@ func (tm *TokenManager) MarkTokenStatus(token *Token, status TokenStatus) {
 			float64(tm.maxBackoffTime),
 		))
 		token.TimeoutUntil = time.Now().Add(timeoutDuration)
-	} else {
+	case Good:
 		// Reset failure count when marked as Good
 		token.FailureCount = 0
+		token.LastSuccess = time.Now()
+	case Invalid:
+		token.TimeoutUntil = time.Time{}
+	}
+
+	// Tokens that aren't in the pool, such as those of logged-in users, aren't persisted
+	if changed && tm.statePath != "" && slices.Contains(tm.tokens, token) {
+		go tm.saveStateAsync()
+	}
+}
+
+// Snapshot returns a copy of the metadata of all tokens, in the order they were configured.
+func (tm *TokenManager) Snapshot() []TokenSnapshot {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	snapshots := make([]TokenSnapshot, len(tm.tokens))
+
+	for i, token := range tm.tokens {
+		snapshots[i] = TokenSnapshot{
+			MaskedValue:  MaskToken(token.Value),
+			Status:       token.Status,
+			FailureCount: token.FailureCount,
+			LastUsed:     token.LastUsed,
+			LastSuccess:  token.LastSuccess,
+			TimeoutUntil: token.TimeoutUntil,
+		}
+	}
+
+	return snapshots
+}
+
+// BackoffRemaining returns how long the token remains timed out after now,
+// rounded down to the second, or 0 if it isn't timed out.
+func (s TokenSnapshot) BackoffRemaining(now time.Time) time.Duration {
+	if s.Status != TimedOut || !s.TimeoutUntil.After(now) {
+		return 0
+	}
+
+	return s.TimeoutUntil.Sub(now).Truncate(time.Second)
+}
+
+// MaskToken hides all but the first and last few characters of a token value.
+//
+// Short values are masked entirely.
+func MaskToken(value string) string {
+	const (
+		visible   = 4
+		minLength = 3 * visible
+	)
+
+	if len(value) < minLength {
+		return strings.Repeat("*", len(value))
+	}
+
+	return value[:visible] + strings.Repeat("*", len(value)-2*visible) + value[len(value)-visible:]
+}
+
+// FirstToken returns the first token in the pool, whatever its status,
+// or nil if the pool is empty.
+func (tm *TokenManager) FirstToken() *Token {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if len(tm.tokens) == 0 {
+		return nil
+	}
+
+	return tm.tokens[0]
+}
+
+// Len returns the number of tokens in the pool.
+func (tm *TokenManager) Len() int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	return len(tm.tokens)
+}
+
+// StatusCounts returns the number of tokens that are in a good state, that are timed out
+// and that are invalid, in that order.
+//
+// Timed out tokens whose timeout has expired are counted as good,
+// since they can be selected again.
+func (tm *TokenManager) StatusCounts() (int, int, int) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	var good, timedOut, invalid int
+
+	now := time.Now()
+
+	for _, token := range tm.tokens {
+		switch token.Status {
+		case Good:
+			good++
+		case TimedOut:
+			if now.After(token.TimeoutUntil) {
+				good++
+			} else {
+				timedOut++
+			}
+		case Invalid:
+			invalid++
+		}
 	}
+
+	return good, timedOut, invalid
 }
 
-// ResetAllTokens resets all tokens to their initial good state.
+// ResetAllTokens resets all tokens to their initial good state, except for invalid tokens.
 func (tm *TokenManager) ResetAllTokens() {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
 	for _, token := range tm.tokens {
+		if token.Status == Invalid {
+			continue
+		}
+
 		token.Status = Good
 		token.FailureCount = 0
 	}
+
+	if tm.statePath != "" {
+		go tm.saveStateAsync()
+	}
 }
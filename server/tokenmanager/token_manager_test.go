@@ -4,6 +4,9 @@
 package tokenmanager
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -161,6 +164,99 @@ func TestResetAllTokens(t *testing.T) {
 	}
 }
 
+// TestStatusCounts verifies that StatusCounts reports the number of good and timed out tokens,
+// counting tokens whose timeout has expired as good.
+func TestStatusCounts(t *testing.T) {
+	tm := NewTokenManager([]string{"token1", "token2", "token3", "token4"}, 5, 1000*time.Millisecond, 32000*time.Millisecond, "round-robin")
+
+	tm.MarkTokenStatus(tm.tokens[0], TimedOut)
+	tm.MarkTokenStatus(tm.tokens[1], Invalid)
+
+	tm.tokens[2].Status = TimedOut
+	tm.tokens[2].TimeoutUntil = time.Now().Add(-time.Second)
+
+	good, timedOut, invalid := tm.StatusCounts()
+	if good != 2 || timedOut != 1 || invalid != 1 {
+		t.Errorf("Expected 2 good, 1 timed out and 1 invalid token, got %d, %d and %d", good, timedOut, invalid)
+	}
+}
+
+// TestSnapshot verifies that Snapshot reports the metadata of all tokens with masked values.
+func TestSnapshot(t *testing.T) {
+	tm := NewTokenManager([]string{"123456_abcdefghijklmnop", "token2"}, 5, time.Minute, time.Hour, "round-robin")
+
+	tm.MarkTokenStatus(tm.tokens[0], TimedOut)
+	tm.MarkTokenStatus(tm.tokens[1], Good)
+
+	snapshots := tm.Snapshot()
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+	}
+
+	if snapshots[0].MaskedValue != "1234***************mnop" {
+		t.Errorf("Expected masked value 1234***************mnop, got %s", snapshots[0].MaskedValue)
+	}
+
+	if snapshots[0].Status != TimedOut || snapshots[0].FailureCount != 1 {
+		t.Errorf("Expected first token to be timed out with 1 failure, got %v with %d", snapshots[0].Status, snapshots[0].FailureCount)
+	}
+
+	if snapshots[0].BackoffRemaining(time.Now()) <= 0 {
+		t.Errorf("Expected first token to have a remaining backoff")
+	}
+
+	if snapshots[1].MaskedValue != "******" {
+		t.Errorf("Expected short token to be fully masked, got %s", snapshots[1].MaskedValue)
+	}
+
+	if snapshots[1].LastSuccess.IsZero() {
+		t.Errorf("Expected second token to have a last success time")
+	}
+}
+
+// TestPersistState verifies that token state saved by one TokenManager
+// is restored by another using the same state file.
+func TestPersistState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	tm := NewTokenManager([]string{"token1", "token2"}, 5, 1000*time.Millisecond, 32000*time.Millisecond, "round-robin")
+	if err := tm.PersistState(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tm.tokens[0].Status = TimedOut
+	tm.tokens[0].FailureCount = 3
+	tm.tokens[0].TimeoutUntil = time.Now().Add(time.Minute)
+
+	if err := tm.SaveState(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.Contains(string(data), "token1") {
+		t.Errorf("Expected token values not to be written to the state file")
+	}
+
+	restored := NewTokenManager([]string{"token2", "token1", "token3"}, 5, 1000*time.Millisecond, 32000*time.Millisecond, "round-robin")
+	if err := restored.PersistState(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if token := restored.tokens[1]; token.Status != TimedOut || token.FailureCount != 3 {
+		t.Errorf("Expected token1 to be restored as timed out with 3 failures, got %v with %d", token.Status, token.FailureCount)
+	}
+
+	for _, token := range []*Token{restored.tokens[0], restored.tokens[2]} {
+		if token.Status != Good || token.FailureCount != 0 {
+			t.Errorf("Expected %s to be good with 0 failures, got %v with %d", token.Value, token.Status, token.FailureCount)
+		}
+	}
+}
+
 // TestGetFallbackToken verifies that when all tokens are timed out,
 // the TokenManager correctly selects and resets a fallback token.
 func TestGetFallbackToken(t *testing.T) {
@@ -234,3 +330,55 @@ func TestEmptyTokenList(t *testing.T) {
 		t.Errorf("Expected nil token for empty token list, got %v", token)
 	}
 }
+
+// TestReconfigure verifies that Reconfigure replaces the token pool
+// while keeping the metadata of tokens that are still configured.
+func TestReconfigure(t *testing.T) {
+	tm := NewTokenManager([]string{"token1", "token2"}, 5, 1000*time.Millisecond, 32000*time.Millisecond, "round-robin")
+
+	kept := tm.tokens[0]
+	tm.MarkTokenStatus(kept, TimedOut)
+
+	added, removed := tm.Reconfigure([]string{"token3", "token1"}, 3, 2000*time.Millisecond, 16000*time.Millisecond, "random")
+	if added != 1 || removed != 1 {
+		t.Errorf("Expected 1 token added and 1 removed, got %d and %d", added, removed)
+	}
+
+	if len(tm.tokens) != 2 || tm.tokens[0].Value != "token3" || tm.tokens[1] != kept {
+		t.Fatalf("Expected tokens [token3 token1] with token1 kept, got %v", tm.tokens)
+	}
+
+	if kept.Status != TimedOut || kept.FailureCount != 1 {
+		t.Errorf("Expected token1 to stay timed out with 1 failure, got %v with %d", kept.Status, kept.FailureCount)
+	}
+
+	if tm.maxRetries != 3 || tm.baseTimeout != 2000*time.Millisecond || tm.loadBalancingMethod != "random" {
+		t.Errorf("Expected settings to be updated, got %d, %v, %s", tm.maxRetries, tm.baseTimeout, tm.loadBalancingMethod)
+	}
+}
+
+// TestInvalidToken verifies that invalid tokens are no longer selected,
+// even after all tokens are reset.
+func TestInvalidToken(t *testing.T) {
+	tm := NewTokenManager([]string{"token1", "token2"}, 5, 1000*time.Millisecond, 32000*time.Millisecond, "round-robin")
+
+	tm.MarkTokenStatus(tm.tokens[0], Invalid)
+	tm.MarkTokenStatus(tm.tokens[1], TimedOut)
+	tm.ResetAllTokens()
+
+	if tm.tokens[0].Status != Invalid {
+		t.Errorf("Expected invalid token to stay invalid after reset, got %v", tm.tokens[0].Status)
+	}
+
+	for range 4 {
+		if token := tm.GetToken(); token.Value != "token2" {
+			t.Errorf("Expected token2 to be selected, got %s", token.Value)
+		}
+	}
+
+	tm.MarkTokenStatus(tm.tokens[1], Invalid)
+
+	if token := tm.GetToken(); token != nil {
+		t.Errorf("Expected no token when all tokens are invalid, got %s", token.Value)
+	}
+}
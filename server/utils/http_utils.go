@@ -90,3 +90,25 @@ func GenerateETag(content []byte) string {
 
 	return base64.RawURLEncoding.EncodeToString(hashBytes)
 }
+
+// Streamer is implemented by response writers that buffer the response, such as
+// those of the error handling middleware, to allow it to be streamed instead.
+type Streamer interface {
+	// Stream writes the headers set so far to the underlying writer and returns
+	// it, for the rest of the response to be written to it directly.
+	Stream() http.ResponseWriter
+}
+
+// StreamResponse returns a writer that sends the response to the client as it's
+// written, bypassing the buffering that allows an error page to replace it.
+//
+// Errors returned by a handler after it started streaming can't be shown to the
+// client, as part of the response has already been sent. They're logged, and the
+// connection is aborted.
+func StreamResponse(w http.ResponseWriter) http.ResponseWriter {
+	if s, ok := w.(Streamer); ok {
+		return s.Stream()
+	}
+
+	return w
+}
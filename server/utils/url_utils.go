@@ -4,29 +4,31 @@
 package utils
 
 import (
+	"context"
 	"net/url"
 	"strings"
 
 	"codeberg.org/pixivfe/pixivfe/i18n"
 )
 
-// ValidateURL checks if the given URL is valid.
-func ValidateURL(urlString string, urlType string) (*url.URL, error) {
+// ValidateURL checks if the given URL is valid, with errors localized to the
+// locale of ctx.
+func ValidateURL(ctx context.Context, urlString string, urlType string) (*url.URL, error) {
 	parsedURL, err := url.Parse(urlString)
 	if err != nil {
-		return nil, i18n.Errorf("failed to parse %s URL: %w", urlType, err)
+		return nil, i18n.ErrorfContext(ctx, "failed to parse %s URL: %w", urlType, err)
 	}
 
 	// Ensure both scheme and host are present in the URL
 	if parsedURL.Scheme == "" || parsedURL.Host == "" {
-		return nil, i18n.Errorf(
+		return nil, i18n.ErrorfContext(ctx,
 			"%s URL is invalid: %s. Please specify a complete URL with scheme and host, e.g. https://example.com",
 			urlType,
 			urlString)
 	}
 
 	if strings.HasSuffix(parsedURL.Path, "/") {
-		return nil, i18n.Errorf(
+		return nil, i18n.ErrorfContext(ctx,
 			"%s URL path (%s) cannot end in /: %s. PixivFE does not support this now",
 			urlType,
 			parsedURL.Path,